@@ -1,25 +1,166 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/anthonycursewl/anx-agent/internal/ai"
 	"github.com/anthonycursewl/anx-agent/internal/cli"
 	"github.com/anthonycursewl/anx-agent/internal/config"
+	"github.com/anthonycursewl/anx-agent/internal/headless"
+	"github.com/anthonycursewl/anx-agent/internal/theme"
 )
 
 func main() {
-	cfg, err := config.LoadConfig("")
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	var globals headless.Globals
+	root := flag.NewFlagSet("anx-agent", flag.ContinueOnError)
+	root.Usage = usage
+	globals.Register(root)
+	resume := root.Bool("resume", false, "reopen the last chat session")
+	if err := root.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return headless.ExitOK
+		}
+		return headless.ExitUsage
+	}
+
+	args = root.Args()
+	name := "tui"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	switch name {
+	case "tui":
+		return runTUI(&globals, *resume, args)
+	case "help":
+		if len(args) > 0 {
+			if cmd := headless.Lookup(args[0]); cmd != nil {
+				return headless.ExitCode(runCommand(cmd, &globals, []string{"-h"}))
+			}
+		}
+		usage()
+		return headless.ExitOK
+	}
+
+	cmd := headless.Lookup(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "anx-agent: unknown command '%s'\n\n", name)
+		usage()
+		return headless.ExitUsage
+	}
+	err := runCommand(cmd, &globals, args)
+	return headless.ExitCode(err)
+}
+
+// runCommand runs a headless command, interrupted by Ctrl+C or SIGTERM. The
+// theme is not loaded: headless output is plain text and never colored.
+func runCommand(cmd *headless.Command, globals *headless.Globals, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var client *ai.Client
+	env := headless.NewEnv(globals, func() (ai.Generator, error) {
+		cfg, err := setup(globals)
+		if err != nil {
+			return nil, err
+		}
+		client, err = ai.NewClient(cfg.GEMINI_API_KEY)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing AI client: %w", err)
+		}
+		return client, nil
+	})
+	err := cmd.Run(ctx, env, args)
+	if client != nil {
+		client.Close()
+	}
+	env.Report(cmd.Name, err)
+	return err
+}
+
+// setup loads the configuration and applies what the flags leave to it.
+func setup(globals *headless.Globals) (*config.Config, error) {
+	if globals.Config != "" {
+		if _, err := os.Stat(globals.Config); err != nil {
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
+	}
+	cfg, err := config.LoadConfig(globals.Config)
+	if err != nil {
+		return nil, err
+	}
+	if globals.LogLevel == "" {
+		globals.LogLevel = cfg.LogLevel
+	}
+	if err := setLogLevel(globals.LogLevel); err != nil {
+		return nil, err
+	}
+	if globals.Timeout == 0 && cfg.Timeout != "" {
+		if globals.Timeout, err = time.ParseDuration(cfg.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout in config: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// setLogLevel shows the informational logs of the AI client at info and
+// debug; they are hidden by default so as not to mix with command output.
+func setLogLevel(level string) error {
+	switch strings.ToLower(level) {
+	case "debug", "info":
+		log.SetOutput(os.Stderr)
+	case "", "warn", "warning", "error":
+		log.SetOutput(io.Discard)
+	default:
+		return fmt.Errorf("unknown log level '%s'; use debug, info, warn or error", level)
+	}
+	return nil
+}
+
+func runTUI(globals *headless.Globals, resume bool, args []string) int {
+	fs := flag.NewFlagSet("tui", flag.ContinueOnError)
+	globals.Register(fs)
+	fs.BoolVar(&resume, "resume", resume, "reopen the last chat session")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return headless.ExitOK
+		}
+		return headless.ExitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "anx-agent tui: unexpected argument '%s'\n", fs.Arg(0))
+		return headless.ExitUsage
+	}
+
+	cfg, err := setup(globals)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
-		os.Exit(1)
+		return headless.ExitFailure
 	}
 
+	t, err := theme.Load(cfg.Theme)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading theme: %v; using the default theme\n", err)
+		t, _ = theme.Load(theme.Auto)
+	}
+	theme.Set(t)
+
 	aiClient, err := ai.NewClient(cfg.GEMINI_API_KEY)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing AI client: %v\n", err)
-		os.Exit(1)
+		return headless.ExitFailure
 	}
 
 	defer func() {
@@ -27,5 +168,36 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error closing AI client: %v\n", err)
 		}
 	}()
-	cli.Start(aiClient)
+	if err := cli.Start(aiClient, cli.Options{Resume: resume, Keys: cfg.Keys, Layout: cfg.Layout}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return headless.ExitFailure
+	}
+	return headless.ExitOK
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `Usage: anx-agent [flags] [command] [arguments]
+
+Without a command, or with 'tui', anx-agent starts the interactive interface.
+
+Commands:
+`)
+	for _, cmd := range headless.Commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprint(os.Stderr, `  tui        Start the interactive interface (default)
+  help       Show the help of a command
+
+Flags:
+  --config FILE       path of the config file (default config.yaml)
+  --log-level LEVEL   debug, info, warn or error (default warn)
+  --json              write the result as JSON
+  --quiet             do not report progress on standard error
+  --timeout DURATION  give up on each AI request after this long, e.g. 2m
+  --resume            reopen the last chat session (tui)
+
+Headless commands write uncolored output; the theme only applies to the TUI.
+
+Exit codes: 0 success, 1 failure, 2 usage error, 3 review findings at --fail-on, 130 interrupted.
+`)
 }
@@ -0,0 +1,316 @@
+// Package analyzer asks a model about every file of a project, chunk by
+// chunk, and consolidates the answers into a ProjectAnalysis.
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/agent/chunker"
+	"github.com/anthonycursewl/anx-agent/internal/agent/project"
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+)
+
+// DefaultMaxTokensPerChunk keeps each request well within the context window
+// while leaving room for the instructions.
+const DefaultMaxTokensPerChunk = 6000
+
+// ProjectAnalysis is the consolidated result of analyzing a project.
+type ProjectAnalysis struct {
+	Path            string        `json:"path"`
+	Model           string        `json:"model"`
+	GeneratedAt     time.Time     `json:"generated_at"`
+	Overview        string        `json:"overview"`
+	KeyTechnologies []string      `json:"key_technologies"`
+	Structure       string        `json:"structure"`
+	FileSummaries   []FileSummary `json:"file_summaries"`
+	Suggestions     []string      `json:"suggestions"`
+	PotentialIssues []string      `json:"potential_issues"`
+	Usage           ai.Usage      `json:"usage"`
+}
+
+// FileSummary is what the model made of a single file. Error is set when
+// some of its chunks could not be analyzed.
+type FileSummary struct {
+	FilePath     string   `json:"file_path"`
+	Summary      string   `json:"summary"`
+	KeyFunctions []string `json:"key_functions,omitempty"`
+	Dependencies []string `json:"dependencies,omitempty"`
+	Issues       []string `json:"issues,omitempty"`
+	Chunks       int      `json:"chunks"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// Event reports the progress of an analysis.
+type Event struct {
+	File   string // The file being analyzed, empty while consolidating
+	Index  int    // 1-based position of File
+	Total  int
+	Chunk  int // 1-based chunk of File
+	Chunks int
+}
+
+// ProjectAnalyzer runs the analysis with Client. Focus, when set, is a
+// question or topic the analysis should concentrate on.
+type ProjectAnalyzer struct {
+	Client            ai.Generator
+	MaxTokensPerChunk int
+	Focus             string
+	Progress          func(Event)
+}
+
+// AnalyzeProject analyzes files, found under root, and consolidates the
+// results. A file or chunk that fails is recorded in its FileSummary; the
+// analysis only fails when no file could be analyzed.
+func (a *ProjectAnalyzer) AnalyzeProject(ctx context.Context, root string, files []project.File) (*ProjectAnalysis, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files to analyze")
+	}
+	maxTokens := a.MaxTokensPerChunk
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxTokensPerChunk
+	}
+
+	analysis := &ProjectAnalysis{Path: root, Model: ai.Model, GeneratedAt: time.Now()}
+	var firstErr error
+	analyzed := 0
+	for i, file := range files {
+		chunks, err := chunker.ChunkContent(file.Content, maxTokens, ai.EstimateTokens)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("error chunking '%s': %w", file.Path, err)
+			}
+			analysis.FileSummaries = append(analysis.FileSummaries, FileSummary{FilePath: file.Path, Error: err.Error()})
+			continue
+		}
+		summary := FileSummary{FilePath: file.Path, Chunks: len(chunks)}
+		failed := 0
+		for j, chunk := range chunks {
+			chunk.Metadata["filename"] = file.Path
+			a.progress(Event{File: file.Path, Index: i + 1, Total: len(files), Chunk: j + 1, Chunks: len(chunks)})
+			part, err := a.analyzeChunk(ctx, chunk, len(chunks), &analysis.Usage)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("error analyzing '%s': %w", file.Path, err)
+				}
+				failed++
+				summary.addError(chunk, len(chunks), err)
+				continue
+			}
+			if len(chunks) > 1 && part.Summary != "" {
+				part.Summary = fmt.Sprintf("(lines %s-%s) %s", chunk.Metadata["line_start"], chunk.Metadata["line_end"], part.Summary)
+			}
+			summary.merge(part)
+		}
+		if len(chunks) == 0 {
+			summary.Summary = "Empty file."
+		}
+		if failed < len(chunks) || len(chunks) == 0 {
+			analyzed++
+		}
+		analysis.FileSummaries = append(analysis.FileSummaries, summary)
+	}
+	if analyzed == 0 {
+		return nil, firstErr
+	}
+
+	a.progress(Event{Total: len(files)})
+	if err := a.consolidate(ctx, analysis); err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
+
+func (a *ProjectAnalyzer) progress(e Event) {
+	if a.Progress != nil {
+		a.Progress(e)
+	}
+}
+
+// chunkAnswer is the JSON the model is asked to answer a chunk with.
+type chunkAnswer struct {
+	Summary      string   `json:"summary"`
+	KeyFunctions []string `json:"key_functions"`
+	Dependencies []string `json:"dependencies"`
+	Issues       []string `json:"issues"`
+}
+
+func (a *ProjectAnalyzer) analyzeChunk(ctx context.Context, chunk chunker.Chunk, chunks int, usage *ai.Usage) (chunkAnswer, error) {
+	what := fmt.Sprintf("the file `%s`", chunk.Metadata["filename"])
+	if chunks > 1 {
+		what = fmt.Sprintf("lines %s-%s of the file `%s`, which was split in %d parts", chunk.Metadata["line_start"], chunk.Metadata["line_end"], chunk.Metadata["filename"], chunks)
+	}
+	prompt := fmt.Sprintf(`You are an expert software engineer analyzing a project file by file. Below is %s.
+
+Answer with a single JSON object and nothing else, with these fields:
+- "summary": two or three sentences on what this code does and its role in the project
+- "key_functions": the most important functions, types or sections it defines
+- "dependencies": the libraries, packages or files it depends on
+- "issues": bugs, risks or problems worth fixing, each one sentence; empty if none
+%s
+--- CONTENT ---
+%s`, what, a.focus(), chunk.Content)
+
+	reply, err := a.Client.Generate(ctx, prompt)
+	if err != nil {
+		return chunkAnswer{}, err
+	}
+	add(usage, reply.Usage)
+	var answer chunkAnswer
+	if err := decode(reply.Text, &answer); err != nil {
+		// An answer that is not JSON is still a summary.
+		return chunkAnswer{Summary: strings.TrimSpace(reply.Text)}, nil
+	}
+	return answer, nil
+}
+
+// merge adds what the model said about one chunk of the file.
+func (s *FileSummary) merge(part chunkAnswer) {
+	if part.Summary != "" {
+		if s.Summary != "" {
+			s.Summary += " "
+		}
+		s.Summary += strings.TrimSpace(part.Summary)
+	}
+	s.KeyFunctions = union(s.KeyFunctions, part.KeyFunctions)
+	s.Dependencies = union(s.Dependencies, part.Dependencies)
+	s.Issues = union(s.Issues, part.Issues)
+}
+
+// addError records that a chunk of the file could not be analyzed.
+func (s *FileSummary) addError(chunk chunker.Chunk, chunks int, err error) {
+	msg := err.Error()
+	if chunks > 1 {
+		msg = fmt.Sprintf("lines %s-%s: %s", chunk.Metadata["line_start"], chunk.Metadata["line_end"], msg)
+	}
+	if s.Error != "" {
+		s.Error += "; "
+	}
+	s.Error += msg
+}
+
+// projectAnswer is the JSON the model is asked to consolidate with.
+type projectAnswer struct {
+	Overview        string   `json:"overview"`
+	KeyTechnologies []string `json:"key_technologies"`
+	Structure       string   `json:"structure"`
+	Suggestions     []string `json:"suggestions"`
+	PotentialIssues []string `json:"potential_issues"`
+}
+
+func (a *ProjectAnalyzer) consolidate(ctx context.Context, analysis *ProjectAnalysis) error {
+	var sb strings.Builder
+	for _, s := range analysis.FileSummaries {
+		fmt.Fprintf(&sb, "### %s\n%s\n", s.FilePath, s.Summary)
+		if s.Error != "" {
+			fmt.Fprintf(&sb, "(Parts of this file could not be analyzed: %s)\n", s.Error)
+		}
+		if len(s.KeyFunctions) > 0 {
+			fmt.Fprintf(&sb, "Key functions: %s\n", strings.Join(s.KeyFunctions, ", "))
+		}
+		if len(s.Dependencies) > 0 {
+			fmt.Fprintf(&sb, "Dependencies: %s\n", strings.Join(s.Dependencies, ", "))
+		}
+		for _, issue := range s.Issues {
+			fmt.Fprintf(&sb, "Issue: %s\n", issue)
+		}
+		sb.WriteString("\n")
+	}
+	prompt := fmt.Sprintf(`You are an expert software architect. Below are the directory tree of a project and summaries of each of its files.
+
+Answer with a single JSON object and nothing else, with these fields:
+- "overview": a high-level overview of the project: its purpose, how it works and who it is for
+- "key_technologies": the languages, frameworks and notable libraries it uses
+- "structure": a short description of how the project is organized
+- "suggestions": concrete improvements, most valuable first
+- "potential_issues": the most important problems found across the files
+%s
+--- DIRECTORY TREE ---
+%s
+--- FILE SUMMARIES ---
+%s`, a.focus(), tree(analysis.FileSummaries), sb.String())
+
+	reply, err := a.Client.Generate(ctx, prompt)
+	if err != nil {
+		return fmt.Errorf("error consolidating the analysis: %w", err)
+	}
+	add(&analysis.Usage, reply.Usage)
+	var answer projectAnswer
+	if err := decode(reply.Text, &answer); err != nil {
+		answer = projectAnswer{Overview: strings.TrimSpace(reply.Text)}
+	}
+	analysis.Overview = answer.Overview
+	analysis.KeyTechnologies = answer.KeyTechnologies
+	analysis.Structure = answer.Structure
+	analysis.Suggestions = answer.Suggestions
+	analysis.PotentialIssues = answer.PotentialIssues
+	return nil
+}
+
+func (a *ProjectAnalyzer) focus() string {
+	if a.Focus == "" {
+		return ""
+	}
+	return "\nPay particular attention to: " + a.Focus + "\n"
+}
+
+// decode parses the JSON object in text, which models tend to wrap in a
+// code fence or a sentence.
+func decode(text string, v any) error {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("no JSON object in the answer")
+	}
+	return json.Unmarshal([]byte(text[start:end+1]), v)
+}
+
+// tree renders the paths of the files as an indented directory tree.
+func tree(files []FileSummary) string {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.FilePath
+	}
+	sort.Strings(paths)
+	var sb strings.Builder
+	printed := map[string]bool{}
+	for _, p := range paths {
+		parts := strings.Split(p, "/")
+		for i := range parts[:len(parts)-1] {
+			dir := path.Join(parts[:i+1]...)
+			if !printed[dir] {
+				printed[dir] = true
+				sb.WriteString(strings.Repeat("  ", i) + parts[i] + "/\n")
+			}
+		}
+		sb.WriteString(strings.Repeat("  ", len(parts)-1) + parts[len(parts)-1] + "\n")
+	}
+	return sb.String()
+}
+
+func union(a, b []string) []string {
+	seen := map[string]bool{}
+	for _, s := range a {
+		seen[s] = true
+	}
+	for _, s := range b {
+		if s = strings.TrimSpace(s); s != "" && !seen[s] {
+			seen[s] = true
+			a = append(a, s)
+		}
+	}
+	return a
+}
+
+func add(total *ai.Usage, u ai.Usage) {
+	total.Prompt += u.Prompt
+	total.Output += u.Output
+	total.Total += u.Total
+}
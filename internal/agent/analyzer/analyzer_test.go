@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/anthonycursewl/anx-agent/internal/agent/project"
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+)
+
+// generator answers with fn, called with each prompt.
+type generator func(prompt string) (string, error)
+
+func (g generator) Generate(ctx context.Context, prompt string) (ai.Reply, error) {
+	text, err := g(prompt)
+	return ai.Reply{Text: text, Usage: ai.Usage{Total: 1}}, err
+}
+
+func files(paths ...string) []project.File {
+	var out []project.File
+	for _, p := range paths {
+		out = append(out, project.File{Path: p, Content: []byte("package " + p + "\n"), Ext: "go"})
+	}
+	return out
+}
+
+func TestAnalyzeProjectKeepsGoingAfterAnError(t *testing.T) {
+	a := &ProjectAnalyzer{Client: generator(func(prompt string) (string, error) {
+		switch {
+		case strings.Contains(prompt, "`bad.go`"):
+			return "", errors.New("quota exceeded")
+		case strings.Contains(prompt, "FILE SUMMARIES"):
+			return `{"overview": "A project."}`, nil
+		}
+		return `{"summary": "Fine."}`, nil
+	})}
+	analysis, err := a.AnalyzeProject(context.Background(), ".", files("good.go", "bad.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.FileSummaries) != 2 {
+		t.Fatalf("got %d summaries, want 2", len(analysis.FileSummaries))
+	}
+	good, bad := analysis.FileSummaries[0], analysis.FileSummaries[1]
+	if good.Summary != "Fine." || good.Error != "" {
+		t.Errorf("unexpected summary of good.go %+v", good)
+	}
+	if !strings.Contains(bad.Error, "quota exceeded") {
+		t.Errorf("error of bad.go = %q", bad.Error)
+	}
+	if analysis.Overview != "A project." || analysis.Usage.Total != 2 {
+		t.Errorf("unexpected analysis %+v", analysis)
+	}
+}
+
+func TestAnalyzeProjectFailsWhenNothingWasAnalyzed(t *testing.T) {
+	a := &ProjectAnalyzer{Client: generator(func(string) (string, error) {
+		return "", errors.New("quota exceeded")
+	})}
+	if _, err := a.AnalyzeProject(context.Background(), ".", files("a.go", "b.go")); err == nil || !strings.Contains(err.Error(), "a.go") {
+		t.Errorf("got error %v, want the error on a.go", err)
+	}
+}
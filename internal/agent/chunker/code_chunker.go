@@ -0,0 +1,68 @@
+// Package chunker splits file contents into pieces small enough to be sent
+// to a model.
+package chunker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Chunk is a run of whole lines of a file. Metadata holds "line_start" and
+// "line_end", 1-based and inclusive, plus whatever the caller adds, such as
+// "filename".
+type Chunk struct {
+	Content    string
+	Metadata   map[string]string
+	TokenCount int
+}
+
+// softBreak is how full a chunk has to be for a blank line to end it, so
+// that chunks tend to stop between declarations rather than inside them.
+const softBreak = 0.75
+
+// ChunkContent splits content into chunks of at most maxTokensPerChunk
+// tokens as counted by tokenizer. Lines are never split, so a single line
+// longer than the limit makes a chunk of its own.
+func ChunkContent(content []byte, maxTokensPerChunk int, tokenizer func(string) int) ([]Chunk, error) {
+	if maxTokensPerChunk <= 0 {
+		return nil, fmt.Errorf("invalid chunk size %d", maxTokensPerChunk)
+	}
+	text := string(content)
+	if text == "" {
+		return nil, nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var chunks []Chunk
+	var sb strings.Builder
+	start, tokens := 1, 0
+	flush := func(end int) {
+		if sb.Len() == 0 {
+			return
+		}
+		chunks = append(chunks, Chunk{
+			Content:    sb.String(),
+			Metadata:   map[string]string{"line_start": strconv.Itoa(start), "line_end": strconv.Itoa(end)},
+			TokenCount: tokens,
+		})
+		sb.Reset()
+		start, tokens = end+1, 0
+	}
+	for i, line := range lines {
+		n := tokenizer(line)
+		if tokens > 0 && tokens+n > maxTokensPerChunk {
+			flush(i)
+		}
+		sb.WriteString(line)
+		tokens += n
+		if strings.TrimSpace(line) == "" && float64(tokens) >= softBreak*float64(maxTokensPerChunk) {
+			flush(i + 1)
+		}
+	}
+	flush(len(lines))
+	return chunks, nil
+}
@@ -0,0 +1,138 @@
+// Package project finds the files of a project that are worth analyzing.
+package project
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/ignore"
+	"github.com/anthonycursewl/anx-agent/internal/utils"
+)
+
+// File is a text file found in the project.
+type File struct {
+	Path    string // Path relative to the analyzed directory, with forward slashes
+	Content []byte
+	Ext     string // Extension without the dot, e.g. "go"
+}
+
+// Skipped is a file left out of the analysis and why.
+type Skipped struct {
+	Path   string
+	Reason string
+}
+
+// DiscoverFiles lists the text files under rootPath, which may also be a
+// single file. Files ignored by .gitignore, .anxignore or ignorePaths
+// (gitignore patterns) are left out, and so are binary files and files
+// larger than maxFileSizeKB when it is positive. When extensions is not
+// empty, only files with one of them are kept.
+func DiscoverFiles(rootPath string, extensions, ignorePaths []string, maxFileSizeKB int) ([]File, []Skipped, error) {
+	info, err := os.Stat(rootPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error accessing '%s': %w", rootPath, err)
+	}
+
+	wanted := map[string]bool{}
+	for _, ext := range extensions {
+		if ext = strings.TrimPrefix(strings.TrimSpace(ext), "."); ext != "" {
+			wanted[strings.ToLower(ext)] = true
+		}
+	}
+	var files []File
+	var skipped []Skipped
+	read := func(path, rel string, size int64) error {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+		if len(wanted) > 0 && !wanted[ext] {
+			return nil
+		}
+		if maxFileSizeKB > 0 && size > int64(maxFileSizeKB)*1024 {
+			skipped = append(skipped, Skipped{Path: rel, Reason: "larger than " + utils.FormatSize(int64(maxFileSizeKB)*1024)})
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading file '%s': %w", path, err)
+		}
+		if utils.IsBinary(content) {
+			skipped = append(skipped, Skipped{Path: rel, Reason: "binary"})
+			return nil
+		}
+		files = append(files, File{Path: rel, Content: content, Ext: ext})
+		return nil
+	}
+
+	if !info.IsDir() {
+		if err := read(rootPath, filepath.ToSlash(filepath.Base(rootPath)), info.Size()); err != nil {
+			return nil, nil, err
+		}
+		return files, skipped, nil
+	}
+
+	extra := ignore.New(rootPath)
+	for _, p := range ignorePaths {
+		if p = strings.TrimSpace(p); p != "" {
+			extra.Add("", p)
+		}
+	}
+	err = ignore.Walk(rootPath, func(rel string, d fs.DirEntry) error {
+		if extra.Ignored(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		return read(filepath.Join(rootPath, filepath.FromSlash(rel)), rel, info.Size())
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return files, skipped, nil
+}
+
+// CollectFiles runs DiscoverFiles on each of paths, keeping the paths of the
+// files relative to the current directory rather than to their root, so
+// that they can be opened. A file found twice is kept once.
+func CollectFiles(paths, extensions, ignorePaths []string, maxFileSizeKB int) ([]File, []Skipped, error) {
+	var files []File
+	var skipped []Skipped
+	seen := map[string]bool{}
+	for _, root := range paths {
+		found, left, err := DiscoverFiles(root, extensions, ignorePaths, maxFileSizeKB)
+		if err != nil {
+			return nil, nil, err
+		}
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error accessing '%s': %w", root, err)
+		}
+		rebase := func(rel string) string {
+			if !info.IsDir() {
+				return filepath.ToSlash(filepath.Clean(root))
+			}
+			return filepath.ToSlash(filepath.Join(root, filepath.FromSlash(rel)))
+		}
+		for _, f := range found {
+			f.Path = rebase(f.Path)
+			if !seen[f.Path] {
+				seen[f.Path] = true
+				files = append(files, f)
+			}
+		}
+		for _, s := range left {
+			s.Path = rebase(s.Path)
+			skipped = append(skipped, s)
+		}
+	}
+	return files, skipped, nil
+}
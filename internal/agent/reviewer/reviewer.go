@@ -0,0 +1,425 @@
+// Package reviewer asks a model to review files or diffs and collects its
+// answers as structured findings.
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/agent/chunker"
+	"github.com/anthonycursewl/anx-agent/internal/agent/project"
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/diff"
+)
+
+// DefaultMaxTokensPerChunk bounds the code sent in one request.
+const DefaultMaxTokensPerChunk = 6000
+
+// Severity ranks findings; the zero value is the least severe.
+type Severity int
+
+const (
+	Info Severity = iota
+	Low
+	Medium
+	High
+	Critical
+)
+
+var severityNames = []string{"info", "low", "medium", "high", "critical"}
+
+func (s Severity) String() string {
+	if s < Info || s > Critical {
+		return "info"
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity accepts a severity name, in any case.
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(name, n) {
+			return Severity(i), nil
+		}
+	}
+	return Info, fmt.Errorf("unknown severity '%s'; use %s", name, strings.Join(severityNames, ", "))
+}
+
+func (s Severity) MarshalJSON() ([]byte, error) { return json.Marshal(s.String()) }
+
+func (s *Severity) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	// Models sometimes answer with words of their own, such as "warning".
+	// Common ones are mapped and the others count as info rather than
+	// failing the review.
+	*s, _ = ParseSeverity(name)
+	switch strings.ToLower(name) {
+	case "error", "major":
+		*s = High
+	case "warning", "minor":
+		*s = Low
+	}
+	return nil
+}
+
+// Finding is one problem found by the review. Lines are 1-based and
+// inclusive; in a diff review they are lines of the new version.
+type Finding struct {
+	File      string   `json:"file"`
+	StartLine int      `json:"start_line"`
+	EndLine   int      `json:"end_line"`
+	Severity  Severity `json:"severity"`
+	Category  string   `json:"category"`
+	Message   string   `json:"message"`
+	Fix       string   `json:"fix,omitempty"`
+}
+
+// Lines describes the line range of the finding, e.g. "12-14".
+func (f Finding) Lines() string {
+	switch {
+	case f.StartLine <= 0:
+		return ""
+	case f.EndLine <= f.StartLine:
+		return fmt.Sprint(f.StartLine)
+	}
+	return fmt.Sprintf("%d-%d", f.StartLine, f.EndLine)
+}
+
+// Location is the file and line range of the finding, e.g. "main.go:12-14".
+func (f Finding) Location() string {
+	if lines := f.Lines(); lines != "" {
+		return f.File + ":" + lines
+	}
+	return f.File
+}
+
+// Review is the result of a review, most severe findings first.
+type Review struct {
+	Target      string    `json:"target"`
+	Model       string    `json:"model"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Files       []string  `json:"files"`
+	Findings    []Finding `json:"findings"`
+	Usage       ai.Usage  `json:"usage"`
+}
+
+// AtLeast returns the findings of severity min or higher.
+func (r *Review) AtLeast(min Severity) []Finding {
+	var out []Finding
+	for _, f := range r.Findings {
+		if f.Severity >= min {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Counts describes how many findings there are of each severity, e.g.
+// "1 high, 3 low".
+func (r *Review) Counts() string {
+	if len(r.Findings) == 0 {
+		return "no findings"
+	}
+	counts := make([]int, len(severityNames))
+	for _, f := range r.Findings {
+		counts[f.Severity]++
+	}
+	var parts []string
+	for s := Critical; s >= Info; s-- {
+		if counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Event reports the progress of a review.
+type Event struct {
+	File   string
+	Index  int // 1-based position of File
+	Total  int
+	Chunk  int // 1-based chunk of File
+	Chunks int
+}
+
+// Reviewer runs reviews with Client. Focus, when set, is what the review
+// should concentrate on, such as "security".
+type Reviewer struct {
+	Client            ai.Generator
+	MaxTokensPerChunk int
+	Focus             string
+	Progress          func(Event)
+}
+
+func (r *Reviewer) maxTokens() int {
+	if r.MaxTokensPerChunk <= 0 {
+		return DefaultMaxTokensPerChunk
+	}
+	return r.MaxTokensPerChunk
+}
+
+func (r *Reviewer) progress(e Event) {
+	if r.Progress != nil {
+		r.Progress(e)
+	}
+}
+
+// ReviewFiles reviews whole files.
+func (r *Reviewer) ReviewFiles(ctx context.Context, target string, files []project.File) (*Review, error) {
+	review := newReview(target)
+	for i, file := range files {
+		review.Files = append(review.Files, file.Path)
+		chunks, err := chunker.ChunkContent(file.Content, r.maxTokens(), ai.EstimateTokens)
+		if err != nil {
+			return nil, fmt.Errorf("error chunking '%s': %w", file.Path, err)
+		}
+		for j, chunk := range chunks {
+			r.progress(Event{File: file.Path, Index: i + 1, Total: len(files), Chunk: j + 1, Chunks: len(chunks)})
+			var start int
+			fmt.Sscan(chunk.Metadata["line_start"], &start)
+			what := fmt.Sprintf("the file `%s`", file.Path)
+			if len(chunks) > 1 {
+				what = fmt.Sprintf("lines %s-%s of the file `%s`", chunk.Metadata["line_start"], chunk.Metadata["line_end"], file.Path)
+			}
+			prompt := r.prompt(what, "Each line starts with its number.", numbered(chunk.Content, start))
+			text, err := r.ask(ctx, review, prompt)
+			if err != nil {
+				return nil, fmt.Errorf("error reviewing '%s': %w", file.Path, err)
+			}
+			var end int
+			fmt.Sscan(chunk.Metadata["line_end"], &end)
+			findings, err := parseFindings(file.Path, text)
+			if err != nil {
+				findings = []Finding{unreviewed(file.Path, start, end, err)}
+			}
+			review.Findings = append(review.Findings, findings...)
+		}
+	}
+	review.sort()
+	return review, nil
+}
+
+// ReviewPatches reviews the changes of a diff, one file at a time, with as
+// many hunks per request as fit in a chunk. Findings about the context
+// around the changes, rather than the added lines, are left out.
+func (r *Reviewer) ReviewPatches(ctx context.Context, target string, patches []diff.FilePatch) (*Review, error) {
+	review := newReview(target)
+	for i, patch := range patches {
+		path := patch.Path()
+		review.Files = append(review.Files, path)
+		if patch.NewPath == "" {
+			// A deleted file adds nothing to review.
+			continue
+		}
+		inserted := insertedLines(patch.Hunks)
+		chunks := hunkChunks(patch.Hunks, r.maxTokens())
+		for j, chunk := range chunks {
+			r.progress(Event{File: path, Index: i + 1, Total: len(patches), Chunk: j + 1, Chunks: len(chunks)})
+			what := fmt.Sprintf("a change to the file `%s`", path)
+			if patch.OldPath == "" {
+				what = fmt.Sprintf("the new file `%s`", path)
+			}
+			prompt := r.prompt(what, "It is a unified diff where added and unchanged lines start with their number in the new version. Only review the added lines ('+'); the others are context.", chunk)
+			text, err := r.ask(ctx, review, prompt)
+			if err != nil {
+				return nil, fmt.Errorf("error reviewing '%s': %w", path, err)
+			}
+			findings, err := parseFindings(path, text)
+			if err != nil {
+				review.Findings = append(review.Findings, unreviewed(path, 0, 0, err))
+				continue
+			}
+			for _, f := range findings {
+				if touches(f, inserted) {
+					review.Findings = append(review.Findings, f)
+				}
+			}
+		}
+	}
+	review.sort()
+	return review, nil
+}
+
+func newReview(target string) *Review {
+	return &Review{Target: target, Model: ai.Model, GeneratedAt: time.Now(), Findings: []Finding{}}
+}
+
+func (r *Reviewer) prompt(what, format, code string) string {
+	focus := ""
+	if r.Focus != "" {
+		focus = "\nConcentrate on: " + r.Focus + "\n"
+	}
+	return fmt.Sprintf(`You are a senior engineer doing a code review. Below is %s. %s
+
+Report the bugs, security problems, performance problems and maintainability issues worth fixing. Do not report style preferences or things that are fine as they are.
+
+Answer with a single JSON object and nothing else, of the form {"findings": [...]}, where each finding has:
+- "start_line", "end_line": the lines it concerns
+- "severity": "critical", "high", "medium", "low" or "info"
+- "category": one of "bug", "security", "performance", "maintainability", "error-handling", "concurrency", "docs" or "tests"
+- "message": what is wrong and why it matters, in one or two sentences
+- "fix": how to fix it, with a code snippet if it helps
+Answer {"findings": []} if there is nothing to report.
+%s
+--- CODE ---
+%s`, what, format, focus, code)
+}
+
+// ask sends prompt, counts its usage in review and returns the answer.
+func (r *Reviewer) ask(ctx context.Context, review *Review, prompt string) (string, error) {
+	reply, err := r.Client.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	review.Usage.Prompt += reply.Usage.Prompt
+	review.Usage.Output += reply.Usage.Output
+	review.Usage.Total += reply.Usage.Total
+	return reply.Text, nil
+}
+
+// parseFindings reads the findings about file in the answer of the model.
+func parseFindings(file, text string) ([]Finding, error) {
+	var answer struct {
+		Findings []Finding `json:"findings"`
+	}
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("the answer is not a JSON object: %s", truncate(text, 200))
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), &answer); err != nil {
+		return nil, fmt.Errorf("the answer is not valid JSON: %w", err)
+	}
+	findings := make([]Finding, 0, len(answer.Findings))
+	for _, f := range answer.Findings {
+		f.File = file
+		f.Category = strings.ToLower(strings.TrimSpace(f.Category))
+		if f.EndLine < f.StartLine {
+			f.EndLine = f.StartLine
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+// unreviewed is the info finding left in place of the findings of an answer
+// that could not be read, so that one bad answer does not fail the review.
+func unreviewed(file string, start, end int, err error) Finding {
+	return Finding{
+		File:      file,
+		StartLine: start,
+		EndLine:   end,
+		Severity:  Info,
+		Category:  "review",
+		Message:   "This part was not reviewed: " + err.Error(),
+	}
+}
+
+func (r *Review) sort() {
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		a, b := r.Findings[i], r.Findings[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.StartLine < b.StartLine
+	})
+}
+
+// numbered prefixes each line of code with its number, counting from start.
+func numbered(code string, start int) string {
+	var sb strings.Builder
+	for i, line := range diff.SplitLines(code) {
+		fmt.Fprintf(&sb, "%5d| %s", start+i, line)
+		if !strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// insertedLines returns the numbers, in the new version, of the lines that
+// hunks add.
+func insertedLines(hunks []diff.Hunk) map[int]bool {
+	lines := map[int]bool{}
+	for _, h := range hunks {
+		n := h.NewStart
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case diff.Insert:
+				lines[n] = true
+				n++
+			case diff.Equal:
+				n++
+			}
+		}
+	}
+	return lines
+}
+
+// touches reports whether the lines of f include an inserted line. A
+// finding without lines is about the change as a whole and is kept.
+func touches(f Finding, inserted map[int]bool) bool {
+	if f.StartLine <= 0 {
+		return true
+	}
+	for n := range inserted {
+		if n >= f.StartLine && n <= f.EndLine {
+			return true
+		}
+	}
+	return false
+}
+
+// hunkChunks renders hunks with the numbers of their lines in the new
+// version, grouping as many hunks as fit in maxTokens.
+func hunkChunks(hunks []diff.Hunk, maxTokens int) []string {
+	var chunks []string
+	var sb strings.Builder
+	tokens := 0
+	for _, h := range hunks {
+		var hb strings.Builder
+		hb.WriteString(h.Header() + "\n")
+		n := h.NewStart
+		for _, l := range h.Lines {
+			text := strings.TrimSuffix(l.Text, "\n")
+			switch l.Kind {
+			case diff.Delete:
+				fmt.Fprintf(&hb, "     - %s\n", text)
+			case diff.Insert:
+				fmt.Fprintf(&hb, "%5d + %s\n", n, text)
+				n++
+			default:
+				fmt.Fprintf(&hb, "%5d   %s\n", n, text)
+				n++
+			}
+		}
+		t := ai.EstimateTokens(hb.String())
+		if tokens > 0 && tokens+t > maxTokens {
+			chunks = append(chunks, sb.String())
+			sb.Reset()
+			tokens = 0
+		}
+		sb.WriteString(hb.String())
+		tokens += t
+	}
+	if sb.Len() > 0 {
+		chunks = append(chunks, sb.String())
+	}
+	return chunks
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,72 @@
+package reviewer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/anthonycursewl/anx-agent/internal/agent/project"
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/diff"
+)
+
+// generator answers with fn, called with each prompt.
+type generator func(prompt string) (string, error)
+
+func (g generator) Generate(ctx context.Context, prompt string) (ai.Reply, error) {
+	text, err := g(prompt)
+	return ai.Reply{Text: text}, err
+}
+
+func TestReviewFilesKeepsGoingAfterAnUnreadableAnswer(t *testing.T) {
+	r := &Reviewer{Client: generator(func(prompt string) (string, error) {
+		if strings.Contains(prompt, "`bad.go`") {
+			return "Sorry, I cannot help with that.", nil
+		}
+		return `{"findings": [{"start_line": 2, "severity": "high", "category": "Bug", "message": "Wrong."}]}`, nil
+	})}
+	files := []project.File{
+		{Path: "bad.go", Content: []byte("package p\n")},
+		{Path: "good.go", Content: []byte("package p\n\nvar x = 1\n")},
+	}
+	review, err := r.ReviewFiles(context.Background(), "test", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(review.Findings) != 2 {
+		t.Fatalf("got findings %+v, want 2", review.Findings)
+	}
+	got, skipped := review.Findings[0], review.Findings[1]
+	if got.File != "good.go" || got.Severity != High || got.Category != "bug" || got.Lines() != "2" {
+		t.Errorf("unexpected finding %+v", got)
+	}
+	if skipped.File != "bad.go" || skipped.Severity != Info || !strings.Contains(skipped.Message, "not reviewed") {
+		t.Errorf("unexpected finding for the unreadable answer %+v", skipped)
+	}
+}
+
+func TestReviewPatchesKeepsFindingsOnAddedLines(t *testing.T) {
+	patches, err := diff.ParsePatch(diff.Unified("a/f.go", "b/f.go", "1\n2\n3\n4\n5\n6\n", "1\n2\nthree\n4\n5\n6\n", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Reviewer{Client: generator(func(string) (string, error) {
+		return `{"findings": [
+			{"start_line": 3, "severity": "high", "message": "On the added line."},
+			{"start_line": 1, "end_line": 4, "severity": "low", "message": "Around the added line."},
+			{"start_line": 5, "severity": "critical", "message": "On the context."},
+			{"start_line": 40, "end_line": 1000000000, "severity": "medium", "message": "Outside the change."}
+		]}`, nil
+	})}
+	review, err := r.ReviewPatches(context.Background(), "test", patches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range review.Findings {
+		got = append(got, f.Message)
+	}
+	if want := []string{"On the added line.", "Around the added line."}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("findings %q, want %q", got, want)
+	}
+}
@@ -9,10 +9,38 @@ import (
 	"google.golang.org/api/option"
 )
 
+// Model is the Gemini model used for every request.
+const Model = "gemini-2.5-flash"
+
 type Client struct {
 	genaiClient *genai.Client
 }
 
+// Turn is one message of a conversation. Role is "user" or "model".
+type Turn struct {
+	Role string `json:"role"`
+	Text string `json:"text"`
+}
+
+// Usage is the number of tokens a request consumed, as reported by the API.
+type Usage struct {
+	Prompt int `json:"prompt"`
+	Output int `json:"output"`
+	Total  int `json:"total"`
+}
+
+// Reply is the text of a response along with its token usage.
+type Reply struct {
+	Text  string
+	Usage Usage
+}
+
+// Generator sends single prompts. Client implements it; commands that only
+// need to generate text depend on it instead.
+type Generator interface {
+	Generate(ctx context.Context, prompt string) (Reply, error)
+}
+
 func NewClient(apiKey string) (*Client, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key is required")
@@ -29,27 +57,58 @@ func NewClient(apiKey string) (*Client, error) {
 }
 
 func (c *Client) GetResponse(prompt string) (string, error) {
-	return c.CallModel(prompt)
+	return c.CallModel(context.Background(), prompt)
+}
+
+func (c *Client) CallModel(ctx context.Context, prompt string) (string, error) {
+	reply, err := c.Generate(ctx, prompt)
+	return reply.Text, err
 }
 
-func (c *Client) CallModel(prompt string) (string, error) {
+// Generate sends a single prompt and returns the reply with its usage.
+func (c *Client) Generate(ctx context.Context, prompt string) (Reply, error) {
 	if c.genaiClient == nil {
-		return "", fmt.Errorf("AI client not initialized")
+		return Reply{}, fmt.Errorf("AI client not initialized")
 	}
 
-	ctx := context.Background()
-	model := c.genaiClient.GenerativeModel("gemini-2.5-flash")
+	model := c.genaiClient.GenerativeModel(Model)
 	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
 
 	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
+		return Reply{}, fmt.Errorf("failed to generate content: %w", err)
+	}
+	return responseReply(resp)
+}
+
+// Chat sends prompt as the next message of a conversation that already went
+// through history.
+func (c *Client) Chat(ctx context.Context, history []Turn, prompt string) (Reply, error) {
+	if c.genaiClient == nil {
+		return Reply{}, fmt.Errorf("AI client not initialized")
 	}
 
-	if len(resp.Candidates) > 0 && len(resp.Candidates[0].Content.Parts) > 0 {
-		return fmt.Sprintf("%s", resp.Candidates[0].Content.Parts[0]), nil
+	session := c.genaiClient.GenerativeModel(Model).StartChat()
+	for _, turn := range history {
+		session.History = append(session.History, &genai.Content{Role: turn.Role, Parts: []genai.Part{genai.Text(turn.Text)}})
+	}
+	resp, err := session.SendMessage(ctx, genai.Text(prompt))
+	if err != nil {
+		return Reply{}, fmt.Errorf("failed to generate content: %w", err)
+	}
+	return responseReply(resp)
+}
+
+func responseReply(resp *genai.GenerateContentResponse) (Reply, error) {
+	var reply Reply
+	if u := resp.UsageMetadata; u != nil {
+		reply.Usage = Usage{Prompt: int(u.PromptTokenCount), Output: int(u.CandidatesTokenCount), Total: int(u.TotalTokenCount)}
+	}
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil && len(resp.Candidates[0].Content.Parts) > 0 {
+		reply.Text = fmt.Sprintf("%s", resp.Candidates[0].Content.Parts[0])
+		return reply, nil
 	}
 
-	return "", fmt.Errorf("no text content found in response")
+	return reply, fmt.Errorf("no text content found in response")
 }
 
 func (c *Client) Close() error {
@@ -0,0 +1,14 @@
+package ai
+
+import "unicode/utf8"
+
+// EstimateTokens gives a rough token count for text, using the common
+// approximation of four characters per token. It is meant for budgeting
+// prompts, not for billing.
+func EstimateTokens(text string) int {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return 0
+	}
+	return (n + 3) / 4
+}
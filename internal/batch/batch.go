@@ -0,0 +1,237 @@
+// Package batch reads task files, which describe many AI tasks at once, and
+// keeps the state that lets a run resume after failures.
+package batch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of tasks.
+const (
+	KindGenerate = "generate"
+	KindModify   = "modify"
+	KindAnalyze  = "analyze"
+)
+
+// Paths is a list of paths or glob patterns, written in YAML as a list or
+// as a single string.
+type Paths []string
+
+func (p *Paths) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*p = Paths{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*p = list
+	return nil
+}
+
+// Task is one entry of a task file. Exactly one of Generate, Modify and
+// Analyze is set:
+//
+//   - generate: the file to create following Instruction
+//   - modify: the files to rewrite following Instruction
+//   - analyze: the files to answer Question about, in Output if set
+//
+// Context lists files shared as reference. Paths are relative to the
+// directory of the task file.
+type Task struct {
+	Name        string `yaml:"name" json:"name"`
+	Generate    string `yaml:"generate,omitempty" json:"generate,omitempty"`
+	Modify      Paths  `yaml:"modify,omitempty" json:"modify,omitempty"`
+	Analyze     Paths  `yaml:"analyze,omitempty" json:"analyze,omitempty"`
+	Instruction string `yaml:"instruction,omitempty" json:"instruction,omitempty"`
+	Question    string `yaml:"question,omitempty" json:"question,omitempty"`
+	Context     Paths  `yaml:"context,omitempty" json:"context,omitempty"`
+	Output      string `yaml:"output,omitempty" json:"output,omitempty"`
+	Force       bool   `yaml:"force,omitempty" json:"force,omitempty"`
+}
+
+// Kind is generate, modify or analyze.
+func (t Task) Kind() string {
+	switch {
+	case t.Generate != "":
+		return KindGenerate
+	case len(t.Modify) > 0:
+		return KindModify
+	case len(t.Analyze) > 0:
+		return KindAnalyze
+	}
+	return ""
+}
+
+// Hash identifies the definition of the task, so that a task changed since
+// it succeeded runs again on resume.
+func (t Task) Hash() string {
+	data, _ := json.Marshal(t)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func (t Task) validate() error {
+	kinds := 0
+	for _, set := range []bool{t.Generate != "", len(t.Modify) > 0, len(t.Analyze) > 0} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return errors.New("set exactly one of generate, modify and analyze")
+	}
+	switch t.Kind() {
+	case KindGenerate, KindModify:
+		if strings.TrimSpace(t.Instruction) == "" {
+			return fmt.Errorf("%s needs an instruction", t.Kind())
+		}
+		if t.Question != "" {
+			return fmt.Errorf("question only applies to analyze")
+		}
+		if t.Output != "" {
+			return fmt.Errorf("output only applies to analyze")
+		}
+	case KindAnalyze:
+		if strings.TrimSpace(t.Question) == "" {
+			return errors.New("analyze needs a question")
+		}
+		if t.Instruction != "" {
+			return errors.New("instruction does not apply to analyze; use question")
+		}
+	}
+	if t.Force && t.Kind() != KindGenerate {
+		return errors.New("force only applies to generate")
+	}
+	return nil
+}
+
+// Spec is a task file. Jobs bounds how many tasks run at the same time.
+type Spec struct {
+	Jobs  int    `yaml:"jobs"`
+	Tasks []Task `yaml:"tasks"`
+
+	// Path is where the spec was read from and Dir the directory its paths
+	// are relative to.
+	Path string `yaml:"-"`
+	Dir  string `yaml:"-"`
+}
+
+// Load reads and checks a task file. Tasks without a name are named after
+// their position and kind, e.g. "2-modify".
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading task file '%s': %w", path, err)
+	}
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	var spec Spec
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("error parsing task file '%s': %w", path, err)
+	}
+	spec.Path = path
+	spec.Dir = filepath.Dir(path)
+	if spec.Jobs < 0 {
+		return nil, fmt.Errorf("%s: jobs must be at least 1", path)
+	}
+	if len(spec.Tasks) == 0 {
+		return nil, fmt.Errorf("%s: no tasks", path)
+	}
+
+	names := map[string]bool{}
+	for i := range spec.Tasks {
+		t := &spec.Tasks[i]
+		if err := t.validate(); err != nil {
+			label := t.Name
+			if label == "" {
+				label = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("%s: task %s: %w", path, label, err)
+		}
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("%d-%s", i+1, t.Kind())
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("%s: more than one task is named '%s'", path, t.Name)
+		}
+		names[t.Name] = true
+	}
+	return &spec, nil
+}
+
+// Statuses of a task in the state file.
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// TaskState is the outcome of the last run of a task.
+type TaskState struct {
+	Status   string    `json:"status"`
+	Hash     string    `json:"hash"`
+	Error    string    `json:"error,omitempty"`
+	Tokens   int       `json:"tokens"`
+	Files    []string  `json:"files,omitempty"`
+	Finished time.Time `json:"finished"`
+}
+
+// State records the outcome of the tasks of a task file, by name.
+type State struct {
+	Tasks map[string]TaskState `json:"tasks"`
+}
+
+// StatePath is where the state of the task file at path is kept by
+// default: tasks.yaml has tasks.state.json next to it.
+func StatePath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".state.json"
+}
+
+// LoadState reads a state file; a missing one is an empty state.
+func LoadState(path string) (*State, error) {
+	state := &State{Tasks: map[string]TaskState{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("error parsing state file '%s': %w", path, err)
+	}
+	if state.Tasks == nil {
+		state.Tasks = map[string]TaskState{}
+	}
+	return state, nil
+}
+
+// Done reports whether t succeeded in the last run without having changed
+// since.
+func (s *State) Done(t Task) bool {
+	st, ok := s.Tasks[t.Name]
+	return ok && st.Status == StatusOK && st.Hash == t.Hash()
+}
+
+// Save writes the state to path.
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding state: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing state file '%s': %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeSpec(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tasks.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeSpec(t, `jobs: 2
+tasks:
+  - name: gen
+    generate: out/new.go
+    instruction: write it
+    context: src/a.go
+  - modify: [src/a.go, "src/*.go"]
+    instruction: rename
+  - analyze: src
+    question: what does it do?
+    output: report.md
+`)
+	spec, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Jobs != 2 || spec.Path != path || spec.Dir != filepath.Dir(path) || len(spec.Tasks) != 3 {
+		t.Fatalf("unexpected spec %+v", spec)
+	}
+	var names, kinds []string
+	for _, task := range spec.Tasks {
+		names = append(names, task.Name)
+		kinds = append(kinds, task.Kind())
+	}
+	if want := []string{"gen", "2-modify", "3-analyze"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names %q, want %q", names, want)
+	}
+	if want := []string{KindGenerate, KindModify, KindAnalyze}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("kinds %q, want %q", kinds, want)
+	}
+	if got := spec.Tasks[0].Context; !reflect.DeepEqual(got, Paths{"src/a.go"}) {
+		t.Errorf("a single path was read as %q", got)
+	}
+	if got := spec.Tasks[1].Modify; !reflect.DeepEqual(got, Paths{"src/a.go", "src/*.go"}) {
+		t.Errorf("a list of paths was read as %q", got)
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name, spec, want string
+	}{
+		{"no tasks", "jobs: 1\n", "no tasks"},
+		{"negative jobs", "jobs: -1\ntasks:\n  - analyze: a\n    question: q\n", "jobs must be at least 1"},
+		{"unknown field", "tasks:\n  - analyze: a\n    question: q\n    promt: x\n", "promt"},
+		{"two kinds", "tasks:\n  - generate: a\n    modify: b\n    instruction: x\n", "exactly one"},
+		{"no kind", "tasks:\n  - instruction: x\n", "exactly one"},
+		{"no instruction", "tasks:\n  - modify: a\n", "needs an instruction"},
+		{"no question", "tasks:\n  - analyze: a\n", "needs a question"},
+		{"instruction on analyze", "tasks:\n  - analyze: a\n    question: q\n    instruction: x\n", "use question"},
+		{"output on modify", "tasks:\n  - modify: a\n    instruction: x\n    output: r.md\n", "output only applies"},
+		{"force on modify", "tasks:\n  - modify: a\n    instruction: x\n    force: true\n", "force only applies"},
+		{"duplicate names", "tasks:\n  - name: t\n    analyze: a\n    question: q\n  - name: t\n    analyze: b\n    question: q\n", "more than one task is named 't'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeSpec(t, tt.spec))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("loading a missing file should fail")
+	}
+}
+
+func TestStateDone(t *testing.T) {
+	task := Task{Name: "t", Analyze: Paths{"a"}, Question: "q"}
+	changed := task
+	changed.Question = "another question"
+	tests := []struct {
+		name  string
+		state TaskState
+		task  Task
+		want  bool
+	}{
+		{"succeeded", TaskState{Status: StatusOK, Hash: task.Hash()}, task, true},
+		{"failed", TaskState{Status: StatusFailed, Hash: task.Hash()}, task, false},
+		{"changed since", TaskState{Status: StatusOK, Hash: task.Hash()}, changed, false},
+	}
+	for _, tt := range tests {
+		s := &State{Tasks: map[string]TaskState{"t": tt.state}}
+		if got := s.Done(tt.task); got != tt.want {
+			t.Errorf("%s: Done = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if (&State{Tasks: map[string]TaskState{}}).Done(task) {
+		t.Error("a task that never ran is done")
+	}
+}
+
+func TestStateSaveAndLoad(t *testing.T) {
+	path := StatePath(filepath.Join(t.TempDir(), "tasks.yaml"))
+	if filepath.Base(path) != "tasks.state.json" {
+		t.Errorf("StatePath = %s", path)
+	}
+	state, err := LoadState(path)
+	if err != nil || len(state.Tasks) != 0 {
+		t.Fatalf("missing state file: %+v, %v; want an empty state", state, err)
+	}
+	task := Task{Name: "t", Generate: "a.go", Instruction: "x"}
+	state.Tasks[task.Name] = TaskState{Status: StatusOK, Hash: task.Hash(), Tokens: 7, Files: []string{"a.go"}}
+	if err := state.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Done(task) || loaded.Tasks["t"].Tokens != 7 {
+		t.Errorf("unexpected state after a round trip %+v", loaded)
+	}
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// action is something the explorer can do to the highlighted entry or the
+// current directory. Actions are bound to keys and listed in the help and the
+// command palette.
+type action struct {
+	name        string
+	keys        []string
+	description string
+	run         func(m *model) tea.Cmd
+}
+
+var explorerActions = []action{
+	{name: "back", keys: []string{"q", "esc"}, description: "Return to the chat", run: func(m *model) tea.Cmd {
+		m.mode = modeChat
+		return nil
+	}},
+	{name: "open", keys: []string{"enter"}, description: "Open the directory or modify the file with AI", run: (*model).openSelected},
+	{name: "context", keys: []string{"x"}, description: "Add or remove the file from the context", run: (*model).toggleSelectedContext},
+	{name: "create", keys: []string{"c"}, description: "Create an empty file", run: func(m *model) tea.Cmd {
+		m.mode = modeCreateFileInput
+		m.textInput.Placeholder = "New file name (empty)..."
+		m.textInput.Focus()
+		return textarea.Blink
+	}},
+	{name: "generate", keys: []string{"a"}, description: "Create a file with AI", run: func(m *model) tea.Cmd {
+		if !m.contextFiles.empty() {
+			m.addMessage(roleInfo, "💡 Note: The next file will be created using the stored context ("+m.contextFiles.summary()+").")
+		}
+		m.mode = modeAIFilenameInput
+		m.textInput.Placeholder = "File name to generate by AI..."
+		m.textInput.Focus()
+		return textarea.Blink
+	}},
+	{name: "mkdir", keys: []string{"n"}, description: "Create a directory", run: func(m *model) tea.Cmd { return m.fileOpKey("n") }},
+	{name: "rename", keys: []string{"r"}, description: "Rename the entry", run: func(m *model) tea.Cmd { return m.fileOpKey("r") }},
+	{name: "move", keys: []string{"m"}, description: "Move the entry to another directory or path", run: func(m *model) tea.Cmd { return m.fileOpKey("m") }},
+	{name: "copy", keys: []string{"y"}, description: "Copy the entry to another directory or path", run: func(m *model) tea.Cmd { return m.fileOpKey("y") }},
+	{name: "duplicate", keys: []string{"p"}, description: "Duplicate the entry next to itself", run: func(m *model) tea.Cmd { return m.fileOpKey("p") }},
+	{name: "delete", keys: []string{"D"}, description: "Delete the entry (kept in .anx/trash, 'undo' restores it)", run: func(m *model) tea.Cmd { return m.fileOpKey("D") }},
+	{name: "find", keys: []string{"/", "ctrl+p"}, description: "Find a file anywhere in the workspace", run: (*model).openFinder},
+	{name: "sort", keys: []string{"s"}, description: "Sort by name, size or modification time", run: func(m *model) tea.Cmd {
+		m.sortBy = (m.sortBy + 1) % len(sortNames)
+		return m.relistDirectory()
+	}},
+	{name: "hidden", keys: []string{"."}, description: "Show or hide hidden files", run: func(m *model) tea.Cmd {
+		m.showHidden = !m.showHidden
+		return m.relistDirectory()
+	}},
+	{name: "ignored", keys: []string{"i"}, description: "Show or hide files ignored by .gitignore/.anxignore", run: func(m *model) tea.Cmd {
+		m.showIgnored = !m.showIgnored
+		return m.relistDirectory()
+	}},
+}
+
+func findAction(actions []action, name string) (action, bool) {
+	for _, a := range actions {
+		if a.name == name {
+			return a, true
+		}
+	}
+	return action{}, false
+}
+
+// actionBindings lists the explorer actions with their main key for the
+// list help.
+func actionBindings(k keyMap) []key.Binding {
+	bindings := make([]key.Binding, 0, len(explorerActions)+1)
+	for _, a := range explorerActions {
+		bindings = append(bindings, key.NewBinding(key.WithKeys(k.keys(scopeExplorer, a.name)...), key.WithHelp(k.hint(scopeExplorer, a.name), a.name)))
+	}
+	down, up := k.hint(scopeExplorer, "scroll-down"), k.hint(scopeExplorer, "scroll-up")
+	return append(bindings, key.NewBinding(key.WithKeys(down, up), key.WithHelp(down+"/"+up, "scroll preview")))
+}
+
+func actionsHelp(title string, k keyMap) string {
+	var sb strings.Builder
+	sb.WriteString(title + "\n")
+	for _, a := range explorerActions {
+		sb.WriteString(fmt.Sprintf("  %-18s %-15s %s\n", k.describe(scopeExplorer, a.name), a.name, a.description))
+	}
+	for _, b := range explorerBindings {
+		sb.WriteString(fmt.Sprintf("  %-18s %-15s %s\n", k.describe(scopeExplorer, b.name), b.name, b.description))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (m *model) openSelected() tea.Cmd {
+	selectedItem, ok := m.list.SelectedItem().(item)
+	if !ok {
+		return nil
+	}
+
+	targetPath := filepath.Join(m.currentPath, selectedItem.path)
+	if selectedItem.path == ".." {
+		targetPath = filepath.Dir(m.currentPath)
+	}
+
+	if selectedItem.isDir {
+		return m.listDirectory(targetPath)
+	}
+	m.loading = true
+	m.addMessage(roleInfo, "Reading file "+targetPath+" to modify...")
+	return m.readFileContent(targetPath)
+}
+
+func (m *model) toggleSelectedContext() tea.Cmd {
+	selectedItem, ok := m.list.SelectedItem().(item)
+	if !ok || selectedItem.isDir {
+		return nil
+	}
+	targetPath := filepath.Join(m.currentPath, selectedItem.path)
+	if m.contextFiles.remove(targetPath) {
+		m.addMessage(roleInfo, "Removed '"+filepath.Base(targetPath)+"' from the context ("+m.contextFiles.summary()+").")
+		return nil
+	}
+	m.loading = true
+	m.addMessage(roleInfo, "Reading file "+targetPath+" for context...")
+	return m.readFileForContext(targetPath)
+}
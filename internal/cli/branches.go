@@ -0,0 +1,357 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/session"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// branch is one line of a conversation tree. The transcript and turns of the
+// current branch live in the model and are copied here when switching away.
+type branch struct {
+	id           int
+	parent       int
+	title        string
+	forkedAt     int
+	created      time.Time
+	messages     []message
+	conversation []ai.Turn
+}
+
+// branchesPanel is the state of the 'branches' view.
+type branchesPanel struct {
+	cursor     int
+	returnMode int
+}
+
+func mainBranch() *branch {
+	return &branch{id: 1, title: "main", created: time.Now()}
+}
+
+func (m *model) findBranch(id int) *branch {
+	for _, b := range m.branches {
+		if b.id == id {
+			return b
+		}
+	}
+	return nil
+}
+
+func (m *model) messageIndex(id int) int {
+	for i, msg := range m.messages {
+		if msg.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// conversationUntil rebuilds the turns sent to the AI by the first n
+// messages: every chat reply along with the input it answers.
+func (m *model) conversationUntil(n int) []ai.Turn {
+	if n >= len(m.messages) {
+		return append([]ai.Turn(nil), m.conversation...)
+	}
+	inputs := map[int]string{}
+	var turns []ai.Turn
+	for _, msg := range m.messages[:n] {
+		switch {
+		case msg.role == roleUser:
+			inputs[msg.id] = msg.content
+		case msg.replyTo != 0:
+			input, ok := inputs[msg.replyTo]
+			if !ok {
+				continue
+			}
+			turns = append(turns, ai.Turn{Role: "user", Text: input}, ai.Turn{Role: "model", Text: msg.content})
+		}
+	}
+	return turns
+}
+
+// storeBranch copies the live transcript into the current branch.
+func (m *model) storeBranch() {
+	if b := m.findBranch(m.branch); b != nil {
+		b.messages = append([]message(nil), m.messages...)
+		b.conversation = append([]ai.Turn(nil), m.conversation...)
+	}
+}
+
+// fork starts a branch sharing the first n messages of the current one and
+// switches to it. The current branch is kept as it is.
+func (m *model) fork(n int, title string) *branch {
+	conversation := m.conversationUntil(n)
+	m.storeBranch()
+	b := &branch{id: 1, parent: m.branch, title: title, forkedAt: n, created: time.Now()}
+	for _, other := range m.branches {
+		if other.id >= b.id {
+			b.id = other.id + 1
+		}
+	}
+	m.branches = append(m.branches, b)
+	m.branch = b.id
+	m.messages = append([]message(nil), m.messages[:n]...)
+	m.conversation = conversation
+	m.layout.chatScroll = 0
+	return b
+}
+
+func (m *model) switchBranch(id int) error {
+	target := m.findBranch(id)
+	if target == nil {
+		return fmt.Errorf("no branch #%d", id)
+	}
+	if id == m.branch {
+		return nil
+	}
+	m.storeBranch()
+	m.branch = id
+	m.messages = append([]message(nil), target.messages...)
+	m.conversation = append([]ai.Turn(nil), target.conversation...)
+	m.layout.chatScroll = 0
+	m.sessionDirty = true
+	return nil
+}
+
+// regenerate asks again for the chat reply at index i, on a new branch, so
+// that the previous answer is kept.
+func (m *model) regenerate(i int) tea.Cmd {
+	u := m.messageIndex(m.messages[i].replyTo)
+	if u < 0 {
+		m.addMessage(roleError, "The message this answer replies to is not in the transcript.")
+		return nil
+	}
+	input := m.messages[u]
+	previous := m.branch
+	b := m.fork(u+1, "regenerate: "+sessionTitle(input.content))
+	m.mode = modeChat
+	m.addMessage(roleInfo, fmt.Sprintf("🌿 Branch #%d: regenerating the answer. The previous one stays on branch #%d ('branches' to switch).", b.id, previous))
+	return m.chat(input.id, input.content)
+}
+
+// resend replaces the user message at index i with input on a new branch
+// and sends it again.
+func (m *model) resend(i int, input string) tea.Cmd {
+	previous := m.branch
+	b := m.fork(i, "edit: "+sessionTitle(input))
+	m.mode = modeChat
+	m.addMessage(roleInfo, fmt.Sprintf("🌿 Branch #%d: resending the edited message. The original stays on branch #%d ('branches' to switch).", b.id, previous))
+	return m.submitChat(input)
+}
+
+func (m *model) startEdit(i int) tea.Cmd {
+	m.selection.editing = i
+	m.mode = modeEditMessage
+	m.textInput.SetValue(m.messages[i].content)
+	m.resizeComposer()
+	return m.textInput.Focus()
+}
+
+func (m *model) submitEdit(input string) tea.Cmd {
+	if input == "" {
+		m.mode = modeSelect
+		return nil
+	}
+	return m.resend(m.selection.editing, input)
+}
+
+func regenerateCommand(m *model, args Args) tea.Cmd {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].replyTo != 0 {
+			return m.regenerate(i)
+		}
+	}
+	m.addMessage(roleError, "Nothing to regenerate: the chat has no answer yet.")
+	return nil
+}
+
+func branchCommand(m *model, args Args) tea.Cmd {
+	title := args.String("title")
+	if title == "" {
+		title = fmt.Sprintf("fork of #%d", m.branch)
+	}
+	// The command itself is left out of the new branch.
+	previous := m.branch
+	b := m.fork(len(m.messages)-1, title)
+	m.addMessage(roleInfo, fmt.Sprintf("🌿 Forked branch #%d from #%d. Both continue separately ('branches' to switch).", b.id, previous))
+	return nil
+}
+
+func branchesCommand(m *model, args Args) tea.Cmd {
+	if id := args.Int("id"); id != 0 {
+		if err := m.switchBranch(id); err != nil {
+			m.addMessage(roleError, err.Error())
+			return nil
+		}
+		m.addMessage(roleInfo, fmt.Sprintf("Switched to branch #%d (%s).", id, m.findBranch(id).title))
+		return nil
+	}
+	m.branchesPanel = branchesPanel{returnMode: m.mode}
+	for i, b := range m.branchTree() {
+		if b.branch.id == m.branch {
+			m.branchesPanel.cursor = i
+		}
+	}
+	m.mode = modeBranches
+	return nil
+}
+
+type treeEntry struct {
+	branch *branch
+	depth  int
+}
+
+// branchTree lists the branches depth first, children after their parent.
+func (m *model) branchTree() []treeEntry {
+	children := map[int][]*branch{}
+	for _, b := range m.branches {
+		children[b.parent] = append(children[b.parent], b)
+	}
+	var entries []treeEntry
+	var walk func(parent, depth int)
+	walk = func(parent, depth int) {
+		for _, b := range children[parent] {
+			entries = append(entries, treeEntry{branch: b, depth: depth})
+			walk(b.id, depth+1)
+		}
+	}
+	walk(0, 0)
+	return entries
+}
+
+func (m *model) branchLength(b *branch) int {
+	if b.id == m.branch {
+		return len(m.messages)
+	}
+	return len(b.messages)
+}
+
+func (m *model) updateBranches(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	tree := m.branchTree()
+	p := &m.branchesPanel
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	switch m.keys.action(scopeBranches, msg.String()) {
+	case "back":
+		m.mode = p.returnMode
+	case "up":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down":
+		if p.cursor < len(tree)-1 {
+			p.cursor++
+		}
+	case "switch":
+		b := tree[p.cursor].branch
+		if err := m.switchBranch(b.id); err != nil {
+			m.addMessage(roleError, err.Error())
+		}
+		m.mode = modeChat
+		return m, m.textInput.Focus()
+	case "delete":
+		b := tree[p.cursor].branch
+		if err := m.deleteBranch(b.id); err != nil {
+			m.addMessage(roleError, err.Error())
+			return m, nil
+		}
+		m.addMessage(roleInfo, fmt.Sprintf("Deleted branch #%d (%s).", b.id, b.title))
+		if p.cursor >= len(tree)-1 {
+			p.cursor = len(tree) - 2
+		}
+	}
+	return m, nil
+}
+
+// deleteBranch removes a branch that is neither current nor the parent of
+// another one.
+func (m *model) deleteBranch(id int) error {
+	if id == m.branch {
+		return fmt.Errorf("branch #%d is the current branch", id)
+	}
+	for _, b := range m.branches {
+		if b.parent == id {
+			return fmt.Errorf("branch #%d has branches of its own", id)
+		}
+	}
+	for i, b := range m.branches {
+		if b.id == id {
+			m.branches = append(m.branches[:i], m.branches[i+1:]...)
+			m.sessionDirty = true
+			return nil
+		}
+	}
+	return fmt.Errorf("no branch #%d", id)
+}
+
+func (m model) branchesView() string {
+	tree := m.branchTree()
+	var sb strings.Builder
+	for i, entry := range tree {
+		b := entry.branch
+		marker := "  "
+		if b.id == m.branch {
+			marker = "● "
+		}
+		indent := strings.Repeat("   ", entry.depth)
+		if entry.depth > 0 {
+			indent = strings.Repeat("   ", entry.depth-1) + "└─ "
+		}
+		detail := fmt.Sprintf("%d messages", m.branchLength(b))
+		if b.parent != 0 {
+			detail += fmt.Sprintf(", forked from #%d at message %d", b.parent, b.forkedAt)
+		}
+		line := fmt.Sprintf("%s%s#%d %s  %s", marker, indent, b.id, truncate(b.title, 40), m.styles.diffContext.Render(detail))
+		if i == m.branchesPanel.cursor {
+			sb.WriteString(m.styles.diffCursor.Render("▶ ") + line + "\n")
+		} else {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+
+	return m.styles.app.Render(lipgloss.JoinVertical(lipgloss.Left,
+		m.styles.header.Render(fmt.Sprintf("Branches (%d)", len(m.branches))),
+		sb.String(),
+		m.styles.statusBar.Width(m.width-4).Render(m.styles.statusText.Render(m.keys.footer(scopeBranches, "up/down", "select", "switch", "switch", "delete", "delete", "back", "back"))),
+	))
+}
+
+func toSessionMessages(messages []message) []session.Message {
+	out := make([]session.Message, 0, len(messages))
+	for _, msg := range messages {
+		entry := session.Message{ID: msg.id, ReplyTo: msg.replyTo, Kind: msg.role, Content: msg.content, Time: msg.time, Model: msg.model, Files: msg.files}
+		if msg.usage != (ai.Usage{}) {
+			usage := msg.usage
+			entry.Usage = &usage
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// fromSessionMessages restores messages, numbering those saved before
+// messages had IDs.
+func (m *model) fromSessionMessages(messages []session.Message) []message {
+	out := make([]message, 0, len(messages))
+	for _, msg := range messages {
+		restored := message{id: msg.ID, replyTo: msg.ReplyTo, role: msg.Kind, content: msg.Content, time: msg.Time, model: msg.Model, files: msg.Files}
+		if msg.Usage != nil {
+			restored.usage = *msg.Usage
+		}
+		if restored.id == 0 {
+			m.lastMessageID++
+			restored.id = m.lastMessageID
+		}
+		if restored.id > m.lastMessageID {
+			m.lastMessageID = restored.id
+		}
+		out = append(out, restored)
+	}
+	return out
+}
@@ -1,17 +1,24 @@
 package cli
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/anthonycursewl/anx-agent/internal/agent/reviewer"
 	"github.com/anthonycursewl/anx-agent/internal/ai"
-	"github.com/charmbracelet/bubbles/key"
+	"github.com/anthonycursewl/anx-agent/internal/inputs"
+	"github.com/anthonycursewl/anx-agent/internal/jobs"
+	"github.com/anthonycursewl/anx-agent/internal/journal"
+	"github.com/anthonycursewl/anx-agent/internal/prompts"
+	"github.com/anthonycursewl/anx-agent/internal/session"
+	"github.com/anthonycursewl/anx-agent/internal/theme"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
-	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -26,19 +33,38 @@ type styles struct {
 	statusBar     lipgloss.Style
 	statusText    lipgloss.Style
 	statusSpinner lipgloss.Style
+	diffAdd       lipgloss.Style
+	diffDel       lipgloss.Style
+	diffContext   lipgloss.Style
+	diffHunk      lipgloss.Style
+	diffCursor    lipgloss.Style
+	finderMatch   lipgloss.Style
+	selected      lipgloss.Style
+	border        lipgloss.TerminalColor
+	syntax        string
 }
 
-func defaultStyles() styles {
+func newStyles(t *theme.Theme) styles {
+	c := t.Colors
 	return styles{
 		app:           lipgloss.NewStyle().Margin(1, 2),
-		header:        lipgloss.NewStyle().Foreground(lipgloss.Color("#76D7C4")).Bold(true).MarginBottom(1),
-		userMsg:       lipgloss.NewStyle().Foreground(lipgloss.Color("#5DADE2")).MarginLeft(2),
-		aiMsg:         lipgloss.NewStyle().Foreground(lipgloss.Color("#F7DC6F")).MarginLeft(2),
-		errorMsg:      lipgloss.NewStyle().Foreground(lipgloss.Color("#E74C3C")).MarginLeft(2),
-		infoMsg:       lipgloss.NewStyle().Foreground(lipgloss.Color("#AAB7B8")).MarginLeft(2),
-		statusBar:     lipgloss.NewStyle().Border(lipgloss.NormalBorder(), true, false, false, false).BorderForeground(lipgloss.Color("#566573")).Padding(0, 1),
-		statusText:    lipgloss.NewStyle().Foreground(lipgloss.Color("#85929E")),
-		statusSpinner: lipgloss.NewStyle().Foreground(lipgloss.Color("#FAD02E")),
+		header:        t.Style(c.Accent).Bold(true).MarginBottom(1),
+		userMsg:       t.Style(c.User).MarginLeft(2),
+		aiMsg:         t.Style(c.AI).MarginLeft(2),
+		errorMsg:      t.Style(c.Error).MarginLeft(2),
+		infoMsg:       t.Style(c.Info).MarginLeft(2),
+		statusBar:     lipgloss.NewStyle().Border(lipgloss.NormalBorder(), true, false, false, false).BorderForeground(t.Color(c.Border)).Padding(0, 1),
+		statusText:    t.Style(c.Muted),
+		statusSpinner: t.Style(c.Spinner),
+		diffAdd:       t.Style(c.Added),
+		diffDel:       t.Style(c.Removed),
+		diffContext:   t.Style(c.Muted),
+		diffHunk:      t.Style(c.Accent),
+		diffCursor:    t.Style(c.Selected).Bold(true),
+		finderMatch:   t.Style(c.Match).Bold(true),
+		selected:      lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).BorderForeground(t.Color(c.Selected)).Foreground(t.Color(c.Selected)).Padding(0, 0, 0, 1),
+		border:        t.Color(c.Border),
+		syntax:        t.SyntaxStyle(),
 	}
 }
 
@@ -50,23 +76,45 @@ const (
 	modeAIPromptInput
 	modeAIModifyInput
 	modeAIAnalyzeInput
+	modeDiffReview
+	modeHistory
+	modeFinder
+	modeRenameInput
+	modeMoveInput
+	modeCopyInput
+	modeMkdirInput
+	modeConfirmDelete
+	modePalette
+	modeJobs
+	modeSelect
+	modeSaveBlockInput
+	modeBranches
+	modeEditMessage
+	modeFindings
 )
 
-type aiResponseMsg string
+type aiResponseMsg struct {
+	reply ai.Reply
+	files []string
+}
 
 type aiFileContentMsg struct {
 	fileName string
+	prompt   string
 	content  string
 }
 
 type aiModifiedContentMsg struct {
-	path    string
-	content string
+	path     string
+	prompt   string
+	original string
+	content  string
 }
 
 type fileContextReadMsg struct {
 	path    string
 	content []byte
+	refresh bool
 }
 
 type fileReadMsg struct {
@@ -80,27 +128,21 @@ type errMsg struct{ err error }
 
 func (e errMsg) Error() string { return e.err.Error() }
 
-type item struct {
-	path  string
-	isDir bool
-}
-
-func (i item) Title() string {
-	if i.isDir {
-		return "📁 " + filepath.Base(i.path)
-	}
-	return "📄 " + filepath.Base(i.path)
-}
-
-func (i item) Description() string { return i.path }
-func (i item) FilterValue() string { return i.path }
-
 type model struct {
 	aiClient                *ai.Client
+	journal                 *journal.Journal
+	gitStatus               *statusCache
 	commands                map[string]Command
 	list                    list.Model
-	textInput               textinput.Model
-	messages                []string
+	historyList             list.Model
+	textInput               textarea.Model
+	messages                []message
+	lastMessageID           int
+	branches                []*branch
+	branch                  int
+	branchesPanel           branchesPanel
+	lastReview              *reviewer.Review
+	findingsPanel           findingsPanel
 	spinner                 spinner.Model
 	loading                 bool
 	width                   int
@@ -111,116 +153,328 @@ type model struct {
 	fileCreationName        string
 	fileModificationPath    string
 	fileModificationContent string
-	fileContext             string
-}
-
-type Command struct {
-	Name        string
-	Description string
-	Execute     func(m *model, args []string) tea.Cmd
+	fileOpSource            string
+	inputHistory            *inputs.History
+	recall                  inputRecall
+	completions             []string
+	palette                 *commandPalette
+	sessions                *session.Store
+	session                 *session.Session
+	conversation            []ai.Turn
+	sessionSaveFailed       bool
+	sessionDirty            bool
+	saveSeq                 int
+	sessionWriter           *sessionWriter
+	resumeOnStart           bool
+	keys                    keyMap
+	jobs                    *jobs.Manager
+	jobsPanel               jobsPanel
+	pendingReviews          map[int]aiModifiedContentMsg
+	layout                  splitLayout
+	selection               messageSelection
+	contextFiles            contextSet
+	diffReview              *diffReview
+	finder                  *fileFinder
+	pendingSelect           string
+	preview                 viewport.Model
+	previewFile             filePreview
+	previewPath             string
+	showHidden              bool
+	showIgnored             bool
+	sortBy                  int
 }
 
 func initialModel(aiClient *ai.Client) model {
-	ti := textinput.New()
-	ti.Placeholder = "Write a message or command ('help' to show help)..."
-	ti.Focus()
-	ti.CharLimit = 1024
-	ti.Width = 50
+	ti := newComposer()
 
+	st := newStyles(theme.Current())
 	s := spinner.New()
 	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#FAD02E"))
+	s.Style = st.statusSpinner
 
 	items := []list.Item{}
-	delegate := list.NewDefaultDelegate()
-	delegate.Styles.SelectedTitle = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).BorderForeground(lipgloss.Color("#C472DA")).Foreground(lipgloss.Color("#C472DA")).Padding(0, 0, 0, 1)
-	delegate.Styles.SelectedDesc = delegate.Styles.SelectedTitle.Copy()
-	l := list.New(items, delegate, 0, 0)
+	l := list.New(items, listDelegate(st), 0, 0)
 	l.Title = "File Explorer"
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(false)
-	l.AdditionalShortHelpKeys = func() []key.Binding {
-		return []key.Binding{
-			key.NewBinding(key.WithKeys("q", "esc"), key.WithHelp("q/esc", "back")),
-			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "open/modify")),
-			key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "read for context")),
-			key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "create empty")),
-			key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "create with AI")),
-		}
-	}
+	l.KeyMap.Quit.SetEnabled(false)
 
 	m := model{
 		aiClient:    aiClient,
+		journal:     journal.Open(journal.DefaultDir),
+		gitStatus:   &statusCache{},
+		historyList: newHistoryList(),
 		textInput:   ti,
-		messages:    []string{"info:Welcome to ANX Agent. Write 'help' to show help."},
 		spinner:     s,
 		currentPath: ".",
 		list:        l,
 		mode:        modeChat,
-		styles:      defaultStyles(),
+		layout:      splitLayout{share: defaultExplorerShare},
+		branches:    []*branch{mainBranch()},
+		branch:      1,
+		styles:      st,
+		jobs:        jobs.NewManager(),
+
+		sessionWriter: &sessionWriter{},
+
+		pendingReviews: map[int]aiModifiedContentMsg{},
+	}
+
+	m.addMessage(roleInfo, "Welcome to ANX Agent. Write 'help' to show help.")
+
+	history, err := openInputHistory()
+	if err != nil {
+		m.addMessage(roleError, err.Error())
 	}
+	m.inputHistory = history
+	m.resetRecall()
 
+	store, err := openSessionStore()
+	if err != nil {
+		m.addMessage(roleError, err.Error()+". Sessions will not be saved.")
+	}
+	m.sessions = store
+	m.session = session.New(workspaceDir())
+
+	m.applyKeyMap(defaultKeyMap())
 	m.registerCommands()
 	return m
 }
 
 func (m *model) registerCommands() {
-	m.commands = map[string]Command{
-		"help": {
-			Name: "help", Description: "Show this help message",
+	commands := []Command{
+		{
+			Name: "help", Description: "Show this help message, or the usage of a command",
+			Args:    []Arg{{Name: "command", Optional: true, Description: "Command to describe"}},
 			Execute: helpCommand,
 		},
-		"ls": {
+		{
 			Name: "ls", Description: "Show the file explorer",
+			Args:    []Arg{{Name: "dir", Type: ArgDir, Optional: true, Description: "Directory to show instead of the current one"}},
 			Execute: listCommand,
 		},
-		"exit": {
-			Name: "exit", Description: "Exit the application",
-			Execute: exitCommand,
+		{
+			Name: "cd", Description: "Change the current directory",
+			Args:    []Arg{{Name: "dir", Type: ArgDir, Optional: true, Description: "Directory to change to; the workspace root if omitted"}},
+			Execute: cdCommand,
 		},
-		"quit": {
-			Name: "quit", Description: "Alias for 'exit'",
+		{
+			Name: "open", Description: "Modify a file with AI",
+			Args:    []Arg{{Name: "file", Type: ArgFile, Description: "File to modify"}},
+			Execute: openCommand,
+		},
+		{
+			Name: "cat", Description: "Show the content of a file",
+			Args:    []Arg{{Name: "file", Type: ArgFile, Description: "File to show"}},
+			Flags:   []Flag{{Name: "lines", Short: "n", Type: ArgInt, Default: "200", Description: "Maximum number of lines to show"}},
+			Execute: catCommand,
+		},
+		{
+			Name: "add", Description: "Add files matching glob patterns to the context",
+			Args:    []Arg{{Name: "glob", Type: ArgGlob, Variadic: true, Description: "File or pattern such as '*.go' or 'internal/**/*.go'"}},
+			Flags:   []Flag{{Name: "all", Short: "a", Type: ArgBool, Description: "Include files ignored by .gitignore/.anxignore"}},
+			Execute: addCommand,
+		},
+		{
+			Name: "exit", Aliases: []string{"quit"}, Description: "Exit the application",
 			Execute: exitCommand,
 		},
-		"analyze": {
-			Name: "analyze", Description: "Analyze a file whose content is stored as context (use 'x' in explorer)",
+		{
+			Name: "analyze", Description: "Analyze a file, or the files stored as context (use 'x' in explorer)",
+			Args: []Arg{
+				{Name: "file", Type: ArgFile, Optional: true, Description: "File to analyze; the context is used if omitted"},
+				{Name: "question", Optional: true, Variadic: true, Description: "What to analyze in the file"},
+			},
 			Execute: analyzeCommand,
 		},
+		{
+			Name: "review", Description: "Review files, directories, a diff or the context files, and list the findings",
+			Args:    []Arg{{Name: "path", Type: ArgPath, Optional: true, Variadic: true, Description: "Files or directories to review, or a .diff or .patch file; the context is used if omitted"}},
+			Flags: []Flag{
+				{Name: "focus", Short: "f", Description: "What the review should concentrate on, e.g. security"},
+				{Name: "diff", Short: "d", Type: ArgFile, Description: "Review the changes of this unified diff"},
+			},
+			Execute: reviewCommand,
+		},
+		{
+			Name: "findings", Description: "Browse the findings of the last review",
+			Execute: findingsCommand,
+		},
+		{
+			Name: "context", Description: "List the context files; '/context add|rm|pin|unpin|clear' to manage them",
+			Args: []Arg{
+				{Name: "action", Optional: true, Description: "list, add, rm, pin, unpin or clear"},
+				{Name: "files", Optional: true, Variadic: true, Description: "Files, patterns or context numbers for the action"},
+			},
+			Execute: contextCommand,
+		},
+		{
+			Name: "undo", Description: "Revert the last file change made by the agent",
+			Execute: undoCommand,
+		},
+		{
+			Name: "history", Description: "List all file changes and revert any of them",
+			Execute: historyCommand,
+		},
+		{
+			Name: "sessions", Description: "List saved chat sessions",
+			Execute: sessionsCommand,
+		},
+		{
+			Name: "resume", Description: "Reopen a saved session with its transcript, context and conversation",
+			Args:    []Arg{{Name: "id", Optional: true, Description: "Session ID or a unique prefix of it; the last session if omitted"}},
+			Execute: resumeCommand,
+		},
+		{
+			Name: "export", Description: "Save the conversation as Markdown, JSON or standalone HTML",
+			Args: []Arg{{Name: "file", Type: ArgPath, Optional: true, Description: "File to write; anx-<session>.<format> in the current directory if omitted"}},
+			Flags: []Flag{
+				{Name: "format", Short: "f", Description: "md, json or html; taken from the file extension if omitted"},
+				{Name: "force", Type: ArgBool, Description: "Overwrite the file if it exists"},
+			},
+			Execute: exportCommand,
+		},
+		{
+			Name: "jobs", Description: "Show running and finished jobs; '/jobs cancel <n>' to stop one",
+			Args: []Arg{
+				{Name: "action", Optional: true, Description: "'cancel' to stop a job"},
+				{Name: "id", Type: ArgInt, Optional: true, Description: "Number of the job to cancel"},
+			},
+			Execute: jobsCommand,
+		},
+		{
+			Name: "regenerate", Description: "Ask again for the last answer, keeping the previous one on its own branch",
+			Execute: regenerateCommand,
+		},
+		{
+			Name: "branch", Description: "Fork the conversation into a new branch and continue on it",
+			Args:    []Arg{{Name: "title", Optional: true, Variadic: true, Description: "Name of the branch"}},
+			Execute: branchCommand,
+		},
+		{
+			Name: "branches", Description: "Show the conversation branches as a tree, or switch to one",
+			Args:    []Arg{{Name: "id", Type: ArgInt, Optional: true, Description: "Number of the branch to switch to"}},
+			Execute: branchesCommand,
+		},
+		{
+			Name: "select", Description: "Select a message to copy it or to save or apply one of its code blocks",
+			Execute: selectCommand,
+		},
+		{
+			Name: "layout", Description: "Show the explorer and the chat side by side, or one at a time",
+			Args:    []Arg{{Name: "layout", Optional: true, Description: "split or single; toggles if omitted"}},
+			Execute: layoutCommand,
+		},
+		{
+			Name: "theme", Description: "List the color themes, or switch to one",
+			Args:    []Arg{{Name: "name", Optional: true, Description: "Built-in theme, user theme or path of a YAML theme"}},
+			Execute: themeCommand,
+		},
+		{
+			Name: "inputs", Description: "Show recent inputs; '/inputs clear [chat|modify|analyze]' to forget them",
+			Args: []Arg{
+				{Name: "action", Optional: true, Description: "'clear', or the kind of input to show"},
+				{Name: "kind", Optional: true, Description: "Kind of input to clear: chat, modify or analyze"},
+			},
+			Execute: inputsCommand,
+		},
+	}
+
+	m.commands = map[string]Command{}
+	for _, c := range commands {
+		m.commands[c.Name] = c
 	}
 }
 
-func helpCommand(m *model, args []string) tea.Cmd {
+func helpCommand(m *model, args Args) tea.Cmd {
+	if name := args.String("command"); name == "keys" {
+		m.addMessage(roleInfo, "\n"+viewsHelp(m.keys))
+		return nil
+	} else if name != "" {
+		command, ok := m.lookupCommand(name)
+		if !ok {
+			m.addMessage(roleError, "Unknown command '"+name+"'.")
+			return nil
+		}
+		m.addMessage(roleInfo, command.Help())
+		return nil
+	}
+
 	var helpBuilder strings.Builder
-	helpBuilder.WriteString("Available commands:\n")
-	for name, cmd := range m.commands {
-		helpBuilder.WriteString(fmt.Sprintf("  %-15s %s\n", name, cmd.Description))
+	helpBuilder.WriteString("Available commands ('/help <command>' for details). A command with arguments starts with a slash; on its own, as in 'help', the slash is optional:\n")
+	for _, name := range m.commandNames() {
+		command := m.commands[name]
+		description := command.Description
+		if len(command.Aliases) > 0 {
+			description += " (alias: " + strings.Join(command.Aliases, ", ") + ")"
+		}
+		helpBuilder.WriteString(fmt.Sprintf("  %-32s %s\n", command.Usage(), description))
 	}
-	m.messages = append(m.messages, "info:"+helpBuilder.String())
-	m.messages = append(m.messages, "info:\nIn the explorer ('ls'):\n  'enter' to open dir or modify file\n  'x' to read for context\n  'c' to create empty file\n  'a' to create file with AI")
+	m.addMessage(roleInfo, helpBuilder.String())
+	m.addMessage(roleInfo, "\n"+bindingsHelp("In the prompt:", m.keys, scopeInput, inputBindings))
+	m.addMessage(roleInfo, "\n"+actionsHelp("In the explorer ('ls' to open it):", m.keys))
+	m.addMessage(roleInfo, "Keys can be remapped under 'keys:' in config.yaml; '/help keys' lists those of the other views.")
 	return nil
 }
 
-func listCommand(m *model, args []string) tea.Cmd {
+func listCommand(m *model, args Args) tea.Cmd {
 	m.mode = modeExplorer
+	if dir := args.String("dir"); dir != "" {
+		return m.listDirectory(dir)
+	}
 	return m.listDirectory(m.currentPath)
 }
 
-func exitCommand(m *model, args []string) tea.Cmd {
-	m.messages = append(m.messages, "info:Goodbye!")
+func exitCommand(m *model, args Args) tea.Cmd {
+	m.addMessage(roleInfo, "Goodbye!")
 	return tea.Quit
 }
 
-func analyzeCommand(m *model, args []string) tea.Cmd {
-	if m.fileContext == "" {
-		m.messages = append(m.messages, "error:No file context stored. Use 'x' in the explorer to read a file for analysis context first.")
+func analyzeCommand(m *model, args Args) tea.Cmd {
+	if path := args.String("file"); path != "" {
+		question := args.String("question")
+		if question == "" {
+			m.addMessage(roleError, "analyze: missing argument <question>\nUsage: "+m.commands["analyze"].Usage())
+			return nil
+		}
+		return m.analyzeFile(path, question)
+	}
+	if m.contextFiles.empty() {
+		m.addMessage(roleError, "No file context stored. Use 'x' in the explorer to read a file for analysis context first.")
 		m.mode = modeChat
 		return nil
 	}
 	m.mode = modeAIAnalyzeInput
 	m.textInput.Placeholder = "What do you want to analyze in the file context?"
 	m.textInput.Focus()
-	m.messages = append(m.messages, "info:💡 Context stored from file. Enter your analysis instructions.")
-	return textinput.Blink
+	m.addMessage(roleInfo, "💡 Context: "+m.contextFiles.summary()+". Enter your analysis instructions.")
+	return textarea.Blink
+}
+
+// analyzeFile answers question about a single file, sharing the context files
+// as reference.
+func (m *model) analyzeFile(path, question string) tea.Cmd {
+	reference := m.contextFiles.prompt(path)
+	files := append([]string{path}, m.contextFiles.paths()...)
+	client := m.aiClient
+	return m.startJob("analyze", displayPath(path), func(ctx context.Context, progress func(string)) tea.Msg {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return errMsg{err}
+		}
+		var file contextSet
+		file.add(path, content)
+		prompt := prompts.Analyze(file.prompt(), question)
+		if reference != "" {
+			prompt += "\n\n--- REFERENCE FILES ---\n" + reference
+		}
+		progress("waiting for " + ai.Model)
+		reply, err := client.Generate(ctx, prompt)
+		if err != nil {
+			return errMsg{err}
+		}
+		return aiResponseMsg{reply: reply, files: files}
+	})
 }
 
 func (m *model) readFileContent(filePath string) tea.Cmd {
@@ -243,100 +497,143 @@ func (m *model) readFileForContext(filePath string) tea.Cmd {
 	}
 }
 
-func (m *model) listDirectory(path string) tea.Cmd {
-	m.currentPath = path
+func (m *model) refreshContextFile(filePath string) tea.Cmd {
 	return func() tea.Msg {
-		entries, err := os.ReadDir(path)
+		content, err := os.ReadFile(filePath)
 		if err != nil {
 			return errMsg{err}
 		}
-
-		items := []list.Item{
-			item{path: "..", isDir: true},
-		}
-		for _, entry := range entries {
-			items = append(items, item{path: entry.Name(), isDir: entry.IsDir()})
-		}
-		return m.list.SetItems(items)
+		return fileContextReadMsg{path: filePath, content: content, refresh: true}
 	}
 }
 
-func (m *model) createFileWithContent(filePath string, content string) tea.Cmd {
-	m.loading = true
-	return tea.Batch(
-		m.spinner.Tick,
-		func() tea.Msg {
-			err := os.WriteFile(filePath, []byte(content), 0644)
-			if err != nil {
-				return errMsg{err}
-			}
-			return fileWrittenMsg(filePath)
-		},
-	)
+func (m *model) createFileWithContent(filePath string, content string, prompt string) tea.Cmd {
+	j := m.journal
+	return m.startJob("write", displayPath(filePath), func(ctx context.Context, progress func(string)) tea.Msg {
+		if ctx.Err() != nil {
+			return errMsg{ctx.Err()}
+		}
+		if _, err := j.Write(filePath, []byte(content), prompt); err != nil {
+			return errMsg{err}
+		}
+		return fileWrittenMsg(filePath)
+	})
 }
 
 func (m *model) createFile(filePath string) tea.Cmd {
-	m.loading = true
+	m.mode = modeExplorer
+	m.textInput.Placeholder = "Write a message or command..."
 	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
-		return func() tea.Msg {
-			return errMsg{fmt.Errorf("file '%s' already exists", filePath)}
-		}
+		m.addMessage(roleError, fmt.Sprintf("file '%s' already exists", filePath))
+		return nil
 	}
 
-	return tea.Batch(
-		m.spinner.Tick,
-		func() tea.Msg {
-			f, err := os.Create(filePath)
-			if err != nil {
-				return errMsg{err}
-			}
-			_ = f.Close()
-			return fileWrittenMsg(filePath)
-		},
-	)
+	j := m.journal
+	return m.startJob("create", displayPath(filePath), func(ctx context.Context, progress func(string)) tea.Msg {
+		if _, err := j.Create(filePath, ""); err != nil {
+			return errMsg{err}
+		}
+		return fileWrittenMsg(filePath)
+	})
 }
 
-func (m *model) parseCommand(input string) (string, []string) {
-	parts := strings.Fields(input)
-	if len(parts) == 0 {
-		return "", nil
+func (m model) Init() tea.Cmd {
+	cmds := []tea.Cmd{textarea.Blink, m.listDirectory(m.currentPath)}
+	if m.resumeOnStart {
+		cmds = append(cmds, m.loadSession(""))
+	}
+	if m.layout.enabled {
+		cmds = append(cmds, tea.EnableMouseCellMotion)
 	}
-	return parts[0], parts[1:]
+	return tea.Batch(cmds...)
 }
 
-func (m model) Init() tea.Cmd {
-	return tea.Batch(textinput.Blink, m.listDirectory(m.currentPath))
+// Update handles msg and saves the session when the transcript changed.
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	before := len(m.messages)
+	updated, cmd := m.update(msg)
+	switch u := updated.(type) {
+	case *model:
+		save := u.autoSave(before)
+		return u, tea.Batch(cmd, save)
+	case model:
+		save := u.autoSave(before)
+		return u, tea.Batch(cmd, save)
+	}
+	return updated, cmd
 }
 
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.list.SetSize(msg.Width-4, msg.Height-6)
-		m.textInput.Width = msg.Width - 10
+		m.resize()
 		return m, nil
 
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
+
 	case errMsg:
 		m.loading = false
-		m.messages = append(m.messages, "error:"+msg.Error())
+		m.addMessage(roleError, msg.Error())
 		m.mode = modeChat
 		return m, nil
 
+	case jobDoneMsg:
+		return m.handleJobDone(msg)
+
 	case aiResponseMsg:
-		m.loading = false
-		m.messages = append(m.messages, "ai:"+string(msg))
+		m.addReply(msg.reply, msg.files)
+		return m, nil
+
+	case reviewDoneMsg:
+		return m, m.handleReviewDone(msg)
+
+	case copiedMsg:
+		m.handleCopied(msg)
+		return m, nil
+
+	case chatResponseMsg:
+		m.handleChatResponse(msg)
+		return m, nil
+
+	case sessionLoadedMsg:
+		return m, m.handleSessionLoaded(msg.session)
+
+	case saveSessionMsg:
+		if msg.seq != m.saveSeq {
+			return m, nil
+		}
+		return m, m.saveSessionCmd()
+
+	case sessionSavedMsg:
+		m.handleSessionSaved(msg.err)
 		return m, nil
 
 	case aiFileContentMsg:
-		m.messages = append(m.messages, "info:Content generated by AI. Writing to file...")
-		return m, m.createFileWithContent(msg.fileName, msg.content)
+		m.addMessage(roleInfo, "Content generated by AI. Writing to file...")
+		return m, m.createFileWithContent(msg.fileName, msg.content, msg.prompt)
+
+	case contextFilesReadMsg:
+		m.handleContextFilesRead(msg)
+		return m, nil
+
+	case conversationExportedMsg:
+		m.handleConversationExported(msg)
+		return m, nil
+
+	case fileShownMsg:
+		m.addMessage(roleInfo, displayPath(msg.path)+":")
+		m.addMessage(roleCode, msg.text)
+		return m, nil
 
 	case fileContextReadMsg:
 		m.loading = false
-		m.fileContext = string(msg.content)
-		m.mode = modeExplorer
-		m.messages = append(m.messages, "info:✅ Context from '"+filepath.Base(msg.path)+"' stored. You can now use 'a' to create a new file or 'analyze' to analyze this context.")
+		m.contextFiles.add(msg.path, msg.content)
+		if !msg.refresh {
+			m.addMessage(roleInfo, "✅ Added '"+filepath.Base(msg.path)+"' to the context ("+m.contextFiles.summary()+"). Use 'a' to create a new file, '/analyze <file> <question>' to analyze it or 'context' to manage it.")
+		}
 		return m, nil
 
 	case fileReadMsg:
@@ -344,32 +641,96 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.mode = modeAIModifyInput
 		m.fileModificationPath = msg.path
 		m.fileModificationContent = string(msg.content)
-		m.messages = append(m.messages, "info:File '"+filepath.Base(msg.path)+"' read. How do you want to modify it?")
+		m.addMessage(roleInfo, "File '"+filepath.Base(msg.path)+"' read. How do you want to modify it?")
 		m.textInput.Placeholder = "Ej: 'Add a comment to the main function'..."
 		m.textInput.Focus()
-		return m, textinput.Blink
+		return m, textarea.Blink
 
 	case aiModifiedContentMsg:
-		m.messages = append(m.messages, "info:Content modified by AI. Writing changes...")
-		return m, m.createFileWithContent(msg.path, msg.content)
+		return m, m.openDiffReview(msg.path, msg.prompt, msg.original, msg.content)
+
+	case directoryListedMsg:
+		m.list.Title = m.explorerTitle(msg.skipped)
+		cmd := m.list.SetItems(msg.items)
+		if m.pendingSelect != "" {
+			for i, it := range msg.items {
+				if it.(item).path == m.pendingSelect {
+					m.list.Select(i)
+				}
+			}
+			m.pendingSelect = ""
+		}
+		if m.list.Index() >= len(msg.items) {
+			m.list.Select(len(msg.items) - 1)
+		}
+		return m, tea.Batch(cmd, m.syncPreview())
+
+	case previewLoadedMsg:
+		m.handlePreviewLoaded(filePreview(msg))
+		return m, nil
+
+	case finderIndexedMsg:
+		m.handleFinderIndexed(msg)
+		return m, nil
+
+	case historyLoadedMsg:
+		return m, m.handleHistoryLoaded(msg)
+
+	case changeRevertedMsg:
+		return m, m.handleChangeReverted(journal.Entry(msg))
+
+	case fileOpDoneMsg:
+		return m, m.handleFileOpDone(msg)
 
 	case fileWrittenMsg:
-		m.loading = false
-		m.messages = append(m.messages, "info:✅ File created/modified: "+string(msg))
-		m.mode = modeExplorer
-		m.textInput.Placeholder = "Write a message or command..."
+		m.addMessage(roleInfo, "✅ File created/modified: "+string(msg))
+		m.previewPath = ""
+		if m.contextFiles.contains(string(msg)) {
+			return m, tea.Batch(m.listDirectory(m.currentPath), m.refreshContextFile(string(msg)))
+		}
 		return m, m.listDirectory(m.currentPath)
 
+	case editorClosedMsg:
+		return m, m.handleEditorClosed(msg)
+
 	case tea.KeyMsg:
 		if m.mode == modeExplorer {
 			return m.updateExplorer(msg)
 		}
-		if m.mode == modeChat || m.mode == modeCreateFileInput || m.mode == modeAIFilenameInput || m.mode == modeAIPromptInput || m.mode == modeAIModifyInput || m.mode == modeAIAnalyzeInput {
+		if m.mode == modeDiffReview {
+			return m.updateDiffReview(msg)
+		}
+		if m.mode == modeHistory {
+			return m.updateHistory(msg)
+		}
+		if m.mode == modeFinder {
+			return m.updateFinder(msg)
+		}
+		if m.mode == modeConfirmDelete {
+			return m.updateConfirmDelete(msg)
+		}
+		if m.mode == modePalette {
+			return m.updatePalette(msg)
+		}
+		if m.mode == modeJobs {
+			return m.updateJobs(msg)
+		}
+		if m.mode == modeSelect {
+			return m.updateSelection(msg)
+		}
+		if m.mode == modeBranches {
+			return m.updateBranches(msg)
+		}
+		if m.mode == modeFindings {
+			return m.updateFindings(msg)
+		}
+		if m.mode == modeChat || m.mode == modeCreateFileInput || m.mode == modeAIFilenameInput || m.mode == modeAIPromptInput || m.mode == modeAIModifyInput || m.mode == modeAIAnalyzeInput ||
+			m.mode == modeRenameInput || m.mode == modeMoveInput || m.mode == modeCopyInput || m.mode == modeMkdirInput || m.mode == modeSaveBlockInput || m.mode == modeEditMessage {
 			return m.updateTextInputModes(msg)
 		}
 	}
 
-	if m.loading {
+	if m.busy() {
 		spinner, cmd := m.spinner.Update(msg)
 		m.spinner = spinner
 		return m, cmd
@@ -379,277 +740,363 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) updateTextInputModes(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.Type {
-	case tea.KeyCtrlC:
+	if m.recall.searching && m.updateReverseSearch(msg) {
+		return m, nil
+	}
+	if msg.String() == "ctrl+c" {
 		return m, tea.Quit
-	case tea.KeyEsc:
+	}
+	action := m.keys.action(scopeInput, msg.String())
+	if action != "complete" {
+		m.completions = nil
+	}
+
+	switch action {
+	case "complete":
+		if m.layout.enabled && m.mode == modeChat && m.textInput.Value() == "" {
+			m.focusExplorer()
+			return m, nil
+		}
+		m.completeInput()
+		return m, nil
+	case "focus":
+		if m.mode == modeChat {
+			m.focusExplorer()
+			return m, nil
+		}
+	case "page-up", "page-down":
+		if m.layout.enabled {
+			lines := m.height / 2
+			if action == "page-down" {
+				lines = -lines
+			}
+			m.scrollChat(lines)
+			return m, nil
+		}
+	case "palette":
+		if m.mode == modeChat {
+			return m, m.openPalette()
+		}
+	case "search-history":
+		if m.startReverseSearch() {
+			return m, nil
+		}
+	case "history-prev", "history-next":
+		direction := "up"
+		if action == "history-next" {
+			direction = "down"
+		}
+		if m.recallInput(direction) {
+			return m, nil
+		}
+	case "finder":
+		if m.mode == modeChat {
+			return m, m.openFinder()
+		}
+	case "editor":
+		return m, m.openEditor()
+	case "select":
+		if m.mode == modeChat {
+			return m, m.startSelection()
+		}
+	case "cancel":
+		if m.mode == modeSaveBlockInput || m.mode == modeEditMessage {
+			m.textInput.Reset()
+			m.resizeComposer()
+			m.textInput.Placeholder = "Write a message or command..."
+			m.textInput.Blur()
+			m.mode = modeSelect
+			return m, nil
+		}
 		m.mode = modeExplorer
 		m.textInput.Reset()
+		m.resizeComposer()
+		m.resetRecall()
 		m.textInput.Placeholder = "Write a message or command..."
 		m.fileCreationName = ""
 		m.fileModificationPath = ""
 		m.fileModificationContent = ""
-		m.fileContext = ""
+		m.fileOpSource = ""
 		return m, nil
 
-	case tea.KeyEnter:
+	case "submit":
 		input := strings.TrimSpace(m.textInput.Value())
 		m.textInput.Reset()
+		m.resizeComposer()
+		m.recordInput(input)
 
 		switch m.mode {
 		case modeChat:
 			if input == "" {
 				return m, nil
 			}
-			m.messages = append(m.messages, "user: "+input)
-			cmdName, args := m.parseCommand(input)
-			if command, exists := m.commands[cmdName]; exists {
-				return m, command.Execute(m, args)
-			}
-			m.loading = true
-			return m, tea.Batch(
-				m.spinner.Tick,
-				func() tea.Msg {
-					res, err := m.aiClient.GetResponse(input)
-					if err != nil {
-						return errMsg{err}
-					}
-					return aiResponseMsg(res)
-				},
-			)
+			m.layout.chatScroll = 0
+			return m, m.submitChat(input)
 
 		case modeCreateFileInput:
 			filePath := filepath.Join(m.currentPath, input)
 			return m, m.createFile(filePath)
 
+		case modeRenameInput, modeMoveInput, modeCopyInput, modeMkdirInput:
+			return m, m.submitFileOp(input)
+
+		case modeSaveBlockInput:
+			return m, m.submitSaveBlock(input)
+
+		case modeEditMessage:
+			return m, m.submitEdit(input)
+
 		case modeAIFilenameInput:
 			m.fileCreationName = filepath.Join(m.currentPath, input)
 			m.mode = modeAIPromptInput
 			m.textInput.Placeholder = "Describe what sould do the file..."
-			m.messages = append(m.messages, "info:File to create: "+m.fileCreationName)
-			return m, textinput.Blink
+			m.addMessage(roleInfo, "File to create: "+m.fileCreationName)
+			return m, textarea.Blink
 
 		case modeAIPromptInput:
-			m.loading = true
 			prompt := input
 			fileName := m.fileCreationName
-			m.messages = append(m.messages, "user: "+prompt)
+			m.addMessage(roleUser, prompt)
 			m.mode = modeChat
 
-			var finalPrompt string
-			if m.fileContext != "" {
-				m.messages = append(m.messages, "info:💡 Using stored context to generate the file...")
-				finalPrompt = fmt.Sprintf(
-					"You are an expert file creator. Create a new file based on user instructions and context from another file.\n\n--- FILE CONTEXT ---\n%s\n\n--- USER INSTRUCTIONS FOR NEW FILE '%s' ---\n%s\n\nIMPORTANT: Only output the raw, complete content for the new file. Do not include any explanations, greetings, or markdown code fences.",
-					m.fileContext,
-					filepath.Base(fileName),
-					prompt,
-				)
-				m.fileContext = ""
-			} else {
-				finalPrompt = fmt.Sprintf("Generate the complete file content for a file named `%s`. The file should accomplish the following: %s. Only output the raw file content, without any explanation or markdown formatting.", filepath.Base(fileName), prompt)
+			if !m.contextFiles.empty() {
+				m.addMessage(roleInfo, "💡 Using stored context to generate the file ("+m.contextFiles.summary()+")...")
 			}
+			finalPrompt := prompts.Generate(fileName, prompt, m.contextFiles.prompt())
 
-			return m, tea.Batch(
-				m.spinner.Tick,
-				func() tea.Msg {
-					res, err := m.aiClient.GetResponse(finalPrompt)
-					if err != nil {
-						return errMsg{err}
-					}
-					return aiFileContentMsg{fileName: fileName, content: res}
-				},
-			)
+			return m, m.startJob("generate", displayPath(fileName), m.askAI(finalPrompt, func(res ai.Reply) tea.Msg {
+				return aiFileContentMsg{fileName: fileName, prompt: prompt, content: res.Text}
+			}))
 
 		case modeAIModifyInput:
-			m.loading = true
 			instructions := input
 			originalContent := m.fileModificationContent
 			filePath := m.fileModificationPath
-			m.messages = append(m.messages, "user: "+instructions)
+			m.addMessage(roleUser, instructions)
 			m.mode = modeChat
 
-			finalPrompt := fmt.Sprintf(
-				"You are an expert file editor. The user wants to modify a file. Below is the original content of the file and the user's instructions. Your task is to return the *entire*, *new* content of the file with the modifications applied. \n\nIMPORTANT: Only output the raw, complete, modified file content. Do not include any explanations, greetings, or markdown code fences like ```go ... ```.\n\n--- ORIGINAL FILE CONTENT ---\n%s\n\n--- USER INSTRUCTIONS ---\n%s",
-				originalContent,
-				instructions,
-			)
-
-			return m, tea.Batch(
-				m.spinner.Tick,
-				func() tea.Msg {
-					res, err := m.aiClient.GetResponse(finalPrompt)
-					if err != nil {
-						return errMsg{err}
-					}
-					return aiModifiedContentMsg{path: filePath, content: res}
-				},
-			)
+			finalPrompt := prompts.Modify(originalContent, instructions, m.contextFiles.prompt(filePath))
+
+			return m, m.startJob("modify", displayPath(filePath), m.askAI(finalPrompt, func(res ai.Reply) tea.Msg {
+				return aiModifiedContentMsg{path: filePath, prompt: instructions, original: originalContent, content: res.Text}
+			}))
 
 		case modeAIAnalyzeInput:
-			m.loading = true
 			instructions := input
-			fileContext := m.fileContext
-			m.messages = append(m.messages, "user: "+instructions)
+			fileContext := m.contextFiles.prompt()
+			files := m.contextFiles.paths()
+			m.addMessage(roleUser, instructions)
 			m.mode = modeChat
 
-			finalPrompt := fmt.Sprintf(
-				"You are an expert file analyzer. The user has provided the content of a file and wants you to analyze it based on their instructions. Your task is to provide a comprehensive analysis. \n\n--- FILE CONTENT TO ANALYZE ---\n%s\n\n--- USER INSTRUCTIONS FOR ANALYSIS ---\n%s",
-				fileContext,
-				instructions,
-			)
-			m.fileContext = ""
+			finalPrompt := prompts.Analyze(fileContext, instructions)
 
-			return m, tea.Batch(
-				m.spinner.Tick,
-				func() tea.Msg {
-					res, err := m.aiClient.GetResponse(finalPrompt)
-					if err != nil {
-						return errMsg{err}
-					}
-					return aiResponseMsg(res)
-				},
-			)
+			return m, m.startJob("analyze", m.contextFiles.summary(), m.askAI(finalPrompt, func(res ai.Reply) tea.Msg {
+				return aiResponseMsg{reply: res, files: files}
+			}))
 		}
 	}
 
 	var cmd tea.Cmd
 	m.textInput, cmd = m.textInput.Update(msg)
+	m.resizeComposer()
 	return m, cmd
 }
 
 func (m *model) updateExplorer(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "q", "esc":
-		m.mode = modeChat
-		m.fileContext = ""
+	name := m.keys.action(scopeExplorer, msg.String())
+	if m.scrollPreview(name) {
 		return m, nil
-	case "c":
-		m.mode = modeCreateFileInput
-		m.textInput.Placeholder = "New file name (empty)..."
-		m.textInput.Focus()
-		return m, textinput.Blink
-	case "a":
-		if m.fileContext != "" {
-			m.messages = append(m.messages, "info:💡 Note: The next file will be created using the stored context.")
-		}
-		m.mode = modeAIFilenameInput
-		m.textInput.Placeholder = "File name to generate by AI..."
-		m.textInput.Focus()
-		return m, textinput.Blink
-
-	case "x":
-		selectedItem, ok := m.list.SelectedItem().(item)
-		if !ok || selectedItem.isDir {
-			return m, nil
-		}
-		targetPath := filepath.Join(m.currentPath, selectedItem.path)
-		m.loading = true
-		m.messages = append(m.messages, "info:Reading file "+targetPath+" for context...")
-		return m, m.readFileForContext(targetPath)
-
-	case "enter":
-		selectedItem, ok := m.list.SelectedItem().(item)
-		if !ok {
-			return m, nil
-		}
-
-		targetPath := filepath.Join(m.currentPath, selectedItem.path)
-		if selectedItem.path == ".." {
-			targetPath = filepath.Dir(m.currentPath)
-		}
-
-		if selectedItem.isDir {
-			return m, m.listDirectory(targetPath)
-		} else {
-			m.loading = true
-			m.messages = append(m.messages, "info:Reading file "+targetPath+" to modify...")
-			return m, m.readFileContent(targetPath)
-		}
+	}
+	if cmd, ok := m.layoutAction(name); ok {
+		return m, cmd
+	}
+	if name == "palette" {
+		return m, m.openPalette()
+	}
+	if a, ok := findAction(explorerActions, name); ok {
+		return m, a.run(m)
 	}
 
 	var cmd tea.Cmd
 	m.list, cmd = m.list.Update(msg)
-	return m, cmd
+	return m, tea.Batch(cmd, m.syncPreview())
 }
 
 func (m model) View() string {
+	if m.splitVisible() {
+		return m.splitView()
+	}
+
 	var view string
 
 	switch m.mode {
-	case modeExplorer:
-		header := m.list.View()
-		if m.fileContext != "" {
-			contextMsg := m.styles.infoMsg.Render("\n💡 Context stored. Press 'a' to create a new file or type 'analyze' to use it.")
-			header = lipgloss.JoinVertical(lipgloss.Left, header, contextMsg)
-		}
-		view = m.styles.app.Render(header)
+	case modeDiffReview:
+		view = m.diffReviewView()
+	case modeHistory:
+		view = m.styles.app.Render(m.historyList.View())
+	case modeFinder:
+		view = m.finderView()
+	case modePalette:
+		view = m.paletteView()
+	case modeJobs:
+		view = m.jobsView()
+	case modeBranches:
+		view = m.branchesView()
+	case modeFindings:
+		view = m.findingsView()
+	case modeExplorer, modeConfirmDelete:
+		view = m.styles.app.Render(m.explorerPane(m.width-4, true))
 	default:
-		var messages strings.Builder
-		for _, msg := range m.messages {
-			parts := strings.SplitN(msg, ":", 2)
-			prefix, content := parts[0], parts[1]
-
-			switch prefix {
-			case "user":
-				messages.WriteString(m.styles.userMsg.Render("You: "+content) + "\n\n")
-			case "ai":
-				messages.WriteString(m.styles.aiMsg.Render("🤖: "+content) + "\n\n")
-			case "error":
-				messages.WriteString(m.styles.errorMsg.Render("❌ Error: "+content) + "\n\n")
-			case "info":
-				messages.WriteString(m.styles.infoMsg.Render(content) + "\n\n")
-			}
-		}
-
 		mainContent := lipgloss.JoinVertical(
 			lipgloss.Left,
 			m.styles.header.Render("ANX Agent"),
-			messages.String(),
+			m.renderMessages(m.width-4),
 		)
+		view = m.styles.app.Render(lipgloss.JoinVertical(lipgloss.Left, mainContent, m.chatFooter(m.width-4)))
+	}
 
-		var status string
-		if m.loading {
-			status = m.spinner.View() + " Processing..."
-		} else {
-			switch m.mode {
-			case modeChat:
-				status = "MODE: Chat | 'ls' to explore | 'exit' to exit"
-			case modeCreateFileInput:
-				status = "MODE: Create File | 'Enter' to confirm | 'Esc' to cancel"
-			case modeAIFilenameInput:
-				status = "MODE: File Name (AI) | 'Enter' to continue | 'Esc' to cancel"
-			case modeAIPromptInput:
-				status = "MODE: Description (AI) | 'Enter' to generate | 'Esc' to cancel"
-			case modeAIModifyInput:
-				status = "MODE: Modify with AI | 'Enter' to send | 'Esc' to cancel"
-			case modeAIAnalyzeInput:
-				status = "MODE: Analyze (AI) | 'Enter' to analyze | 'Esc' to cancel"
-			}
-		}
+	return view
+}
 
-		inputView := m.textInput.View()
-		statusTextView := m.styles.statusText.Render(status)
-		availableWidth := m.width - lipgloss.Width(statusTextView) - 5
-		m.textInput.Width = availableWidth
+// explorerPane renders the file list with its preview, when there is room
+// for it, and the indicators under it.
+func (m model) explorerPane(width int, focused bool) string {
+	l := m.list
+	if !focused {
+		l.Styles.Title = l.Styles.Title.Background(m.styles.border)
+	}
+	header := l.View()
+	if m.showPreview() {
+		listWidth, _ := m.explorerWidths()
+		header = lipgloss.JoinHorizontal(lipgloss.Top, lipgloss.NewStyle().Width(listWidth+1).Render(header), m.previewView())
+	}
+	if !m.contextFiles.empty() {
+		contextMsg := m.styles.infoMsg.Width(width - 2).Render("\n💡 Context: " + m.contextFiles.summary() + ". Press 'a' to create a new file or type 'analyze' to use it.")
+		header = lipgloss.JoinVertical(lipgloss.Left, header, contextMsg)
+	}
+	if n := m.jobs.Running(); n > 0 {
+		header = lipgloss.JoinVertical(lipgloss.Left, header, m.styles.statusText.Width(width).Render(m.spinner.View()+" "+m.jobsStatus(n)))
+	}
+	if m.mode == modeConfirmDelete {
+		header = lipgloss.JoinVertical(lipgloss.Left, header, m.confirmDeleteView())
+	}
+	return header
+}
 
-		statusBar := m.styles.statusBar.Width(m.width - 4).Render(
-			lipgloss.JoinHorizontal(lipgloss.Left,
-				inputView,
-				"  ",
-				statusTextView,
-			),
-		)
+// renderMessages renders the transcript wrapped to width. While selecting,
+// it ends at the selected message, since only the end of it is shown.
+func (m model) renderMessages(width int) string {
+	shown := m.messages
+	selecting := m.mode == modeSelect || m.mode == modeSaveBlockInput || m.mode == modeEditMessage
+	if selecting {
+		shown = m.messages[:m.selection.cursor+1]
+	}
+	var messages strings.Builder
+	for i, msg := range shown {
+		messages.WriteString(m.renderMessage(msg, selecting && i == m.selection.cursor, width) + "\n\n")
+	}
+	return messages.String()
+}
 
-		view = m.styles.app.Render(lipgloss.JoinVertical(lipgloss.Left, mainContent, statusBar))
+// chatFooter renders the composer with the status line and, when files are
+// stored, the context indicator above them.
+func (m model) chatFooter(width int) string {
+	statusBar := m.styles.statusBar.Width(width).Render(
+		lipgloss.JoinVertical(lipgloss.Left,
+			m.textInput.View(),
+			m.styles.statusText.Render(m.chatStatus()),
+		),
+	)
+	if m.contextFiles.empty() {
+		return statusBar
 	}
+	context := m.styles.infoMsg.Width(width - 2).Render("💡 Context: " + m.contextFiles.summary())
+	return lipgloss.JoinVertical(lipgloss.Left, context, statusBar)
+}
 
-	return view
+func (m model) chatStatus() string {
+	var status string
+	if m.loading {
+		status = m.spinner.View() + " Processing..."
+	} else if m.recall.searching {
+		status = m.reverseSearchStatus()
+	} else if len(m.completions) > 0 {
+		status = m.completionStatus()
+	} else {
+		switch m.mode {
+		case modeSelect:
+			status = m.selectionStatus()
+		case modeSaveBlockInput:
+			status = "MODE: Save Code Block | " + m.inputHint("save")
+		case modeEditMessage:
+			status = "MODE: Edit and Resend (new branch) | " + m.inputHint("resend")
+		case modeChat:
+			k := m.keys
+			if m.layout.enabled {
+				status = fmt.Sprintf("MODE: Chat | '%s' to the explorer | '%s' to find a file | '%s' palette | '%s' new line | 'exit' to exit",
+					k.hint(scopeInput, "focus"), k.hint(scopeInput, "finder"), k.hint(scopeInput, "palette"), k.hint(scopeInput, "newline"))
+				break
+			}
+			status = fmt.Sprintf("MODE: Chat | 'ls' to explore | '%s' to find a file | '%s' palette | '%s' new line | '%s' open in $EDITOR | 'exit' to exit",
+				k.hint(scopeInput, "finder"), k.hint(scopeInput, "palette"), k.hint(scopeInput, "newline"), k.hint(scopeInput, "editor"))
+		case modeExplorer, modeConfirmDelete:
+			status = fmt.Sprintf("MODE: Explorer | '%s' to the chat", m.keys.hint(scopeExplorer, "focus"))
+		case modeCreateFileInput:
+			status = "MODE: Create File | " + m.inputHint("confirm")
+		case modeAIFilenameInput:
+			status = "MODE: File Name (AI) | " + m.inputHint("continue")
+		case modeAIPromptInput:
+			status = "MODE: Description (AI) | " + m.inputHint("generate")
+		case modeAIModifyInput:
+			status = "MODE: Modify with AI | " + m.inputHint("send")
+		case modeAIAnalyzeInput:
+			status = "MODE: Analyze (AI) | " + m.inputHint("analyze")
+		case modeRenameInput:
+			status = "MODE: Rename " + m.fileOpSource + " | " + m.inputHint("confirm")
+		case modeMoveInput:
+			status = "MODE: Move " + m.fileOpSource + " | " + m.inputHint("confirm")
+		case modeCopyInput:
+			status = "MODE: Copy " + m.fileOpSource + " | " + m.inputHint("confirm")
+		case modeMkdirInput:
+			status = "MODE: New Directory | " + m.inputHint("confirm")
+		}
+	}
+
+	if n := m.jobs.Running(); n > 0 && !m.loading {
+		status = m.spinner.View() + " " + m.jobsStatus(n) + " | " + status
+	}
+	return status
 }
 
-func Start(aiClient *ai.Client) {
-	p := tea.NewProgram(initialModel(aiClient), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		log.Fatal("Error starting the application: ", err)
+// inputHint tells which keys submit and cancel the current input.
+func (m model) inputHint(verb string) string {
+	return fmt.Sprintf("'%s' to %s | '%s' to cancel", m.keys.hint(scopeInput, "submit"), verb, m.keys.hint(scopeInput, "cancel"))
+}
+
+func Start(aiClient *ai.Client, opts Options) error {
+	keys, err := newKeyMap(opts.Keys)
+	if err != nil {
+		return err
+	}
+	split, err := parseLayout(opts.Layout)
+	if err != nil {
+		return err
 	}
+	m := initialModel(aiClient)
+	m.applyKeyMap(keys)
+	m.layout.enabled = split
+	m.resumeOnStart = opts.Resume
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	final, err := p.Run()
+	if err != nil {
+		return fmt.Errorf("error starting the application: %w", err)
+	}
+	// A save may still be waiting for its delay.
+	switch f := final.(type) {
+	case model:
+		f.saveSession()
+	case *model:
+		f.saveSession()
+	}
+	return nil
 }
\ No newline at end of file
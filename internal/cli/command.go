@@ -0,0 +1,374 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ArgType says how a positional argument or flag value is validated before
+// a command runs. Path types are resolved against the current directory.
+type ArgType int
+
+const (
+	ArgString ArgType = iota
+	ArgInt
+	ArgBool
+	ArgPath
+	ArgFile
+	ArgDir
+	ArgGlob
+)
+
+type Arg struct {
+	Name        string
+	Type        ArgType
+	Optional    bool
+	Variadic    bool
+	Description string
+}
+
+type Flag struct {
+	Name        string
+	Short       string
+	Type        ArgType
+	Default     string
+	Description string
+}
+
+type Command struct {
+	Name        string
+	Aliases     []string
+	Description string
+	Args        []Arg
+	Flags       []Flag
+	Execute     func(m *model, args Args) tea.Cmd
+}
+
+// Args holds the validated arguments of a command invocation.
+type Args struct {
+	values     map[string]string
+	rest       []string
+	flags      map[string]string
+	positional []string
+}
+
+// String returns the named argument or flag. A variadic argument returns
+// all of its values joined by spaces.
+func (a Args) String(name string) string {
+	if v, ok := a.values[name]; ok {
+		return v
+	}
+	return a.flags[name]
+}
+
+func (a Args) Int(name string) int {
+	n, _ := strconv.Atoi(a.String(name))
+	return n
+}
+
+func (a Args) Bool(name string) bool { return a.flags[name] == "true" }
+
+// Rest returns the values of the variadic argument.
+func (a Args) Rest() []string { return a.rest }
+
+// Positional returns every positional argument as typed.
+func (a Args) Positional() []string { return a.positional }
+
+// commandPrefix starts a command that takes arguments in the chat.
+const commandPrefix = "/"
+
+func (c Command) Usage() string {
+	var sb strings.Builder
+	sb.WriteString(commandPrefix + c.Name)
+	for _, arg := range c.Args {
+		name := arg.Name
+		if arg.Variadic {
+			name += "..."
+		}
+		if arg.Optional {
+			sb.WriteString(" [" + name + "]")
+		} else {
+			sb.WriteString(" <" + name + ">")
+		}
+	}
+	for _, f := range c.Flags {
+		sb.WriteString(" [--" + f.Name)
+		if f.Type != ArgBool {
+			sb.WriteString(" " + strings.ToUpper(f.Name))
+		}
+		sb.WriteString("]")
+	}
+	return sb.String()
+}
+
+// Help describes the command with its aliases, arguments and flags.
+func (c Command) Help() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Usage: %s\n  %s\n", c.Usage(), c.Description)
+	if len(c.Aliases) > 0 {
+		fmt.Fprintf(&sb, "Aliases: %s\n", strings.Join(c.Aliases, ", "))
+	}
+	if len(c.Args) > 0 {
+		sb.WriteString("Arguments:\n")
+		for _, arg := range c.Args {
+			fmt.Fprintf(&sb, "  %-16s %s\n", arg.Name, arg.Description)
+		}
+	}
+	if len(c.Flags) > 0 {
+		sb.WriteString("Flags:\n")
+		for _, f := range c.Flags {
+			name := "--" + f.Name
+			if f.Short != "" {
+				name = "-" + f.Short + ", " + name
+			}
+			desc := f.Description
+			if f.Default != "" {
+				desc += " (default " + f.Default + ")"
+			}
+			fmt.Fprintf(&sb, "  %-16s %s\n", name, desc)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (c Command) flag(name string) (Flag, bool) {
+	for _, f := range c.Flags {
+		if f.Name == name || (f.Short != "" && f.Short == name) {
+			return f, true
+		}
+	}
+	return Flag{}, false
+}
+
+// parse validates tokens against the command definition. Paths are resolved
+// relative to dir.
+func (c Command) parse(dir string, tokens []string) (Args, error) {
+	args := Args{values: map[string]string{}, flags: map[string]string{}}
+	for _, f := range c.Flags {
+		if f.Default != "" {
+			args.flags[f.Name] = f.Default
+		}
+	}
+
+	flagsDone := false
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if flagsDone || !isFlag(token) {
+			args.positional = append(args.positional, token)
+			continue
+		}
+		if token == "--" {
+			flagsDone = true
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(token, "-"), "=")
+		f, ok := c.flag(name)
+		if !ok {
+			return Args{}, fmt.Errorf("unknown flag '%s'", token)
+		}
+		if f.Type == ArgBool {
+			if !hasValue {
+				value = "true"
+			}
+		} else if !hasValue {
+			if i+1 >= len(tokens) {
+				return Args{}, fmt.Errorf("flag '--%s' needs a value", f.Name)
+			}
+			i++
+			value = tokens[i]
+		}
+		value, err := checkValue(dir, "--"+f.Name, f.Type, value)
+		if err != nil {
+			return Args{}, err
+		}
+		args.flags[f.Name] = value
+	}
+
+	pos := args.positional
+	for _, arg := range c.Args {
+		if len(pos) == 0 {
+			if !arg.Optional {
+				return Args{}, fmt.Errorf("missing argument <%s>", arg.Name)
+			}
+			continue
+		}
+		n := 1
+		if arg.Variadic {
+			n = len(pos)
+		}
+		for _, token := range pos[:n] {
+			value, err := checkValue(dir, arg.Name, arg.Type, token)
+			if err != nil {
+				return Args{}, err
+			}
+			if arg.Variadic {
+				args.rest = append(args.rest, value)
+			} else {
+				args.values[arg.Name] = value
+			}
+		}
+		if arg.Variadic {
+			args.values[arg.Name] = strings.Join(args.rest, " ")
+		}
+		pos = pos[n:]
+	}
+	if len(pos) > 0 {
+		return Args{}, fmt.Errorf("unexpected argument '%s'", pos[0])
+	}
+	return args, nil
+}
+
+func isFlag(token string) bool {
+	if strings.HasPrefix(token, "--") {
+		return true
+	}
+	return len(token) == 2 && token[0] == '-' && unicode.IsLetter(rune(token[1]))
+}
+
+func checkValue(dir, name string, t ArgType, value string) (string, error) {
+	switch t {
+	case ArgInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("%s must be a number, got '%s'", name, value)
+		}
+	case ArgBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "", fmt.Errorf("%s must be true or false, got '%s'", name, value)
+		}
+	case ArgPath, ArgFile, ArgDir:
+		path := resolvePath(dir, value)
+		if t == ArgPath {
+			return path, nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("cannot access '%s': %w", value, err)
+		}
+		if t == ArgFile && info.IsDir() {
+			return "", fmt.Errorf("'%s' is a directory", value)
+		}
+		if t == ArgDir && !info.IsDir() {
+			return "", fmt.Errorf("'%s' is not a directory", value)
+		}
+		return path, nil
+	}
+	return value, nil
+}
+
+func resolvePath(dir, path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
+
+// tokenize splits input like a shell: whitespace separates words, single
+// quotes keep text literally, double quotes allow \" and \\ escapes and a
+// backslash outside quotes escapes the next character.
+func tokenize(input string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+				i++
+				current.WriteRune(runes[i])
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			current.WriteRune(runes[i])
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+func (m *model) lookupCommand(name string) (Command, bool) {
+	if c, ok := m.commands[name]; ok {
+		return c, true
+	}
+	for _, c := range m.commands {
+		for _, alias := range c.Aliases {
+			if alias == name {
+				return c, true
+			}
+		}
+	}
+	return Command{}, false
+}
+
+// matchCommand reports whether input is a command: a name or alias after a
+// slash, as in "/add main.go", or alone, as in "help". Other messages go to
+// the chat even when their first word names a command, as in "add error
+// handling to the parser".
+func (m *model) matchCommand(input string) (Command, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return Command{}, false
+	}
+	name, slash := strings.CutPrefix(fields[0], commandPrefix)
+	if !slash && len(fields) > 1 {
+		return Command{}, false
+	}
+	return m.lookupCommand(name)
+}
+
+// runCommand parses input for c and executes it, or explains the usage when
+// the arguments do not fit.
+func (m *model) runCommand(c Command, input string) tea.Cmd {
+	tokens, err := tokenize(input)
+	if err == nil {
+		var args Args
+		if args, err = c.parse(m.currentPath, tokens[1:]); err == nil {
+			return c.Execute(m, args)
+		}
+	}
+	m.addMessage(roleError, c.Name+": "+err.Error()+"\nUsage: "+c.Usage())
+	return nil
+}
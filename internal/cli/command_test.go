@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{"  add   main.go  ", []string{"add", "main.go"}},
+		{`add 'my file.go'`, []string{"add", "my file.go"}},
+		{`add "my file.go"`, []string{"add", "my file.go"}},
+		{`say "a \"quoted\" word"`, []string{"say", `a "quoted" word`}},
+		{`say "back\\slash"`, []string{"say", `back\slash`}},
+		{`say "keep \n"`, []string{"say", `keep \n`}},
+		{`say 'no \escapes'`, []string{"say", `no \escapes`}},
+		{`add my\ file.go`, []string{"add", "my file.go"}},
+		{`add pre"mid dle"post`, []string{"add", "premid dlepost"}},
+		{`add ''`, []string{"add", ""}},
+		{"ask über straße", []string{"ask", "über", "straße"}},
+	}
+	for _, tt := range tests {
+		got, err := tokenize(tt.input)
+		if err != nil {
+			t.Errorf("tokenize(%q): %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+	for _, input := range []string{`add 'open`, `add "open`, `add "a\"`} {
+		if _, err := tokenize(input); err == nil {
+			t.Errorf("tokenize(%q) should fail on the unterminated quote", input)
+		}
+	}
+}
+
+func testCommands() *model {
+	return &model{commands: map[string]Command{
+		"add":  {Name: "add", Aliases: []string{"a"}},
+		"help": {Name: "help"},
+	}}
+}
+
+func TestMatchCommand(t *testing.T) {
+	m := testCommands()
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"help", "help"},
+		{"/help", "help"},
+		{"  help  ", "help"},
+		{"/help add", "help"},
+		{"/add main.go", "add"},
+		{"/a main.go", "add"},
+		{"a", "add"},
+		{"add", "add"},
+		// Without the slash, a command with arguments is a chat message.
+		{"add error handling to the parser", ""},
+		{"help me with this", ""},
+		{"/unknown thing", ""},
+		{"unknown", ""},
+		{"", ""},
+		{"/", ""},
+	}
+	for _, tt := range tests {
+		c, ok := m.matchCommand(tt.input)
+		if ok != (tt.want != "") || c.Name != tt.want {
+			t.Errorf("matchCommand(%q) = %q, %v; want %q", tt.input, c.Name, ok, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "f.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	c := Command{
+		Name: "test",
+		Args: []Arg{
+			{Name: "file", Type: ArgFile},
+			{Name: "n", Type: ArgInt, Optional: true},
+			{Name: "rest", Optional: true, Variadic: true},
+		},
+		Flags: []Flag{
+			{Name: "all", Short: "a", Type: ArgBool},
+			{Name: "dir", Type: ArgDir},
+			{Name: "mode", Default: "fast"},
+		},
+	}
+
+	args, err := c.parse(dir, []string{"-a", "f.txt", "3", "--dir=d", "x", "--", "--y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := args.String("file"); got != filepath.Join(dir, "f.txt") {
+		t.Errorf("file = %q", got)
+	}
+	if args.Int("n") != 3 || !args.Bool("all") || args.String("mode") != "fast" {
+		t.Errorf("unexpected values %+v", args)
+	}
+	if got := args.String("dir"); got != filepath.Join(dir, "d") {
+		t.Errorf("dir = %q", got)
+	}
+	if got := args.Rest(); !reflect.DeepEqual(got, []string{"x", "--y"}) {
+		t.Errorf("rest = %q", got)
+	}
+
+	errors := []struct {
+		tokens []string
+		want   string
+	}{
+		{nil, "missing argument <file>"},
+		{[]string{"what"}, "cannot access 'what'"},
+		{[]string{"d"}, "'d' is a directory"},
+		{[]string{"f.txt", "three"}, "n must be a number, got 'three'"},
+		{[]string{"f.txt", "--dir", "f.txt"}, "'f.txt' is not a directory"},
+		{[]string{"f.txt", "--dir"}, "flag '--dir' needs a value"},
+		{[]string{"f.txt", "--all=maybe"}, "--all must be true or false"},
+		{[]string{"f.txt", "--nope"}, "unknown flag '--nope'"},
+	}
+	for _, tt := range errors {
+		_, err := c.parse(dir, tt.tokens)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parse(%q) = %v, want an error containing %q", tt.tokens, err, tt.want)
+		}
+	}
+
+	single := Command{Name: "one", Args: []Arg{{Name: "x"}}}
+	if _, err := single.parse(dir, []string{"a", "b"}); err == nil || !strings.Contains(err.Error(), "unexpected argument 'b'") {
+		t.Errorf("extra argument: got %v", err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	c := Command{
+		Name:  "analyze",
+		Args:  []Arg{{Name: "file", Type: ArgFile, Optional: true}, {Name: "question", Optional: true, Variadic: true}},
+		Flags: []Flag{{Name: "all", Type: ArgBool}, {Name: "focus"}},
+	}
+	if got, want := c.Usage(), "/analyze [file] [question...] [--all] [--focus FOCUS]"; got != want {
+		t.Errorf("Usage() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxShownCompletions = 12
+
+func (m *model) commandNames() []string {
+	names := make([]string, 0, len(m.commands))
+	for name := range m.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completeInput completes the word being typed on Tab: a command name after
+// a slash at the start of a chat message, otherwise a path relative to the
+// current directory. Ambiguous completions are extended to their common prefix and
+// listed in the status bar.
+func (m *model) completeInput() {
+	m.completions = nil
+	value := m.textInput.Value()
+	if strings.Contains(value, "\n") {
+		return
+	}
+
+	head, token := "", value
+	var candidates []string
+	switch m.mode {
+	case modeChat:
+		if i := strings.LastIndex(value, " "); i >= 0 {
+			head, token = value[:i+1], value[i+1:]
+			candidates = completePath(m.currentPath, token)
+		} else if strings.HasPrefix(token, commandPrefix) {
+			for _, name := range m.commandNames() {
+				for _, word := range append([]string{name}, m.commands[name].Aliases...) {
+					if word = commandPrefix + word; strings.HasPrefix(word, token) {
+						candidates = append(candidates, word)
+					}
+				}
+			}
+			if len(candidates) == 1 {
+				candidates[0] += " "
+			}
+		}
+	case modeCreateFileInput, modeAIFilenameInput, modeRenameInput, modeMoveInput, modeCopyInput, modeMkdirInput:
+		candidates = completePath(m.currentPath, token)
+	}
+
+	if len(candidates) == 0 {
+		return
+	}
+	completed := commonPrefix(candidates)
+	if len(candidates) > 1 {
+		m.completions = candidates
+	}
+	if len(completed) > len(token) {
+		m.textInput.SetValue(head + completed)
+	}
+}
+
+// completePath lists the entries matching token, which is relative to base
+// unless it is absolute. Directories end with a slash so completion can
+// continue into them.
+func completePath(base, token string) []string {
+	dirPart, prefix := "", token
+	if i := strings.LastIndex(token, "/"); i >= 0 {
+		dirPart, prefix = token[:i+1], token[i+1:]
+	}
+	dir := dirPart
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dirPart)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		if e.IsDir() {
+			name += "/"
+		}
+		candidates = append(candidates, dirPart+name)
+	}
+	return candidates
+}
+
+func commonPrefix(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	prefix := values[0]
+	for _, v := range values[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	// Names that differ inside a multi-byte rune share only the runes
+	// before it.
+	for len(prefix) < len(values[0]) && len(prefix) > 0 && !utf8.RuneStart(values[0][len(prefix)]) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
+
+func (m *model) completionStatus() string {
+	shown := m.completions
+	more := ""
+	if len(shown) > maxShownCompletions {
+		shown = shown[:maxShownCompletions]
+		more = " …"
+	}
+	return "Completions: " + strings.Join(shown, "  ") + more
+}
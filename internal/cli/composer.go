@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// composerMaxHeight is how many lines the composer grows to before it
+// scrolls. It does not limit how much can be written.
+const composerMaxHeight = 8
+
+type editorClosedMsg struct {
+	content string
+	err     error
+}
+
+// newComposer returns the multiline prompt editor. Enter submits, so new
+// lines are inserted with Alt+Enter or Ctrl+J.
+func newComposer() textarea.Model {
+	ta := textarea.New()
+	ta.Placeholder = "Write a message or command ('help' to show help)..."
+	ta.Prompt = "┃ "
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+	ta.SetHeight(1)
+	ta.SetWidth(50)
+	ta.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"), key.WithHelp("alt+enter", "new line"))
+	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	ta.Focus()
+	return ta
+}
+
+// resizeComposer fits the composer height to its content.
+func (m *model) resizeComposer() {
+	height := m.textInput.LineCount()
+	if height > composerMaxHeight {
+		height = composerMaxHeight
+	}
+	if height != m.textInput.Height() {
+		m.textInput.SetHeight(height)
+	}
+}
+
+// editorCommand builds the command for $VISUAL or $EDITOR, which may carry
+// its own arguments, falling back to vi.
+func editorCommand(args ...string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		fields = []string{"vi"}
+	}
+	return exec.Command(fields[0], append(fields[1:], args...)...)
+}
+
+// openEditor hands the current draft to the user's editor and loads the
+// result back into the composer when the editor exits.
+func (m *model) openEditor() tea.Cmd {
+	f, err := os.CreateTemp("", "anx-prompt-*.md")
+	if err != nil {
+		return func() tea.Msg { return errMsg{fmt.Errorf("error creating draft file: %w", err)} }
+	}
+	path := f.Name()
+	_, err = f.WriteString(m.textInput.Value())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return func() tea.Msg { return errMsg{fmt.Errorf("error writing draft file: %w", err)} }
+	}
+
+	return tea.ExecProcess(editorCommand(path), func(err error) tea.Msg {
+		defer os.Remove(path)
+		if err != nil {
+			return editorClosedMsg{err: fmt.Errorf("error running editor: %w", err)}
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return editorClosedMsg{err: fmt.Errorf("error reading draft file: %w", err)}
+		}
+		return editorClosedMsg{content: strings.TrimRight(string(content), "\n")}
+	})
+}
+
+func (m *model) handleEditorClosed(msg editorClosedMsg) tea.Cmd {
+	if msg.err != nil {
+		m.addMessage(roleError, msg.err.Error())
+		return nil
+	}
+	m.textInput.SetValue(msg.content)
+	m.resizeComposer()
+	return m.textInput.Focus()
+}
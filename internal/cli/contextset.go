@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/prompts"
+	"github.com/anthonycursewl/anx-agent/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type contextFile struct {
+	path    string
+	content string
+	tokens  int
+	pinned  bool
+}
+
+func (f contextFile) size() int64 { return int64(len(f.content)) }
+
+// contextSet holds the files the user has chosen to share with the AI. Files
+// stay in the set until they are removed; pinned files also survive a clear.
+type contextSet struct {
+	files []contextFile
+}
+
+func (c *contextSet) empty() bool { return len(c.files) == 0 }
+
+func (c *contextSet) index(path string) int {
+	path = filepath.Clean(path)
+	for i, f := range c.files {
+		if f.path == path {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *contextSet) contains(path string) bool { return c.index(path) >= 0 }
+
+// add stores path in the set, refreshing its content if it is already there.
+func (c *contextSet) add(path string, content []byte) {
+	f := contextFile{
+		path:    filepath.Clean(path),
+		content: string(content),
+		tokens:  ai.EstimateTokens(string(content)),
+	}
+	if i := c.index(path); i >= 0 {
+		f.pinned = c.files[i].pinned
+		c.files[i] = f
+		return
+	}
+	c.files = append(c.files, f)
+}
+
+func (c *contextSet) remove(path string) bool {
+	i := c.index(path)
+	if i < 0 {
+		return false
+	}
+	c.files = append(c.files[:i], c.files[i+1:]...)
+	return true
+}
+
+func (c *contextSet) setPinned(path string, pinned bool) bool {
+	i := c.index(path)
+	if i < 0 {
+		return false
+	}
+	c.files[i].pinned = pinned
+	return true
+}
+
+// clear removes every file that is not pinned and reports how many were
+// removed.
+func (c *contextSet) clear() int {
+	kept := c.files[:0]
+	for _, f := range c.files {
+		if f.pinned {
+			kept = append(kept, f)
+		}
+	}
+	removed := len(c.files) - len(kept)
+	c.files = kept
+	return removed
+}
+
+// move follows a file or directory that was moved from one path to another.
+// An empty to drops the files, as when they are deleted.
+func (c *contextSet) move(from, to string) {
+	from = filepath.Clean(from)
+	kept := c.files[:0]
+	for _, f := range c.files {
+		rel, err := filepath.Rel(from, f.path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			kept = append(kept, f)
+			continue
+		}
+		if to != "" {
+			f.path = filepath.Join(to, rel)
+			kept = append(kept, f)
+		}
+	}
+	c.files = kept
+}
+
+func (c *contextSet) tokens() int {
+	total := 0
+	for _, f := range c.files {
+		total += f.tokens
+	}
+	return total
+}
+
+func (c *contextSet) paths() []string {
+	paths := make([]string, len(c.files))
+	for i, f := range c.files {
+		paths[i] = f.path
+	}
+	return paths
+}
+
+// resolve accepts either the 1-based position shown by 'context' or a path,
+// relative to dir as with 'context add'.
+func (c *contextSet) resolve(dir, ref string) (string, bool) {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(c.files) {
+			return "", false
+		}
+		return c.files[n-1].path, true
+	}
+	path := ref
+	if !filepath.IsAbs(ref) {
+		path = filepath.Join(dir, filepath.FromSlash(ref))
+	}
+	if c.contains(path) {
+		return filepath.Clean(path), true
+	}
+	return "", false
+}
+
+func (c *contextSet) summary() string {
+	return fmt.Sprintf("%d file(s), ~%s tokens", len(c.files), utils.FormatCount(c.tokens()))
+}
+
+// prompt renders the files as clearly delimited sections, leaving out the
+// paths in exclude.
+func (c *contextSet) prompt(exclude ...string) string {
+	var files []contextFile
+	for _, f := range c.files {
+		skip := false
+		for _, e := range exclude {
+			if f.path == filepath.Clean(e) {
+				skip = true
+			}
+		}
+		if !skip {
+			files = append(files, f)
+		}
+	}
+
+	shared := make([]prompts.File, len(files))
+	for i, f := range files {
+		shared[i] = prompts.File{Path: f.path, Content: f.content}
+	}
+	return prompts.Files(shared)
+}
+
+func contextCommand(m *model, a Args) tea.Cmd {
+	args := a.Positional()
+	sub := "list"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch sub {
+	case "list", "ls":
+		if m.contextFiles.empty() {
+			m.addMessage(roleInfo, "The context is empty. Press 'x' on a file in the explorer or use '/context add <file>'.")
+			return nil
+		}
+		var sb strings.Builder
+		sb.WriteString("Context files (" + m.contextFiles.summary() + "):\n")
+		for i, f := range m.contextFiles.files {
+			pin := "  "
+			if f.pinned {
+				pin = "📌"
+			}
+			fmt.Fprintf(&sb, "  %2d. %s %-40s %10s  ~%s tokens\n", i+1, pin, f.path, utils.FormatSize(f.size()), utils.FormatCount(f.tokens))
+		}
+		m.addMessage(roleInfo, sb.String())
+		return nil
+
+	case "add":
+		if len(args) < 2 {
+			m.addMessage(roleError, "Usage: /context add <file>...")
+			return nil
+		}
+		return m.addToContext(args[1:], false)
+
+	case "rm", "remove", "pin", "unpin":
+		if len(args) < 2 {
+			m.addMessage(roleError, "Usage: /context "+sub+" <file|number>")
+			return nil
+		}
+		path, ok := m.contextFiles.resolve(m.currentPath, args[1])
+		if !ok {
+			m.addMessage(roleError, "'"+args[1]+"' is not in the context. Use 'context' to list it.")
+			return nil
+		}
+		if sub == "rm" || sub == "remove" {
+			m.contextFiles.remove(path)
+			m.addMessage(roleInfo, "Removed '"+path+"' from the context ("+m.contextFiles.summary()+").")
+			return nil
+		}
+		m.contextFiles.setPinned(path, sub == "pin")
+		if sub == "pin" {
+			m.addMessage(roleInfo, "📌 Pinned '"+path+"'. It will be kept when the context is cleared.")
+		} else {
+			m.addMessage(roleInfo, "Unpinned '"+path+"'.")
+		}
+		return nil
+
+	case "clear":
+		removed := m.contextFiles.clear()
+		m.addMessage(roleInfo, fmt.Sprintf("Removed %d file(s) from the context; %d pinned file(s) kept.", removed, len(m.contextFiles.files)))
+		return nil
+	}
+
+	m.addMessage(roleError, "Unknown context action '"+sub+"'. Use list, add, rm, pin, unpin or clear.")
+	return nil
+}
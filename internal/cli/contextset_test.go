@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestContextSetResolve(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	var c contextSet
+	c.add(filepath.Join(sub, "a.go"), []byte("package a\n"))
+	c.add(filepath.Join(root, "b.go"), []byte("package b\n"))
+
+	tests := []struct {
+		dir, ref, want string
+	}{
+		{sub, "a.go", filepath.Join(sub, "a.go")},
+		{root, "sub/a.go", filepath.Join(sub, "a.go")},
+		{sub, "../b.go", filepath.Join(root, "b.go")},
+		{root, filepath.Join(root, "b.go"), filepath.Join(root, "b.go")},
+		{root, "2", filepath.Join(root, "b.go")},
+		{root, "a.go", ""},
+		{sub, "3", ""},
+	}
+	for _, tt := range tests {
+		got, ok := c.resolve(tt.dir, tt.ref)
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("resolve(%s, %s) = %q, %v; want %q", tt.dir, tt.ref, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/diff"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const diffContextLines = 3
+
+type diffReview struct {
+	path     string
+	prompt   string
+	original string
+	proposed string
+	hunks    []diff.Hunk
+	accepted []bool
+	cursor   int
+	offsets  []int
+	viewport viewport.Model
+}
+
+func newDiffReview(path, prompt, original, proposed string, width, height int) *diffReview {
+	hunks := diff.Hunks(original, proposed, diffContextLines)
+	accepted := make([]bool, len(hunks))
+	for i := range accepted {
+		accepted[i] = true
+	}
+	return &diffReview{
+		path:     path,
+		prompt:   prompt,
+		original: original,
+		proposed: proposed,
+		hunks:    hunks,
+		accepted: accepted,
+		viewport: viewport.New(width, height),
+	}
+}
+
+func (r *diffReview) acceptedCount() int {
+	n := 0
+	for _, ok := range r.accepted {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *diffReview) result() string {
+	return diff.Apply(r.original, r.hunks, r.accepted)
+}
+
+func (r *diffReview) refresh(s styles) {
+	var sb strings.Builder
+	r.offsets = r.offsets[:0]
+	line := 0
+	for i, h := range r.hunks {
+		r.offsets = append(r.offsets, line)
+
+		mark := "[ ]"
+		if r.accepted[i] {
+			mark = "[✓]"
+		}
+		pointer := "  "
+		headerStyle := s.diffHunk
+		if i == r.cursor {
+			pointer = "▶ "
+			headerStyle = s.diffCursor
+		}
+		sb.WriteString(headerStyle.Render(fmt.Sprintf("%s%s %s", pointer, mark, h.Header())) + "\n")
+		line++
+
+		for _, l := range h.Lines {
+			text := diff.Prefix(l.Kind) + strings.TrimRight(l.Text, "\r\n")
+			style := s.diffContext
+			if r.accepted[i] {
+				switch l.Kind {
+				case diff.Insert:
+					style = s.diffAdd
+				case diff.Delete:
+					style = s.diffDel
+				}
+			}
+			sb.WriteString("    " + style.Render(text) + "\n")
+			line++
+		}
+		sb.WriteString("\n")
+		line++
+	}
+	r.viewport.SetContent(sb.String())
+}
+
+func (r *diffReview) scrollToCursor() {
+	if r.cursor >= len(r.offsets) {
+		return
+	}
+	top := r.offsets[r.cursor]
+	if top < r.viewport.YOffset || top >= r.viewport.YOffset+r.viewport.Height {
+		r.viewport.SetYOffset(top)
+	}
+}
+
+func (m *model) openDiffReview(path, prompt, original, proposed string) tea.Cmd {
+	review := newDiffReview(path, prompt, original, proposed, m.width-4, m.height-8)
+	if len(review.hunks) == 0 {
+		m.addMessage(roleInfo, "The AI proposed no changes to '"+filepath.Base(path)+"'.")
+		m.mode = modeExplorer
+		return nil
+	}
+	review.refresh(m.styles)
+	m.diffReview = review
+	m.mode = modeDiffReview
+	m.addMessage(roleInfo, fmt.Sprintf("Reviewing %d change(s) proposed for '%s'.", len(review.hunks), filepath.Base(path)))
+	return nil
+}
+
+func (m *model) updateDiffReview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := m.diffReview
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	switch m.keys.action(scopeDiff, msg.String()) {
+	case "reject":
+		m.diffReview = nil
+		m.mode = modeExplorer
+		m.addMessage(roleInfo, "Changes to '"+filepath.Base(r.path)+"' rejected. The file was not modified.")
+		return m, nil
+	case "up":
+		if r.cursor > 0 {
+			r.cursor--
+		}
+		r.refresh(m.styles)
+		r.scrollToCursor()
+		return m, nil
+	case "down":
+		if r.cursor < len(r.hunks)-1 {
+			r.cursor++
+		}
+		r.refresh(m.styles)
+		r.scrollToCursor()
+		return m, nil
+	case "toggle":
+		r.accepted[r.cursor] = !r.accepted[r.cursor]
+		r.refresh(m.styles)
+		return m, nil
+	case "accept-all":
+		for i := range r.accepted {
+			r.accepted[i] = true
+		}
+		return m, m.applyDiffReview()
+	case "apply":
+		return m, m.applyDiffReview()
+	}
+
+	var cmd tea.Cmd
+	r.viewport, cmd = r.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m *model) applyDiffReview() tea.Cmd {
+	r := m.diffReview
+	m.diffReview = nil
+	if r.acceptedCount() == 0 {
+		m.mode = modeExplorer
+		m.addMessage(roleInfo, "No changes accepted. '"+filepath.Base(r.path)+"' was not modified.")
+		return nil
+	}
+	m.addMessage(roleInfo, fmt.Sprintf("Writing %d of %d change(s) to '%s'...", r.acceptedCount(), len(r.hunks), filepath.Base(r.path)))
+	return m.createFileWithContent(r.path, r.result(), r.prompt)
+}
+
+func (m model) diffReviewView() string {
+	r := m.diffReview
+	header := m.styles.header.Render(fmt.Sprintf("Review changes: %s (%d/%d hunks accepted)", r.path, r.acceptedCount(), len(r.hunks)))
+	help := m.styles.statusText.Render(m.keys.footer(scopeDiff, "up/down", "select hunk", "toggle", "toggle", "accept-all", "accept all", "apply", "apply accepted", "reject", "reject"))
+	return m.styles.app.Render(lipgloss.JoinVertical(lipgloss.Left, header, r.viewport.View(), m.styles.statusBar.Width(m.width-4).Render(help)))
+}
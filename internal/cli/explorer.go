@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/git"
+	"github.com/anthonycursewl/anx-agent/internal/ignore"
+	"github.com/anthonycursewl/anx-agent/internal/utils"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	sortByName int = iota
+	sortBySize
+	sortByModTime
+)
+
+var sortNames = []string{"name", "size", "modified"}
+
+type item struct {
+	path      string
+	isDir     bool
+	size      int64
+	modTime   time.Time
+	gitStatus string
+}
+
+func (i item) Title() string {
+	title := "📄 " + filepath.Base(i.path)
+	if i.path == ".." {
+		title = "📁 .."
+	} else if i.isDir {
+		title = "📁 " + filepath.Base(i.path) + "/"
+	}
+	if i.gitStatus != "" {
+		title += "  [" + i.gitStatus + "]"
+	}
+	return title
+}
+
+func (i item) Description() string {
+	switch {
+	case i.path == "..":
+		return "parent directory"
+	case i.isDir:
+		return "directory • " + i.modTime.Format("2006-01-02 15:04")
+	}
+	return utils.FormatSize(i.size) + " • " + i.modTime.Format("2006-01-02 15:04")
+}
+
+func (i item) FilterValue() string { return i.path }
+
+type listingOptions struct {
+	showHidden  bool
+	showIgnored bool
+	sortBy      int
+	status      *statusCache
+	refresh     bool
+}
+
+// statusCache keeps the git status of the repository being browsed, so that
+// moving between its directories does not run git status each time. Listing
+// the same directory again, as after a change, refreshes it.
+type statusCache struct {
+	mu     sync.Mutex
+	root   string
+	status map[string]string
+}
+
+func (c *statusCache) get(dir string, refresh bool) map[string]string {
+	if c == nil {
+		status, _ := git.Status(dir)
+		return status
+	}
+	root, err := git.Root(dir)
+	if err != nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if refresh || root != c.root || c.status == nil {
+		c.root = root
+		c.status, _ = git.Status(root)
+	}
+	return c.status
+}
+
+type directoryListedMsg struct {
+	path    string
+	items   []list.Item
+	skipped int
+}
+
+func (m *model) listDirectory(path string) tea.Cmd {
+	opts := listingOptions{showHidden: m.showHidden, showIgnored: m.showIgnored, sortBy: m.sortBy, status: m.gitStatus, refresh: path == m.currentPath}
+	m.currentPath = path
+	return func() tea.Msg {
+		items, skipped, err := readDirectory(path, opts)
+		if err != nil {
+			return errMsg{err}
+		}
+		return directoryListedMsg{path: path, items: items, skipped: skipped}
+	}
+}
+
+// readDirectory lists path with directories first, leaving out hidden and
+// ignored entries unless opts asks for them. It also reports how many
+// entries were left out.
+func readDirectory(path string, opts listingOptions) ([]list.Item, int, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not read directory: %w", err)
+	}
+
+	root := "."
+	if rel, err := filepath.Rel(root, path); err != nil || strings.HasPrefix(rel, "..") {
+		root = path
+	}
+	matcher, err := ignore.ForDir(root, path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	absDir, _ := filepath.Abs(path)
+	if real, err := filepath.EvalSymlinks(absDir); err == nil {
+		absDir = real
+	}
+	status := opts.status.get(path, opts.refresh)
+
+	var items []item
+	skipped := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if !opts.showHidden && strings.HasPrefix(name, ".") {
+			skipped++
+			continue
+		}
+		if !opts.showIgnored {
+			if rel, err := filepath.Rel(root, filepath.Join(path, name)); err == nil && matcher.Ignored(rel, entry.IsDir()) {
+				skipped++
+				continue
+			}
+		}
+
+		it := item{path: name, isDir: entry.IsDir()}
+		if info, err := entry.Info(); err == nil {
+			it.size = info.Size()
+			it.modTime = info.ModTime()
+		}
+		it.gitStatus = gitMarker(status, filepath.Join(absDir, name), it.isDir)
+		items = append(items, it)
+	}
+
+	sortItems(items, opts.sortBy)
+
+	listItems := []list.Item{item{path: "..", isDir: true}}
+	for _, it := range items {
+		listItems = append(listItems, it)
+	}
+	return listItems, skipped, nil
+}
+
+func gitMarker(status map[string]string, path string, isDir bool) string {
+	if code, ok := status[path]; ok {
+		return git.Marker(code)
+	}
+	if !isDir {
+		return ""
+	}
+	prefix := path + string(filepath.Separator)
+	for changed := range status {
+		if strings.HasPrefix(changed, prefix) {
+			return "•"
+		}
+	}
+	return ""
+}
+
+func sortItems(items []item, sortBy int) {
+	sort.SliceStable(items, func(a, b int) bool {
+		x, y := items[a], items[b]
+		if x.isDir != y.isDir {
+			return x.isDir
+		}
+		switch sortBy {
+		case sortBySize:
+			if x.size != y.size {
+				return x.size > y.size
+			}
+		case sortByModTime:
+			if !x.modTime.Equal(y.modTime) {
+				return x.modTime.After(y.modTime)
+			}
+		}
+		return strings.ToLower(x.path) < strings.ToLower(y.path)
+	})
+}
+
+// relistDirectory reloads the current directory keeping the highlighted entry.
+func (m *model) relistDirectory() tea.Cmd {
+	if selected, ok := m.list.SelectedItem().(item); ok {
+		m.pendingSelect = selected.path
+	}
+	return m.listDirectory(m.currentPath)
+}
+
+func (m *model) explorerTitle(skipped int) string {
+	title := fmt.Sprintf("File Explorer: %s • sort: %s", m.currentPath, sortNames[m.sortBy])
+	if skipped > 0 {
+		title += fmt.Sprintf(" • %d hidden", skipped)
+	}
+	return title
+}
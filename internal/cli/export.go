@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/reporting"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type conversationExportedMsg struct {
+	path     string
+	format   reporting.Format
+	messages int
+}
+
+func exportCommand(m *model, args Args) tea.Cmd {
+	format := reporting.Markdown
+	path := args.String("file")
+	if name := args.String("format"); name != "" {
+		f, err := reporting.ParseFormat(name)
+		if err != nil {
+			m.addMessage(roleError, "export: "+err.Error())
+			return nil
+		}
+		format = f
+	} else if path != "" {
+		format = reporting.FormatOf(path)
+	}
+	if path == "" {
+		path = filepath.Join(m.currentPath, "anx-"+m.session.ID+"."+string(format))
+	}
+	force := args.Bool("force")
+
+	m.snapshot()
+	sess := *m.session
+	sess.Messages = append(sess.Messages[:0:0], m.session.Messages...)
+	sess.Context = append(sess.Context[:0:0], m.session.Context...)
+	return func() tea.Msg {
+		if _, err := os.Stat(path); err == nil && !force {
+			return errMsg{fmt.Errorf("'%s' already exists; use --force to overwrite it", displayPath(path))}
+		}
+		data, err := reporting.Export(&sess, format, time.Now())
+		if err != nil {
+			return errMsg{err}
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return errMsg{fmt.Errorf("error writing export: %w", err)}
+		}
+		return conversationExportedMsg{path: path, format: format, messages: len(sess.Messages)}
+	}
+}
+
+func (m *model) handleConversationExported(msg conversationExportedMsg) {
+	m.addMessage(roleInfo, fmt.Sprintf("📄 Exported %d message(s) as %s to %s.", msg.messages, msg.format, displayPath(msg.path)))
+}
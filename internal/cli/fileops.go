@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/journal"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fileOpDoneMsg reports a finished explorer file operation. from and to are
+// the paths as the explorer knows them, so the context set can follow them.
+type fileOpDoneMsg struct {
+	op      string
+	from    string
+	to      string
+	message string
+}
+
+// selectedPath returns the path of the highlighted explorer entry, ignoring
+// the parent directory entry.
+func (m *model) selectedPath() (string, item, bool) {
+	selected, ok := m.list.SelectedItem().(item)
+	if !ok || selected.path == ".." {
+		return "", item{}, false
+	}
+	return filepath.Join(m.currentPath, selected.path), selected, true
+}
+
+func (m *model) promptFileOp(mode int, placeholder, value string) tea.Cmd {
+	m.mode = mode
+	m.textInput.Placeholder = placeholder
+	m.textInput.SetValue(value)
+	m.textInput.Focus()
+	return textarea.Blink
+}
+
+// fileOpKey starts the explorer file operation bound to key.
+func (m *model) fileOpKey(key string) tea.Cmd {
+	if key == "n" {
+		return m.promptFileOp(modeMkdirInput, "New directory name...", "")
+	}
+
+	path, selected, ok := m.selectedPath()
+	if !ok {
+		return nil
+	}
+	m.fileOpSource = path
+
+	switch key {
+	case "r":
+		return m.promptFileOp(modeRenameInput, "New name...", selected.path)
+	case "m":
+		return m.promptFileOp(modeMoveInput, "Move to (directory or new path)...", "")
+	case "y":
+		return m.promptFileOp(modeCopyInput, "Copy to (directory or new path)...", "")
+	case "D":
+		m.mode = modeConfirmDelete
+		return nil
+	}
+
+	target := duplicateName(path)
+	return m.runFileOp(journal.OpCopy, path, target, func() (journal.Entry, error) {
+		return m.journal.Copy(path, target)
+	})
+}
+
+// duplicateName finds a free name next to path in the style "name copy.ext",
+// "name copy 2.ext" and so on.
+func duplicateName(path string) string {
+	ext := ""
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		ext = filepath.Ext(path)
+	}
+	base := strings.TrimSuffix(path, ext) + " copy"
+	target := base + ext
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(target); os.IsNotExist(err) {
+			return target
+		}
+		target = fmt.Sprintf("%s %d%s", base, n, ext)
+	}
+}
+
+// fileOpTarget resolves the destination typed for a move or copy. Relative
+// paths start at the current directory, and an existing directory receives
+// the source under its own name.
+func (m *model) fileOpTarget(input, source string) string {
+	target := input
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(m.currentPath, target)
+	}
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		target = filepath.Join(target, filepath.Base(source))
+	}
+	return target
+}
+
+func (m *model) submitFileOp(input string) tea.Cmd {
+	source := m.fileOpSource
+	mode := m.mode
+	m.fileOpSource = ""
+	if input == "" {
+		m.loading = true
+		return func() tea.Msg { return errMsg{fmt.Errorf("a name is required")} }
+	}
+
+	switch mode {
+	case modeMkdirInput:
+		target := filepath.Join(m.currentPath, input)
+		return m.runFileOp(journal.OpMkdir, "", target, func() (journal.Entry, error) {
+			return m.journal.Mkdir(target)
+		})
+	case modeRenameInput:
+		target := filepath.Join(filepath.Dir(source), input)
+		return m.runFileOp(journal.OpMove, source, target, func() (journal.Entry, error) {
+			return m.journal.Move(source, target)
+		})
+	case modeMoveInput:
+		target := m.fileOpTarget(input, source)
+		return m.runFileOp(journal.OpMove, source, target, func() (journal.Entry, error) {
+			return m.journal.Move(source, target)
+		})
+	case modeCopyInput:
+		target := m.fileOpTarget(input, source)
+		return m.runFileOp(journal.OpCopy, source, target, func() (journal.Entry, error) {
+			return m.journal.Copy(source, target)
+		})
+	}
+	return nil
+}
+
+func (m *model) runFileOp(op, from, to string, run func() (journal.Entry, error)) tea.Cmd {
+	m.mode = modeExplorer
+	m.textInput.Placeholder = "Write a message or command..."
+	title := displayPath(to)
+	if from != "" {
+		title = displayPath(from)
+	}
+	return m.startJob(op, title, func(ctx context.Context, progress func(string)) tea.Msg {
+		if _, err := run(); err != nil {
+			return errMsg{err}
+		}
+		var message string
+		switch op {
+		case journal.OpMkdir:
+			message = "Created directory: " + to
+		case journal.OpMove:
+			message = "Moved '" + from + "' to '" + to + "'"
+		case journal.OpCopy:
+			message = "Copied '" + from + "' to '" + to + "'"
+		case journal.OpDelete:
+			message = "Moved '" + from + "' to the trash"
+		}
+		return fileOpDoneMsg{op: op, from: from, to: to, message: message}
+	})
+}
+
+func (m *model) handleFileOpDone(msg fileOpDoneMsg) tea.Cmd {
+	m.previewPath = ""
+	m.addMessage(roleInfo, "✅ "+msg.message+". Use 'undo' to revert it.")
+
+	switch msg.op {
+	case journal.OpMove:
+		m.contextFiles.move(msg.from, msg.to)
+	case journal.OpDelete:
+		m.contextFiles.move(msg.from, "")
+	}
+	if msg.to != "" && filepath.Dir(msg.to) == filepath.Clean(m.currentPath) {
+		m.pendingSelect = filepath.Base(msg.to)
+	}
+	return m.listDirectory(m.currentPath)
+}
+
+func (m *model) updateConfirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	path := m.fileOpSource
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	switch m.keys.action(scopeConfirm, msg.String()) {
+	case "yes":
+		m.fileOpSource = ""
+		m.mode = modeExplorer
+		return m, m.runFileOp(journal.OpDelete, path, "", func() (journal.Entry, error) {
+			return m.journal.Delete(path)
+		})
+	case "no":
+		m.fileOpSource = ""
+		m.mode = modeExplorer
+	}
+	return m, nil
+}
+
+func (m model) confirmDeleteView() string {
+	what := "file"
+	if info, err := os.Stat(m.fileOpSource); err == nil && info.IsDir() {
+		what = "directory"
+	}
+	return m.styles.errorMsg.Render(fmt.Sprintf("\nDelete %s '%s'? It is moved to the trash and 'undo' restores it. (%s/%s)", what, m.fileOpSource, m.keys.hint(scopeConfirm, "yes"), m.keys.hint(scopeConfirm, "no")))
+}
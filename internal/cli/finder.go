@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/ignore"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+)
+
+const maxFinderFiles = 20000
+
+type finderIndexedMsg struct {
+	files     []string
+	truncated bool
+}
+
+// fileFinder is the fuzzy file search opened with '/' or Ctrl+P. It indexes
+// the workspace once per opening so results always reflect the disk.
+type fileFinder struct {
+	input      textinput.Model
+	files      []string
+	matches    fuzzy.Matches
+	cursor     int
+	indexing   bool
+	truncated  bool
+	status     string
+	returnMode int
+}
+
+func (m *model) openFinder() tea.Cmd {
+	ti := textinput.New()
+	ti.Prompt = "🔍 "
+	ti.Placeholder = "Type to search files in the workspace..."
+	ti.Focus()
+
+	m.finder = &fileFinder{input: ti, indexing: true, returnMode: m.mode}
+	m.mode = modeFinder
+	return tea.Batch(textinput.Blink, indexWorkspace("."))
+}
+
+func indexWorkspace(root string) tea.Cmd {
+	return func() tea.Msg {
+		var files []string
+		truncated := false
+		err := ignore.Walk(root, func(rel string, d fs.DirEntry) error {
+			if d.IsDir() {
+				return nil
+			}
+			if len(files) >= maxFinderFiles {
+				truncated = true
+				return filepath.SkipAll
+			}
+			files = append(files, rel)
+			return nil
+		})
+		if err != nil {
+			return errMsg{err}
+		}
+		return finderIndexedMsg{files: files, truncated: truncated}
+	}
+}
+
+func (f *fileFinder) filter() {
+	query := strings.TrimSpace(f.input.Value())
+	if query == "" {
+		f.matches = make(fuzzy.Matches, len(f.files))
+		for i, file := range f.files {
+			f.matches[i] = fuzzy.Match{Str: file, Index: i}
+		}
+	} else {
+		f.matches = fuzzy.Find(query, f.files)
+	}
+	if f.cursor >= len(f.matches) {
+		f.cursor = len(f.matches) - 1
+	}
+	if f.cursor < 0 {
+		f.cursor = 0
+	}
+}
+
+func (f *fileFinder) selected() (string, bool) {
+	if f.cursor < 0 || f.cursor >= len(f.matches) {
+		return "", false
+	}
+	return filepath.FromSlash(f.matches[f.cursor].Str), true
+}
+
+func (m *model) handleFinderIndexed(msg finderIndexedMsg) {
+	if m.finder == nil {
+		return
+	}
+	m.finder.files = msg.files
+	m.finder.truncated = msg.truncated
+	m.finder.indexing = false
+	m.finder.filter()
+}
+
+func (m *model) updateFinder(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := m.finder
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	switch m.keys.action(scopeFinder, msg.String()) {
+	case "back":
+		m.mode = f.returnMode
+		m.finder = nil
+		return m, nil
+	case "up":
+		if f.cursor > 0 {
+			f.cursor--
+		}
+		return m, nil
+	case "down":
+		if f.cursor < len(f.matches)-1 {
+			f.cursor++
+		}
+		return m, nil
+	case "open":
+		path, ok := f.selected()
+		if !ok {
+			return m, nil
+		}
+		m.finder = nil
+		m.mode = modeExplorer
+		m.loading = true
+		m.addMessage(roleInfo, "Reading file "+path+" to modify...")
+		return m, tea.Batch(m.listDirectory(filepath.Dir(path)), m.readFileContent(path))
+	case "show":
+		path, ok := f.selected()
+		if !ok {
+			return m, nil
+		}
+		m.finder = nil
+		m.mode = modeExplorer
+		m.pendingSelect = filepath.Base(path)
+		return m, m.listDirectory(filepath.Dir(path))
+	case "context":
+		path, ok := f.selected()
+		if !ok {
+			return m, nil
+		}
+		if m.contextFiles.remove(path) {
+			f.status = "Removed '" + path + "' from the context (" + m.contextFiles.summary() + ")."
+			return m, nil
+		}
+		f.status = "Added '" + path + "' to the context."
+		return m, m.refreshContextFile(path)
+	}
+
+	var cmd tea.Cmd
+	previous := f.input.Value()
+	f.input, cmd = f.input.Update(msg)
+	if f.input.Value() != previous {
+		f.cursor = 0
+		f.filter()
+	}
+	return m, cmd
+}
+
+func (m model) finderView() string {
+	f := m.finder
+	var sb strings.Builder
+
+	visible := m.height - 9
+	if visible < 3 {
+		visible = 3
+	}
+	start := 0
+	if f.cursor >= visible {
+		start = f.cursor - visible + 1
+	}
+	end := start + visible
+	if end > len(f.matches) {
+		end = len(f.matches)
+	}
+
+	for i := start; i < end; i++ {
+		match := f.matches[i]
+		var line strings.Builder
+		matched := map[int]bool{}
+		for _, idx := range match.MatchedIndexes {
+			matched[idx] = true
+		}
+		for idx, r := range match.Str {
+			if matched[idx] {
+				line.WriteString(m.styles.finderMatch.Render(string(r)))
+			} else {
+				line.WriteRune(r)
+			}
+		}
+		marker := "  "
+		if m.contextFiles.contains(filepath.FromSlash(match.Str)) {
+			marker = "● "
+		}
+		if i == f.cursor {
+			sb.WriteString(m.styles.diffCursor.Render("▶ ") + marker + line.String() + "\n")
+		} else {
+			sb.WriteString("  " + marker + line.String() + "\n")
+		}
+	}
+
+	var count string
+	switch {
+	case f.indexing:
+		count = "Indexing workspace..."
+	case f.truncated:
+		count = fmt.Sprintf("%d/%d+ files (index truncated)", len(f.matches), len(f.files))
+	default:
+		count = fmt.Sprintf("%d/%d files", len(f.matches), len(f.files))
+	}
+
+	footer := m.keys.footer(scopeFinder, "open", "modify", "show", "show in explorer", "context", "add/remove context", "back", "back")
+	if f.status != "" {
+		footer = f.status + "\n" + footer
+	}
+
+	return m.styles.app.Render(lipgloss.JoinVertical(lipgloss.Left,
+		m.styles.header.Render("Find File"),
+		f.input.View(),
+		m.styles.infoMsg.Render(count),
+		"",
+		sb.String(),
+		m.styles.statusBar.Width(m.width-4).Render(m.styles.statusText.Render(footer)),
+	))
+}
@@ -0,0 +1,271 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/agent/project"
+	"github.com/anthonycursewl/anx-agent/internal/agent/reviewer"
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/diff"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// reviewDoneMsg carries the result of a 'review' job.
+type reviewDoneMsg struct {
+	review *reviewer.Review
+}
+
+// findingsPanel is the state of the 'findings' view.
+type findingsPanel struct {
+	cursor     int
+	returnMode int
+	status     string
+}
+
+// reviewFileLimitKB leaves large generated files out of reviews of
+// directories.
+const reviewFileLimitKB = 100
+
+// isPatchFile reports whether path names a diff by its extension.
+func isPatchFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".diff" || ext == ".patch"
+}
+
+func reviewCommand(m *model, args Args) tea.Cmd {
+	paths := args.Rest()
+	for i, p := range paths {
+		paths[i] = displayPath(p)
+	}
+	patchFile := args.String("diff")
+	if patchFile == "" && len(paths) == 1 && isPatchFile(paths[0]) {
+		patchFile = paths[0]
+	}
+	if patchFile != "" {
+		return m.reviewDiff(patchFile, args.String("focus"))
+	}
+	var files []project.File
+	target := strings.Join(paths, " ")
+	if len(paths) == 0 {
+		if m.contextFiles.empty() {
+			m.addMessage(roleError, "Nothing to review: name files or directories, or add files to the context first.\nUsage: "+m.commands["review"].Usage())
+			return nil
+		}
+		for _, f := range m.contextFiles.files {
+			files = append(files, project.File{Path: filepath.ToSlash(displayPath(f.path)), Content: []byte(f.content)})
+		}
+		target = "the context (" + m.contextFiles.summary() + ")"
+	}
+
+	r := &reviewer.Reviewer{Client: m.aiClient, Focus: args.String("focus")}
+	m.addMessage(roleInfo, "🔎 Reviewing "+target+"...")
+	return m.startJob("review", target, func(ctx context.Context, progress func(string)) tea.Msg {
+		if len(paths) > 0 {
+			found, _, err := project.CollectFiles(paths, nil, nil, reviewFileLimitKB)
+			if err != nil {
+				return errMsg{err}
+			}
+			if len(found) == 0 {
+				return errMsg{fmt.Errorf("no files to review in %s", strings.Join(paths, ", "))}
+			}
+			files = found
+		}
+		r.Progress = func(e reviewer.Event) {
+			progress(fmt.Sprintf("%d/%d %s", e.Index, e.Total, e.File))
+		}
+		review, err := r.ReviewFiles(ctx, target, files)
+		if err != nil {
+			return errMsg{err}
+		}
+		return reviewDoneMsg{review: review}
+	})
+}
+
+// reviewDiff reviews the changes of the unified diff in path.
+func (m *model) reviewDiff(path, focus string) tea.Cmd {
+	r := &reviewer.Reviewer{Client: m.aiClient, Focus: focus}
+	m.addMessage(roleInfo, "🔎 Reviewing the changes of "+path+"...")
+	return m.startJob("review", path, func(ctx context.Context, progress func(string)) tea.Msg {
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return errMsg{fmt.Errorf("error reading the diff: %w", err)}
+		}
+		patches, err := diff.ParsePatch(string(text))
+		if err != nil {
+			return errMsg{fmt.Errorf("error parsing the diff: %w", err)}
+		}
+		if len(patches) == 0 {
+			return errMsg{fmt.Errorf("%s has no changes", path)}
+		}
+		r.Progress = func(e reviewer.Event) {
+			progress(fmt.Sprintf("%d/%d %s", e.Index, e.Total, e.File))
+		}
+		review, err := r.ReviewPatches(ctx, path, patches)
+		if err != nil {
+			return errMsg{err}
+		}
+		return reviewDoneMsg{review: review}
+	})
+}
+
+func (m *model) handleReviewDone(msg reviewDoneMsg) tea.Cmd {
+	review := msg.review
+	m.lastReview = review
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔎 Review of %s: %s in %d file(s).", review.Target, review.Counts(), len(review.Files))
+	const shown = 5
+	for i, f := range review.Findings {
+		if i == shown {
+			fmt.Fprintf(&sb, "\n  ... and %d more", len(review.Findings)-shown)
+			break
+		}
+		fmt.Fprintf(&sb, "\n  [%s] %s: %s", f.Severity, f.Location(), f.Message)
+	}
+	if len(review.Findings) > 0 {
+		sb.WriteString("\n'findings' to browse them.")
+	}
+	// The summary shows as an answer, with the model and token cost of the
+	// review, but is not part of the conversation.
+	m.addReply(ai.Reply{Text: sb.String(), Usage: review.Usage}, nil)
+	if len(review.Findings) > 0 && m.canOpenReview() {
+		m.openFindings()
+	}
+	return nil
+}
+
+func findingsCommand(m *model, args Args) tea.Cmd {
+	if m.lastReview == nil {
+		m.addMessage(roleError, "No review yet. Use '/review [path...]' first.")
+		return nil
+	}
+	if len(m.lastReview.Findings) == 0 {
+		m.addMessage(roleInfo, "The last review has no findings.")
+		return nil
+	}
+	m.openFindings()
+	return nil
+}
+
+func (m *model) openFindings() {
+	m.findingsPanel = findingsPanel{returnMode: m.mode}
+	m.mode = modeFindings
+}
+
+func (m *model) updateFindings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	findings := m.lastReview.Findings
+	p := &m.findingsPanel
+	p.status = ""
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	switch m.keys.action(scopeFindings, msg.String()) {
+	case "back":
+		m.mode = p.returnMode
+	case "up":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down":
+		if p.cursor < len(findings)-1 {
+			p.cursor++
+		}
+	case "open":
+		return m, openAtLine(findings[p.cursor])
+	case "explorer":
+		f := findings[p.cursor]
+		m.mode = modeExplorer
+		m.pendingSelect = filepath.Base(f.File)
+		return m, m.listDirectory(filepath.Dir(filepath.FromSlash(f.File)))
+	case "copy-fix":
+		f := findings[p.cursor]
+		if f.Fix == "" {
+			p.status = "This finding has no suggested fix."
+			return m, nil
+		}
+		return m, copyToClipboard(f.Fix, "suggested fix")
+	}
+	return m, nil
+}
+
+// openAtLine opens the file of a finding in the user's editor, at its first
+// line for the editors that understand +N.
+func openAtLine(f reviewer.Finding) tea.Cmd {
+	args := []string{filepath.FromSlash(f.File)}
+	if f.StartLine > 0 {
+		args = append([]string{"+" + strconv.Itoa(f.StartLine)}, args...)
+	}
+	return tea.ExecProcess(editorCommand(args...), func(err error) tea.Msg {
+		if err != nil {
+			return errMsg{fmt.Errorf("error running editor: %w", err)}
+		}
+		return nil
+	})
+}
+
+func (m model) severityStyle(s reviewer.Severity) lipgloss.Style {
+	switch s {
+	case reviewer.Critical, reviewer.High:
+		return m.styles.errorMsg.UnsetMargins()
+	case reviewer.Medium:
+		return m.styles.diffHunk
+	case reviewer.Low:
+		return m.styles.infoMsg.UnsetMargins()
+	}
+	return m.styles.diffContext
+}
+
+func (m model) findingsView() string {
+	review := m.lastReview
+	p := m.findingsPanel
+	width := m.width - 4
+
+	// The list scrolls to keep the cursor in view above the details.
+	listHeight := m.height/2 - 4
+	if listHeight < 3 {
+		listHeight = 3
+	}
+	first := 0
+	if p.cursor >= listHeight {
+		first = p.cursor - listHeight + 1
+	}
+	var list strings.Builder
+	for i := first; i < len(review.Findings) && i < first+listHeight; i++ {
+		f := review.Findings[i]
+		severity := m.severityStyle(f.Severity).Render(fmt.Sprintf("%-8s", f.Severity))
+		line := fmt.Sprintf("%s %-15s %s  %s", severity, truncate(f.Category, 15), f.Location(), m.styles.diffContext.Render(f.Message))
+		line = ansi.Truncate(line, width-2, "…")
+		if i == p.cursor {
+			list.WriteString(m.styles.diffCursor.Render("▶ ") + line + "\n")
+		} else {
+			list.WriteString("  " + line + "\n")
+		}
+	}
+
+	f := review.Findings[p.cursor]
+	details := []string{
+		m.severityStyle(f.Severity).Bold(true).Render(strings.ToUpper(f.Severity.String())) + " " + f.Category + " · " + f.Location(),
+		lipgloss.NewStyle().Width(width).Render(f.Message),
+	}
+	if f.Fix != "" {
+		details = append(details, "", m.styles.diffHunk.Render("Suggested fix:"), lipgloss.NewStyle().Width(width).Render(f.Fix))
+	}
+
+	footer := m.keys.footer(scopeFindings, "up/down", "select", "open", "open in $EDITOR", "explorer", "show in explorer", "copy-fix", "copy fix", "back", "back")
+	if p.status != "" {
+		footer = p.status + "\n" + footer
+	}
+	return m.styles.app.Render(lipgloss.JoinVertical(lipgloss.Left,
+		m.styles.header.Render(fmt.Sprintf("Review of %s: %s", review.Target, review.Counts())),
+		list.String(),
+		strings.Join(details, "\n"),
+		"",
+		m.styles.statusBar.Width(width).Render(m.styles.statusText.Render(footer)),
+	))
+}
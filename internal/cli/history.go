@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/journal"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type historyLoadedMsg []journal.Entry
+
+type changeRevertedMsg journal.Entry
+
+type historyItem struct {
+	entry journal.Entry
+}
+
+func (i historyItem) Title() string {
+	title := fmt.Sprintf("%-6s %s", i.entry.Op, displayPath(i.entry.Path))
+	if i.entry.From != "" {
+		title = fmt.Sprintf("%-6s %s → %s", i.entry.Op, displayPath(i.entry.From), displayPath(i.entry.Path))
+	}
+	if i.entry.Reverted {
+		title += " (reverted)"
+	}
+	return title
+}
+
+func (i historyItem) Description() string {
+	desc := i.entry.Time.Format("2006-01-02 15:04:05")
+	if i.entry.Prompt != "" {
+		desc += " • " + i.entry.Prompt
+	}
+	return desc
+}
+
+func (i historyItem) FilterValue() string { return i.entry.Path }
+
+func displayPath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+func newHistoryList() list.Model {
+	l := list.New(nil, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Change History"
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	// The keys of the view are handled by updateHistory; see applyKeyMap.
+	l.KeyMap.Quit.SetEnabled(false)
+	return l
+}
+
+func undoCommand(m *model, args Args) tea.Cmd {
+	j := m.journal
+	return m.startJob("undo", "last change", func(ctx context.Context, progress func(string)) tea.Msg {
+		entry, err := j.Undo()
+		if err != nil {
+			return errMsg{err}
+		}
+		return changeRevertedMsg(entry)
+	})
+}
+
+func historyCommand(m *model, args Args) tea.Cmd {
+	return m.loadHistory()
+}
+
+func (m *model) loadHistory() tea.Cmd {
+	return func() tea.Msg {
+		entries, err := m.journal.List()
+		if err != nil {
+			return errMsg{err}
+		}
+		return historyLoadedMsg(entries)
+	}
+}
+
+func (m *model) revertChange(id string) tea.Cmd {
+	j := m.journal
+	return m.startJob("revert", "change "+id, func(ctx context.Context, progress func(string)) tea.Msg {
+		entry, err := j.Revert(id)
+		if err != nil {
+			return errMsg{err}
+		}
+		return changeRevertedMsg(entry)
+	})
+}
+
+func (m *model) handleHistoryLoaded(entries []journal.Entry) tea.Cmd {
+	if len(entries) == 0 {
+		m.addMessage(roleInfo, "No changes recorded yet.")
+		return nil
+	}
+	items := make([]list.Item, len(entries))
+	for i, entry := range entries {
+		items[i] = historyItem{entry: entry}
+	}
+	m.mode = modeHistory
+	return m.historyList.SetItems(items)
+}
+
+func (m *model) handleChangeReverted(entry journal.Entry) tea.Cmd {
+	switch {
+	case entry.Op == journal.OpDelete || entry.Op == journal.OpRevert && entry.Trash != "":
+		m.addMessage(roleInfo, "↩ Restored '"+displayPath(entry.Path)+"' from the trash.")
+	case entry.Op == journal.OpMove:
+		m.addMessage(roleInfo, "↩ Moved '"+displayPath(entry.Path)+"' back to '"+displayPath(entry.From)+"'.")
+		m.contextFiles.move(displayPath(entry.Path), displayPath(entry.From))
+	case entry.Op == journal.OpCopy:
+		m.addMessage(roleInfo, "↩ Moved the copy '"+displayPath(entry.Path)+"' to the trash.")
+	case entry.Op == journal.OpMkdir:
+		m.addMessage(roleInfo, "↩ Removed '"+displayPath(entry.Path)+"'.")
+	case entry.Existed:
+		m.addMessage(roleInfo, "↩ Restored '"+displayPath(entry.Path)+"' to its state from "+entry.Time.Format("15:04:05")+".")
+	default:
+		m.addMessage(roleInfo, "↩ Removed '"+displayPath(entry.Path)+"', which did not exist before the change.")
+	}
+	m.previewPath = ""
+	if m.mode == modeHistory {
+		return tea.Batch(m.loadHistory(), m.listDirectory(m.currentPath))
+	}
+	return m.listDirectory(m.currentPath)
+}
+
+func (m *model) updateHistory(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	switch m.keys.action(scopeHistory, msg.String()) {
+	case "back":
+		m.mode = modeChat
+		return m, nil
+	case "revert":
+		selected, ok := m.historyList.SelectedItem().(historyItem)
+		if !ok {
+			return m, nil
+		}
+		return m, m.revertChange(selected.entry.ID)
+	}
+
+	var cmd tea.Cmd
+	m.historyList, cmd = m.historyList.Update(msg)
+	return m, cmd
+}
@@ -0,0 +1,251 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/config"
+	"github.com/anthonycursewl/anx-agent/internal/inputs"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// inputKinds names the modes whose inputs are remembered.
+var inputKinds = map[int]string{
+	modeChat:           "chat",
+	modeAIModifyInput:  "modify",
+	modeAIAnalyzeInput: "analyze",
+}
+
+// inputRecall tracks browsing the history with Up/Down and the Ctrl+R
+// reverse search. index is the recalled entry, or -1 while editing a new
+// draft.
+type inputRecall struct {
+	index     int
+	draft     string
+	searching bool
+	query     string
+	failed    bool
+}
+
+func openInputHistory() (*inputs.History, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		h, _ := inputs.Open("", inputs.DefaultLimit)
+		return h, err
+	}
+	return inputs.Open(filepath.Join(dir, inputs.FileName), inputs.DefaultLimit)
+}
+
+func (m *model) inputKind() (string, bool) {
+	kind, ok := inputKinds[m.mode]
+	return kind, ok
+}
+
+func (m *model) resetRecall() {
+	m.recall = inputRecall{index: -1}
+}
+
+func (m *model) recordInput(input string) {
+	m.resetRecall()
+	kind, ok := m.inputKind()
+	if !ok {
+		return
+	}
+	if err := m.inputHistory.Add(kind, input); err != nil {
+		m.addMessage(roleError, err.Error())
+	}
+}
+
+func (m *model) showRecalled(entries []string) {
+	if m.recall.index < 0 {
+		m.textInput.SetValue(m.recall.draft)
+	} else {
+		m.textInput.SetValue(entries[m.recall.index])
+	}
+	m.resizeComposer()
+}
+
+// recallInput moves through the history with Up on the first line of the
+// composer and Down on its last line, like a shell. direction is "up" or
+// "down".
+func (m *model) recallInput(direction string) bool {
+	kind, ok := m.inputKind()
+	if !ok {
+		return false
+	}
+	entries := m.inputHistory.List(kind)
+
+	switch direction {
+	case "up":
+		if len(entries) == 0 || m.textInput.Line() != 0 {
+			return false
+		}
+		if m.recall.index < 0 {
+			m.recall.draft = m.textInput.Value()
+			m.recall.index = len(entries)
+		}
+		if m.recall.index > 0 {
+			m.recall.index--
+		}
+		m.showRecalled(entries)
+		return true
+	case "down":
+		if m.recall.index < 0 || m.textInput.Line() != m.textInput.LineCount()-1 {
+			return false
+		}
+		m.recall.index++
+		if m.recall.index >= len(entries) {
+			m.recall.index = -1
+		}
+		m.showRecalled(entries)
+		return true
+	}
+	return false
+}
+
+func (m *model) startReverseSearch() bool {
+	if _, ok := m.inputKind(); !ok {
+		return false
+	}
+	if m.recall.index < 0 {
+		m.recall.draft = m.textInput.Value()
+	}
+	m.recall.searching = true
+	m.recall.query = ""
+	m.recall.failed = false
+	return true
+}
+
+func (m *model) searchInputs(from int) {
+	kind, _ := m.inputKind()
+	i, ok := m.inputHistory.Search(kind, m.recall.query, from)
+	m.recall.failed = !ok
+	if ok {
+		m.recall.index = i
+		m.showRecalled(m.inputHistory.List(kind))
+	}
+}
+
+// updateReverseSearch handles keys while Ctrl+R search is active. Typing
+// refines the query, Ctrl+R jumps to older matches, Enter keeps the match in
+// the composer and Esc or Ctrl+G restores the draft. Any other key keeps the
+// match and is then handled as usual.
+func (m *model) updateReverseSearch(msg tea.KeyMsg) bool {
+	kind, _ := m.inputKind()
+	action := m.keys.action(scopeInput, msg.String())
+	switch {
+	case action == "search-history":
+		from := len(m.inputHistory.List(kind))
+		if m.recall.index >= 0 {
+			from = m.recall.index
+		}
+		m.searchInputs(from)
+		return true
+	case msg.String() == "backspace":
+		if m.recall.query != "" {
+			runes := []rune(m.recall.query)
+			m.recall.query = string(runes[:len(runes)-1])
+			m.searchInputs(len(m.inputHistory.List(kind)))
+		}
+		return true
+	case action == "cancel" || msg.String() == "ctrl+g":
+		m.recall.index = -1
+		m.showRecalled(nil)
+		m.recall.searching = false
+		return true
+	case action == "submit":
+		m.recall.searching = false
+		return true
+	}
+
+	if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+		m.recall.query += string(msg.Runes)
+		from := len(m.inputHistory.List(kind))
+		if m.recall.index >= 0 {
+			from = m.recall.index + 1
+		}
+		m.searchInputs(from)
+		return true
+	}
+	m.recall.searching = false
+	return false
+}
+
+func (m *model) reverseSearchStatus() string {
+	if m.recall.failed {
+		return fmt.Sprintf("(failed reverse-i-search)`%s' | 'Esc' to cancel", m.recall.query)
+	}
+	return fmt.Sprintf("(reverse-i-search)`%s' | 'Ctrl+R' older | 'Enter' to keep | 'Esc' to cancel", m.recall.query)
+}
+
+const inputsUsage = "Usage: /inputs [chat|modify|analyze] | /inputs clear [chat|modify|analyze]"
+
+// knownInputKind reports whether kind names a mode whose inputs are
+// remembered.
+func knownInputKind(kind string) bool {
+	for _, k := range inputKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func inputsCommand(m *model, a Args) tea.Cmd {
+	args := a.Positional()
+	if len(args) > 0 && args[0] == "clear" {
+		kind := ""
+		if len(args) > 1 {
+			kind = args[1]
+		}
+		if kind != "" && !knownInputKind(kind) {
+			m.addMessage(roleError, "Unknown input kind '"+kind+"'.\n"+inputsUsage)
+			return nil
+		}
+		if err := m.inputHistory.Clear(kind); err != nil {
+			m.addMessage(roleError, err.Error())
+			return nil
+		}
+		if kind == "" {
+			m.addMessage(roleInfo, "Input history cleared.")
+		} else {
+			m.addMessage(roleInfo, "Input history for '"+kind+"' cleared.")
+		}
+		return nil
+	}
+
+	kinds := m.inputHistory.Kinds()
+	if len(args) > 0 {
+		if !knownInputKind(args[0]) {
+			m.addMessage(roleError, "Unknown input kind '"+args[0]+"'.\n"+inputsUsage)
+			return nil
+		}
+		kinds = []string{args[0]}
+	}
+	if len(kinds) == 0 {
+		m.addMessage(roleInfo, "No inputs recorded yet.")
+		return nil
+	}
+
+	const shown = 10
+	var sb strings.Builder
+	for _, kind := range kinds {
+		entries := m.inputHistory.List(kind)
+		sb.WriteString(fmt.Sprintf("Recent '%s' inputs (%d stored):\n", kind, len(entries)))
+		start := len(entries) - shown
+		if start < 0 {
+			start = 0
+		}
+		for i := len(entries) - 1; i >= start; i-- {
+			line := strings.SplitN(entries[i], "\n", 2)[0]
+			if len([]rune(line)) > 70 {
+				line = string([]rune(line)[:70]) + "…"
+			}
+			sb.WriteString(fmt.Sprintf("  %3d  %s\n", i+1, line))
+		}
+	}
+	sb.WriteString(inputsUsage)
+	m.addMessage(roleInfo, sb.String())
+	return nil
+}
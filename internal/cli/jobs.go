@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/jobs"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// jobDoneMsg carries the result of a background job back to the model.
+type jobDoneMsg struct {
+	id  int
+	msg tea.Msg
+}
+
+// jobsPanel is the state of the 'jobs' view.
+type jobsPanel struct {
+	cursor     int
+	returnMode int
+}
+
+// startJob runs work in the background as a job that can be listed and
+// canceled. The message work returns is handled once the job finishes,
+// unless it was canceled.
+func (m *model) startJob(kind, title string, work func(ctx context.Context, progress func(string)) tea.Msg) tea.Cmd {
+	manager := m.jobs
+	id, ctx := manager.Start(kind, title)
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		msg := work(ctx, func(p string) { manager.SetProgress(id, p) })
+		return jobDoneMsg{id: id, msg: msg}
+	})
+}
+
+// askAI is the work of a job that sends prompt and turns the reply into a
+// message.
+func (m *model) askAI(prompt string, reply func(ai.Reply) tea.Msg) func(context.Context, func(string)) tea.Msg {
+	client := m.aiClient
+	return func(ctx context.Context, progress func(string)) tea.Msg {
+		progress("waiting for " + ai.Model)
+		res, err := client.Generate(ctx, prompt)
+		if err != nil {
+			return errMsg{err}
+		}
+		return reply(res)
+	}
+}
+
+func (m *model) busy() bool { return m.loading || m.jobs.Running() > 0 }
+
+func (m model) jobsStatus(running int) string {
+	return fmt.Sprintf("%d job(s) running ('jobs' to see them)", running)
+}
+
+func (m model) handleJobDone(msg jobDoneMsg) (tea.Model, tea.Cmd) {
+	var err error
+	if e, ok := msg.msg.(errMsg); ok {
+		err = e.err
+	}
+	job := m.jobs.Finish(msg.id, err)
+	switch job.Status {
+	case jobs.Canceled:
+		return m, nil
+	case jobs.Failed:
+		m.addMessage(roleError, fmt.Sprintf("Job #%d (%s %s) failed: %v", job.ID, job.Kind, job.Title, job.Err))
+		return m, nil
+	}
+
+	// A review would take over the screen; it waits while the user is busy
+	// with something else.
+	if review, ok := msg.msg.(aiModifiedContentMsg); ok && !m.canOpenReview() {
+		m.pendingReviews[job.ID] = review
+		m.addMessage(roleInfo, fmt.Sprintf("✏ Changes to '%s' are ready. Open them from 'jobs' (job #%d).", filepath.Base(review.path), job.ID))
+		return m, nil
+	}
+	return m.update(msg.msg)
+}
+
+func (m *model) canOpenReview() bool {
+	idle := m.mode == modeChat || m.mode == modeExplorer
+	return idle && m.diffReview == nil && strings.TrimSpace(m.textInput.Value()) == ""
+}
+
+// openPendingReview shows the changes of a finished modify job.
+func (m *model) openPendingReview(id int) tea.Cmd {
+	review, ok := m.pendingReviews[id]
+	if !ok {
+		return nil
+	}
+	delete(m.pendingReviews, id)
+	return m.openDiffReview(review.path, review.prompt, review.original, review.content)
+}
+
+func jobsCommand(m *model, args Args) tea.Cmd {
+	switch args.String("action") {
+	case "":
+		m.jobsPanel = jobsPanel{returnMode: m.mode}
+		m.mode = modeJobs
+		return nil
+	case "cancel":
+		id := args.Int("id")
+		if id == 0 {
+			m.addMessage(roleError, "jobs cancel: missing job number\nUsage: "+m.commands["jobs"].Usage())
+			return nil
+		}
+		m.cancelJob(id)
+		return nil
+	}
+	m.addMessage(roleError, "jobs: unknown action '"+args.String("action")+"'\nUsage: "+m.commands["jobs"].Usage())
+	return nil
+}
+
+// cancelJob stops a job; whatever it still returns is dropped.
+func (m *model) cancelJob(id int) {
+	if err := m.jobs.Cancel(id); err != nil {
+		m.addMessage(roleError, err.Error())
+		return
+	}
+	job, _ := m.jobs.Get(id)
+	m.addMessage(roleInfo, fmt.Sprintf("⏹ Canceled job #%d (%s %s).", job.ID, job.Kind, job.Title))
+}
+
+// listedJobs returns the jobs newest first, as the panel shows them.
+func (m model) listedJobs() []jobs.Job {
+	list := m.jobs.List()
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list
+}
+
+func (m *model) updateJobs(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	list := m.listedJobs()
+	p := &m.jobsPanel
+	if p.cursor >= len(list) {
+		p.cursor = len(list) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	switch m.keys.action(scopeJobs, msg.String()) {
+	case "back":
+		m.mode = p.returnMode
+		return m, nil
+	case "up":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down":
+		if p.cursor < len(list)-1 {
+			p.cursor++
+		}
+	case "cancel":
+		if len(list) > 0 {
+			m.cancelJob(list[p.cursor].ID)
+		}
+	case "review":
+		if len(list) > 0 {
+			if _, ok := m.pendingReviews[list[p.cursor].ID]; ok {
+				m.mode = p.returnMode
+				return m, m.openPendingReview(list[p.cursor].ID)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m model) jobsView() string {
+	list := m.listedJobs()
+	var sb strings.Builder
+	if len(list) == 0 {
+		sb.WriteString(m.styles.infoMsg.Render("No jobs yet. AI requests, analyses and file writes show up here.") + "\n")
+	}
+
+	visible := m.height - 8
+	if visible < 3 {
+		visible = 3
+	}
+	start := 0
+	if m.jobsPanel.cursor >= visible {
+		start = m.jobsPanel.cursor - visible + 1
+	}
+	for i := start; i < len(list) && i < start+visible; i++ {
+		job := list[i]
+		status := job.Status.String()
+		style := m.styles.diffContext
+		switch job.Status {
+		case jobs.Running:
+			status = "⟳ " + status
+			style = m.styles.statusSpinner
+		case jobs.Done:
+			style = m.styles.diffAdd
+		case jobs.Failed:
+			style = m.styles.diffDel
+		}
+		detail := job.Progress
+		if job.Err != nil {
+			detail = job.Err.Error()
+		}
+		if _, ok := m.pendingReviews[job.ID]; ok {
+			detail = "changes ready, enter to review"
+		}
+		line := fmt.Sprintf("#%-4d %s %-8s %-30s %6s  %s",
+			job.ID, style.Render(fmt.Sprintf("%-10s", status)), job.Kind, truncate(job.Title, 30), job.Elapsed().Round(time.Second), detail)
+		if i == m.jobsPanel.cursor {
+			sb.WriteString(m.styles.diffCursor.Render("▶ ") + line + "\n")
+		} else {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+
+	return m.styles.app.Render(lipgloss.JoinVertical(lipgloss.Left,
+		m.styles.header.Render(fmt.Sprintf("Jobs (%d running)", m.jobs.Running())),
+		sb.String(),
+		m.styles.statusBar.Width(m.width-4).Render(m.styles.statusText.Render(m.keys.footer(scopeJobs, "up/down", "select", "review", "review changes", "cancel", "cancel", "back", "back"))),
+	))
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,339 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anthonycursewl/anx-agent/internal/config"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// Key bindings are grouped in scopes: the explorer, every mode where the
+// composer takes input, and each full-screen view.
+const (
+	scopeExplorer  = "explorer"
+	scopeInput     = "input"
+	scopeDiff      = "diff"
+	scopeHistory   = "history"
+	scopeFinder    = "finder"
+	scopePalette   = "palette"
+	scopeJobs      = "jobs"
+	scopeBranches  = "branches"
+	scopeFindings  = "findings"
+	scopeSelection = "selection"
+	scopeConfirm   = "confirm"
+)
+
+// binding is a remappable key action that is not an explorer action.
+type binding struct {
+	name        string
+	keys        []string
+	description string
+}
+
+var inputBindings = []binding{
+	{name: "submit", keys: []string{"enter", "ctrl+s"}, description: "Send the message or confirm the input"},
+	{name: "cancel", keys: []string{"esc"}, description: "Cancel the input and show the explorer"},
+	{name: "newline", keys: []string{"alt+enter", "ctrl+j"}, description: "Start a new line"},
+	{name: "complete", keys: []string{"tab"}, description: "Complete commands and file paths"},
+	{name: "history-prev", keys: []string{"up"}, description: "Recall the previous input (on the first line)"},
+	{name: "history-next", keys: []string{"down"}, description: "Recall the next input (on the last line)"},
+	{name: "search-history", keys: []string{"ctrl+r"}, description: "Search previous inputs"},
+	{name: "editor", keys: []string{"ctrl+o"}, description: "Edit the draft in $VISUAL/$EDITOR"},
+	{name: "palette", keys: []string{"ctrl+k"}, description: "Open the command palette"},
+	{name: "finder", keys: []string{"ctrl+p"}, description: "Find a file anywhere in the workspace"},
+	{name: "select", keys: []string{"ctrl+y"}, description: "Select a message to copy it or use its code blocks"},
+	{name: "focus", keys: []string{"shift+tab"}, description: "Focus the explorer (split layout; tab on an empty prompt too)"},
+	{name: "page-up", keys: []string{"pgup"}, description: "Scroll the chat up (split layout)"},
+	{name: "page-down", keys: []string{"pgdown"}, description: "Scroll the chat down (split layout)"},
+}
+
+var explorerBindings = []binding{
+	{name: "palette", keys: []string{"ctrl+k"}, description: "Open the command palette"},
+	{name: "scroll-down", keys: []string{"J"}, description: "Scroll the preview down"},
+	{name: "scroll-up", keys: []string{"K"}, description: "Scroll the preview up"},
+	{name: "page-down", keys: []string{"ctrl+d"}, description: "Scroll the preview down half a page"},
+	{name: "page-up", keys: []string{"ctrl+u"}, description: "Scroll the preview up half a page"},
+	{name: "focus", keys: []string{"tab"}, description: "Focus the chat"},
+	{name: "shrink-pane", keys: []string{"<"}, description: "Narrow the explorer (split layout)"},
+	{name: "grow-pane", keys: []string{">"}, description: "Widen the explorer (split layout)"},
+}
+
+var (
+	backBinding = binding{name: "back", keys: []string{"esc", "q"}, description: "Go back"}
+	upBinding   = binding{name: "up", keys: []string{"up", "k"}, description: "Select the previous entry"}
+	downBinding = binding{name: "down", keys: []string{"down", "j"}, description: "Select the next entry"}
+)
+
+// views lists the bindings of the full-screen views, in the order 'help
+// keys' shows them.
+var views = []struct {
+	scope    string
+	title    string
+	bindings []binding
+}{
+	{scopeDiff, "In the diff review:", []binding{
+		{name: "up", keys: []string{"up", "k"}, description: "Select the previous hunk"},
+		{name: "down", keys: []string{"down", "j"}, description: "Select the next hunk"},
+		{name: "toggle", keys: []string{"space"}, description: "Accept or reject the selected hunk"},
+		{name: "accept-all", keys: []string{"a"}, description: "Accept every hunk and apply them"},
+		{name: "apply", keys: []string{"enter"}, description: "Apply the accepted hunks"},
+		{name: "reject", keys: []string{"r", "esc", "q"}, description: "Reject every change"},
+	}},
+	{scopeHistory, "In the change history:", []binding{
+		backBinding,
+		{name: "revert", keys: []string{"enter", "r"}, description: "Revert the selected change"},
+	}},
+	{scopeFinder, "In the file finder:", []binding{
+		{name: "back", keys: []string{"esc"}, description: "Go back"},
+		{name: "up", keys: []string{"up", "ctrl+p"}, description: "Select the previous file"},
+		{name: "down", keys: []string{"down", "ctrl+n"}, description: "Select the next file"},
+		{name: "open", keys: []string{"enter"}, description: "Modify the file with AI"},
+		{name: "show", keys: []string{"ctrl+o"}, description: "Show the file in the explorer"},
+		{name: "context", keys: []string{"ctrl+x"}, description: "Add or remove the file from the context"},
+	}},
+	{scopePalette, "In the command palette:", []binding{
+		{name: "back", keys: []string{"esc", "ctrl+k"}, description: "Go back"},
+		{name: "up", keys: []string{"up", "ctrl+p"}, description: "Select the previous entry"},
+		{name: "down", keys: []string{"down", "ctrl+n"}, description: "Select the next entry"},
+		{name: "run", keys: []string{"enter"}, description: "Run the selected entry"},
+	}},
+	{scopeJobs, "In the jobs view:", []binding{
+		backBinding, upBinding, downBinding,
+		{name: "cancel", keys: []string{"c", "x"}, description: "Cancel the selected job"},
+		{name: "review", keys: []string{"enter"}, description: "Review the changes proposed by the job"},
+	}},
+	{scopeBranches, "In the branches view:", []binding{
+		backBinding, upBinding, downBinding,
+		{name: "switch", keys: []string{"enter"}, description: "Switch to the selected branch"},
+		{name: "delete", keys: []string{"d"}, description: "Delete the selected branch"},
+	}},
+	{scopeFindings, "In the review findings:", []binding{
+		backBinding, upBinding, downBinding,
+		{name: "open", keys: []string{"enter"}, description: "Open the file at the finding in $EDITOR"},
+		{name: "explorer", keys: []string{"o"}, description: "Show the file in the explorer"},
+		{name: "copy-fix", keys: []string{"y"}, description: "Copy the suggested fix"},
+	}},
+	{scopeSelection, "In the message selection (1-9 pick a code block, 0 none):", []binding{
+		{name: "back", keys: []string{"esc", "q"}, description: "Go back"},
+		{name: "up", keys: []string{"up", "k"}, description: "Select the previous message"},
+		{name: "down", keys: []string{"down", "j"}, description: "Select the next message"},
+		{name: "first", keys: []string{"home", "g"}, description: "Select the first message"},
+		{name: "last", keys: []string{"end", "G"}, description: "Select the last message"},
+		{name: "next-block", keys: []string{"tab"}, description: "Select the next code block"},
+		{name: "copy", keys: []string{"y", "enter"}, description: "Copy the message or code block"},
+		{name: "save", keys: []string{"s"}, description: "Save the code block to a file"},
+		{name: "apply", keys: []string{"a"}, description: "Apply the code block to the open file"},
+		{name: "edit", keys: []string{"e"}, description: "Edit your message and resend it"},
+		{name: "regenerate", keys: []string{"r"}, description: "Ask again for the answer"},
+		{name: "fork", keys: []string{"b"}, description: "Start a branch after the message"},
+	}},
+	{scopeConfirm, "When confirming a delete:", []binding{
+		{name: "yes", keys: []string{"y", "Y"}, description: "Delete"},
+		{name: "no", keys: []string{"n", "N", "esc", "q"}, description: "Keep the file"},
+	}},
+}
+
+// keyMap holds the keys of every action, by scope and action name.
+type keyMap map[string]map[string][]string
+
+func defaultKeyMap() keyMap {
+	k := keyMap{scopeExplorer: {}, scopeInput: {}}
+	for _, a := range explorerActions {
+		k[scopeExplorer][a.name] = a.keys
+	}
+	for _, b := range explorerBindings {
+		k[scopeExplorer][b.name] = b.keys
+	}
+	for _, b := range inputBindings {
+		k[scopeInput][b.name] = b.keys
+	}
+	for _, v := range views {
+		k[v.scope] = map[string][]string{}
+		for _, b := range v.bindings {
+			k[v.scope][b.name] = b.keys
+		}
+	}
+	return k
+}
+
+// newKeyMap applies the bindings of the config file to the defaults. Every
+// problem is reported at once so that the config can be fixed in one go.
+func newKeyMap(overrides map[string]map[string]config.KeyList) (keyMap, error) {
+	k := defaultKeyMap()
+	var errs []error
+	for _, scope := range sortedKeys(overrides) {
+		actions, ok := k[scope]
+		if !ok {
+			errs = append(errs, fmt.Errorf("keys: unknown scope '%s'; use one of %s", scope, strings.Join(sortedKeys(k), ", ")))
+			continue
+		}
+		for _, name := range sortedKeys(overrides[scope]) {
+			if _, ok := actions[name]; !ok {
+				errs = append(errs, fmt.Errorf("keys.%s: unknown action '%s'", scope, name))
+				continue
+			}
+			keys := overrides[scope][name]
+			if len(keys) == 0 {
+				errs = append(errs, fmt.Errorf("keys.%s.%s: no keys given", scope, name))
+				continue
+			}
+			actions[name] = keys
+		}
+	}
+	errs = append(errs, k.validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid key bindings:\n%w", errors.Join(errs...))
+	}
+	return k, nil
+}
+
+// reservedKeys are keys that cannot be bound in a scope, with the reason.
+func reservedKeys(scope string) map[string]string {
+	reserved := map[string]string{"ctrl+c": "quits the application"}
+	switch scope {
+	case scopeExplorer, scopeHistory:
+		lk := list.DefaultKeyMap()
+		for _, b := range []key.Binding{lk.CursorUp, lk.CursorDown} {
+			for _, k := range b.Keys() {
+				reserved[k] = "moves the cursor in the list"
+			}
+		}
+	case scopeSelection:
+		for d := '0'; d <= '9'; d++ {
+			reserved[string(d)] = "picks a code block"
+		}
+	}
+	return reserved
+}
+
+// typingScopes are the scopes where printable keys go to a text input.
+var typingScopes = map[string]bool{scopeInput: true, scopeFinder: true, scopePalette: true}
+
+func (k keyMap) validate() []error {
+	var errs []error
+	for _, scope := range sortedKeys(k) {
+		reserved := reservedKeys(scope)
+		owner := map[string]string{}
+		for _, name := range sortedKeys(k[scope]) {
+			for _, key := range k[scope][name] {
+				switch {
+				case strings.TrimSpace(key) != key || key == "":
+					errs = append(errs, fmt.Errorf("keys.%s.%s: invalid key '%s'", scope, name, key))
+				case reserved[key] != "":
+					errs = append(errs, fmt.Errorf("keys.%s.%s: '%s' %s and cannot be rebound", scope, name, key, reserved[key]))
+				case typingScopes[scope] && (utf8.RuneCountInString(key) == 1 || key == "space" || key == "backspace"):
+					errs = append(errs, fmt.Errorf("keys.%s.%s: '%s' is needed for typing", scope, name, key))
+				case owner[key] != "":
+					errs = append(errs, fmt.Errorf("keys.%s: '%s' is bound to both '%s' and '%s'", scope, key, owner[key], name))
+				default:
+					owner[key] = name
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// action returns the name of the action bound to key in scope, or "".
+func (k keyMap) action(scope, key string) string {
+	if key == " " {
+		key = "space"
+	}
+	for name, keys := range k[scope] {
+		for _, bound := range keys {
+			if bound == key {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func (k keyMap) keys(scope, name string) []string { return k[scope][name] }
+
+// hint returns the main key of an action, for the status bar.
+func (k keyMap) hint(scope, name string) string {
+	keys := k[scope][name]
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0]
+}
+
+func (k keyMap) describe(scope, name string) string {
+	return strings.Join(k[scope][name], ", ")
+}
+
+// footer renders the help line of a view from pairs of action names and
+// labels, such as "up/down", "select": each action shows its main key.
+func (k keyMap) footer(scope string, pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		var keys []string
+		for _, name := range strings.Split(pairs[i], "/") {
+			keys = append(keys, keyLabel(k.hint(scope, name)))
+		}
+		parts = append(parts, strings.Join(keys, "/")+" "+pairs[i+1])
+	}
+	return strings.Join(parts, " • ")
+}
+
+func keyLabel(key string) string {
+	switch key {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	}
+	return key
+}
+
+// viewsHelp lists the keys of every full-screen view.
+func viewsHelp(k keyMap) string {
+	var sections []string
+	for _, v := range views {
+		sections = append(sections, bindingsHelp(v.title, k, v.scope, v.bindings))
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+func bindingsHelp(title string, k keyMap, scope string, bindings []binding) string {
+	var sb strings.Builder
+	sb.WriteString(title + "\n")
+	for _, b := range bindings {
+		fmt.Fprintf(&sb, "  %-18s %-15s %s\n", k.describe(scope, b.name), b.name, b.description)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// applyKeyMap makes k drive the key handling, the list help and the
+// composer's new line key.
+func (m *model) applyKeyMap(k keyMap) {
+	m.keys = k
+	m.list.AdditionalShortHelpKeys = func() []key.Binding {
+		return actionBindings(k)
+	}
+	m.historyList.AdditionalShortHelpKeys = func() []key.Binding {
+		back, revert := k.keys(scopeHistory, "back"), k.keys(scopeHistory, "revert")
+		return []key.Binding{
+			key.NewBinding(key.WithKeys(back...), key.WithHelp(strings.Join(back, "/"), "back")),
+			key.NewBinding(key.WithKeys(revert...), key.WithHelp(strings.Join(revert, "/"), "revert change")),
+		}
+	}
+	m.textInput.KeyMap.InsertNewline.SetKeys(k.keys(scopeInput, "newline")...)
+	m.textInput.KeyMap.InsertNewline.SetHelp(k.hint(scopeInput, "newline"), "new line")
+}
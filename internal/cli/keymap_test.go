@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/anthonycursewl/anx-agent/internal/config"
+)
+
+func TestDefaultKeyMapIsValid(t *testing.T) {
+	if errs := defaultKeyMap().validate(); len(errs) > 0 {
+		t.Fatalf("the default bindings are invalid: %v", errs)
+	}
+}
+
+func TestOverrideBinding(t *testing.T) {
+	k, err := newKeyMap(map[string]map[string]config.KeyList{
+		scopeJobs:  {"cancel": {"z"}},
+		scopeInput: {"submit": {"ctrl+s"}, "newline": {"enter"}},
+		scopeDiff:  {"toggle": {"t", "space"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := k.keys(scopeJobs, "cancel"); !reflect.DeepEqual(got, []string{"z"}) {
+		t.Errorf("cancel = %q, want [z]", got)
+	}
+	if got := k.action(scopeJobs, "z"); got != "cancel" {
+		t.Errorf("z runs %q, want cancel", got)
+	}
+	if got := k.action(scopeJobs, "c"); got != "" {
+		t.Errorf("the replaced key c still runs %q", got)
+	}
+	// Keys freed by an override can be given to another action.
+	if got := k.action(scopeInput, "enter"); got != "newline" {
+		t.Errorf("enter runs %q, want newline", got)
+	}
+	if got := k.action(scopeDiff, " "); got != "toggle" {
+		t.Errorf("the space bar runs %q, want toggle", got)
+	}
+	// Scopes that are not overridden keep their defaults.
+	if got := k.keys(scopeHistory, "revert"); !reflect.DeepEqual(got, []string{"enter", "r"}) {
+		t.Errorf("revert = %q, want the defaults", got)
+	}
+}
+
+func TestRejectBindings(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]map[string]config.KeyList
+		want      string
+	}{
+		{
+			"conflict within a scope",
+			map[string]map[string]config.KeyList{scopeJobs: {"cancel": {"enter"}}},
+			"keys.jobs: 'enter' is bound to both 'cancel' and 'review'",
+		},
+		{
+			"unknown action",
+			map[string]map[string]config.KeyList{scopeJobs: {"explode": {"e"}}},
+			"keys.jobs: unknown action 'explode'",
+		},
+		{
+			"unknown scope",
+			map[string]map[string]config.KeyList{"jbos": {"cancel": {"z"}}},
+			"keys: unknown scope 'jbos'",
+		},
+		{
+			"no keys",
+			map[string]map[string]config.KeyList{scopeJobs: {"cancel": {}}},
+			"keys.jobs.cancel: no keys given",
+		},
+		{
+			"reserved key",
+			map[string]map[string]config.KeyList{scopeSelection: {"copy": {"1"}}},
+			"keys.selection.copy: '1' picks a code block and cannot be rebound",
+		},
+		{
+			"key needed for typing",
+			map[string]map[string]config.KeyList{scopeInput: {"palette": {"p"}}},
+			"keys.input.palette: 'p' is needed for typing",
+		},
+		{
+			"invalid key",
+			map[string]map[string]config.KeyList{scopeJobs: {"cancel": {" z"}}},
+			"keys.jobs.cancel: invalid key ' z'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := newKeyMap(tt.overrides)
+			if err == nil {
+				t.Fatalf("accepted the bindings: %v", k)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRejectReportsEveryProblem(t *testing.T) {
+	_, err := newKeyMap(map[string]map[string]config.KeyList{
+		scopeJobs:     {"explode": {"e"}},
+		scopeBranches: {"delete": {"enter"}},
+	})
+	if err == nil {
+		t.Fatal("accepted the bindings")
+	}
+	for _, want := range []string{"unknown action 'explode'", "'enter' is bound to both"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
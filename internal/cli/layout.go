@@ -0,0 +1,310 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Layouts: the explorer and the chat take the whole screen in turn, or sit
+// side by side.
+const (
+	layoutSingle = "single"
+	layoutSplit  = "split"
+)
+
+const (
+	defaultExplorerShare = 35
+	minExplorerShare     = 15
+	maxExplorerShare     = 70
+	explorerShareStep    = 5
+	// explorerFooterLines is the room kept under the list in the split
+	// layout for the context and jobs indicators.
+	explorerFooterLines = 4
+	wheelLines          = 3
+)
+
+// splitLayout is the state of the side-by-side layout. share is the
+// percentage of the width given to the explorer, chatScroll how many lines
+// the chat is scrolled up from its end.
+type splitLayout struct {
+	enabled    bool
+	share      int
+	dragging   bool
+	chatScroll int
+}
+
+func parseLayout(name string) (bool, error) {
+	switch strings.ToLower(name) {
+	case "", layoutSingle:
+		return false, nil
+	case layoutSplit:
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown layout '%s'; use single or split", name)
+}
+
+func layoutCommand(m *model, args Args) tea.Cmd {
+	split := !m.layout.enabled
+	if name := args.String("layout"); name != "" {
+		var err error
+		if split, err = parseLayout(name); err != nil {
+			m.addMessage(roleError, "layout: "+err.Error())
+			return nil
+		}
+	}
+	cmd := m.setLayout(split)
+	if split {
+		m.addMessage(roleInfo, fmt.Sprintf("Split layout: '%s' switches between the explorer and the chat, '%s'/'%s' resize the explorer. The mouse can click, scroll and drag the divider.",
+			m.keys.hint(scopeExplorer, "focus"), m.keys.hint(scopeExplorer, "shrink-pane"), m.keys.hint(scopeExplorer, "grow-pane")))
+	} else {
+		m.addMessage(roleInfo, "Single layout: 'ls' shows the explorer.")
+	}
+	return cmd
+}
+
+// setLayout switches layouts. The mouse is only captured in the split
+// layout, so that text can still be selected in the terminal otherwise.
+func (m *model) setLayout(split bool) tea.Cmd {
+	m.layout.enabled = split
+	m.layout.dragging = false
+	m.layout.chatScroll = 0
+	m.resize()
+	if split {
+		return tea.EnableMouseCellMotion
+	}
+	m.previewPath = ""
+	return tea.Batch(tea.DisableMouse, m.syncPreview())
+}
+
+// resize fits every component to the window and the layout.
+func (m *model) resize() {
+	if m.layout.enabled {
+		explorerWidth, chatWidth := m.paneWidths()
+		m.list.SetShowHelp(false)
+		// The list truncates its title without counting the title's left
+		// padding, which would wrap it in a narrow pane.
+		m.list.SetSize(explorerWidth-2, m.height-2-explorerFooterLines)
+		m.textInput.SetWidth(chatWidth - 2)
+	} else {
+		m.list.SetShowHelp(true)
+		listWidth, _ := m.explorerWidths()
+		m.list.SetSize(listWidth, m.height-6)
+		m.textInput.SetWidth(m.width - 6)
+	}
+	m.resizePreview()
+	m.historyList.SetSize(m.width-4, m.height-6)
+	if m.diffReview != nil {
+		m.diffReview.viewport.Width = m.width - 4
+		m.diffReview.viewport.Height = m.height - 8
+	}
+}
+
+// paneWidths returns the content widths of the explorer and the chat; the
+// divider and the padding of the chat take the remaining two columns.
+func (m *model) paneWidths() (int, int) {
+	total := m.width - 4
+	explorer := total * m.layout.share / 100
+	return explorer, total - explorer - 2
+}
+
+// dividerX is the screen column of the divider between the panes.
+func (m *model) dividerX() int {
+	explorer, _ := m.paneWidths()
+	return 2 + explorer
+}
+
+func (m *model) resizePanes(share int) {
+	if share < minExplorerShare {
+		share = minExplorerShare
+	}
+	if share > maxExplorerShare {
+		share = maxExplorerShare
+	}
+	m.layout.share = share
+	m.resize()
+}
+
+// splitVisible reports whether the current mode is shown in the split
+// layout; reviews, pickers and the jobs panel still take the whole screen.
+func (m *model) splitVisible() bool {
+	if !m.layout.enabled {
+		return false
+	}
+	switch m.mode {
+	case modeDiffReview, modeHistory, modeFinder, modePalette, modeJobs, modeBranches, modeFindings:
+		return false
+	}
+	return true
+}
+
+func (m *model) explorerFocused() bool {
+	return m.mode == modeExplorer || m.mode == modeConfirmDelete
+}
+
+func (m *model) focusExplorer() {
+	m.mode = modeExplorer
+	m.completions = nil
+}
+
+func (m *model) focusChat() tea.Cmd {
+	m.mode = modeChat
+	return m.textInput.Focus()
+}
+
+// layoutAction runs the explorer actions that move the focus or resize the
+// panes, reporting whether name was one of them.
+func (m *model) layoutAction(name string) (tea.Cmd, bool) {
+	switch name {
+	case "focus":
+		return m.focusChat(), true
+	case "shrink-pane":
+		if m.layout.enabled {
+			m.resizePanes(m.layout.share - explorerShareStep)
+		}
+		return nil, true
+	case "grow-pane":
+		if m.layout.enabled {
+			m.resizePanes(m.layout.share + explorerShareStep)
+		}
+		return nil, true
+	}
+	return nil, false
+}
+
+func (m *model) scrollChat(lines int) {
+	_, width := m.paneWidths()
+	header, footer := m.chatChrome(width)
+	_, max := m.chatBody(width, m.height-2-lipgloss.Height(header)-lipgloss.Height(footer))
+	m.layout.chatScroll += lines
+	if m.layout.chatScroll > max {
+		m.layout.chatScroll = max
+	}
+	if m.layout.chatScroll < 0 {
+		m.layout.chatScroll = 0
+	}
+}
+
+func (m *model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if !m.splitVisible() {
+		return m, nil
+	}
+	divider := m.dividerX()
+	overExplorer := msg.X < divider
+
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp || msg.Button == tea.MouseButtonWheelDown:
+		up := msg.Button == tea.MouseButtonWheelUp
+		if !overExplorer {
+			if up {
+				m.scrollChat(wheelLines)
+			} else {
+				m.scrollChat(-wheelLines)
+			}
+			return m, nil
+		}
+		if up {
+			m.list.CursorUp()
+		} else {
+			m.list.CursorDown()
+		}
+		return m, nil
+
+	case msg.Action == tea.MouseActionMotion && m.layout.dragging:
+		if total := m.width - 4; total > 0 {
+			m.resizePanes((msg.X - 2) * 100 / total)
+		}
+		return m, nil
+
+	case msg.Action == tea.MouseActionRelease:
+		m.layout.dragging = false
+		return m, nil
+
+	case msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft:
+		if msg.X == divider || msg.X == divider+1 {
+			m.layout.dragging = true
+			return m, nil
+		}
+		// Inputs that belong to a flow, like a file name being typed, are
+		// not interrupted by a click.
+		if m.mode != modeChat && m.mode != modeExplorer {
+			return m, nil
+		}
+		if !overExplorer {
+			return m, m.focusChat()
+		}
+		m.focusExplorer()
+		return m, m.clickEntry(msg.Y)
+	}
+	return m, nil
+}
+
+// clickEntry selects the explorer entry at screen row y; clicking the
+// selected entry opens it.
+func (m *model) clickEntry(y int) tea.Cmd {
+	delegate := listDelegate(m.styles)
+	// The list starts under the app margin and the two lines of its title.
+	row := y - 1 - 2
+	step := delegate.Height() + delegate.Spacing()
+	if row < 0 || row%step >= delegate.Height() {
+		return nil
+	}
+	index := m.list.Paginator.Page*m.list.Paginator.PerPage + row/step
+	if index >= len(m.list.VisibleItems()) {
+		return nil
+	}
+	if index == m.list.Index() {
+		return m.openSelected()
+	}
+	m.list.Select(index)
+	return nil
+}
+
+// chatBody renders the transcript into at most height lines and returns it
+// with how far it can be scrolled up.
+func (m model) chatBody(width, height int) (string, int) {
+	lines := strings.Split(m.renderMessages(width), "\n")
+	max := len(lines) - height
+	if max < 0 {
+		max = 0
+	}
+	scroll := m.layout.chatScroll
+	if scroll > max || m.mode == modeSelect || m.mode == modeSaveBlockInput || m.mode == modeEditMessage {
+		scroll = 0
+	}
+	end := len(lines) - scroll
+	start := end - height
+	if start < 0 {
+		start = 0
+	}
+	return lipgloss.NewStyle().Height(height).Render(strings.Join(lines[start:end], "\n")), max
+}
+
+// chatChrome renders what surrounds the transcript in the chat pane. The
+// title is dimmed while the explorer has the focus.
+func (m model) chatChrome(width int) (string, string) {
+	header := m.styles.header.Render("ANX Agent")
+	if m.explorerFocused() {
+		header = m.styles.header.Foreground(m.styles.statusText.GetForeground()).Render("ANX Agent")
+	}
+	return header, m.chatFooter(width)
+}
+
+func (m model) splitView() string {
+	explorerWidth, chatWidth := m.paneWidths()
+	height := m.height - 2
+
+	explorer := lipgloss.NewStyle().
+		Width(explorerWidth).Height(height).MaxHeight(height).
+		Border(lipgloss.NormalBorder(), false, true, false, false).BorderForeground(m.styles.border).
+		Render(m.explorerPane(explorerWidth, m.explorerFocused()))
+
+	header, footer := m.chatChrome(chatWidth)
+	body, _ := m.chatBody(chatWidth, height-lipgloss.Height(header)-lipgloss.Height(footer))
+	chat := lipgloss.NewStyle().PaddingLeft(1).Width(chatWidth + 1).MaxHeight(height).
+		Render(lipgloss.JoinVertical(lipgloss.Left, header, body, footer))
+
+	return m.styles.app.Render(lipgloss.JoinHorizontal(lipgloss.Top, explorer, chat))
+}
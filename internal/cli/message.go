@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Roles of the chat messages. They match the kinds saved in sessions.
+const (
+	roleUser  = "user"
+	roleAI    = "ai"
+	roleError = "error"
+	roleInfo  = "info"
+	roleCode  = "code"
+)
+
+// message is one entry of the chat transcript. model, usage and files are
+// only set on AI replies: who answered, what it cost and which context files
+// were sent along. replyTo links a chat reply to the input it answers.
+type message struct {
+	id      int
+	replyTo int
+	role    string
+	content string
+	time    time.Time
+	model   string
+	usage   ai.Usage
+	files   []string
+}
+
+func (m *model) newMessage(role, content string) message {
+	m.lastMessageID++
+	return message{id: m.lastMessageID, role: role, content: content, time: time.Now()}
+}
+
+func (m *model) addMessage(role, content string) *message {
+	m.messages = append(m.messages, m.newMessage(role, content))
+	return &m.messages[len(m.messages)-1]
+}
+
+// newReply builds an AI reply along with the usage and files of its request.
+func (m *model) newReply(reply ai.Reply, files []string) message {
+	msg := m.newMessage(roleAI, reply.Text)
+	msg.model = ai.Model
+	msg.usage = reply.Usage
+	msg.files = files
+	return msg
+}
+
+func (m *model) addReply(reply ai.Reply, files []string) *message {
+	m.messages = append(m.messages, m.newReply(reply, files))
+	return &m.messages[len(m.messages)-1]
+}
+
+// meta describes when a message was written and, for AI replies, by which
+// model, at what token cost and with which files.
+func (msg message) meta() string {
+	if msg.role != roleUser && msg.role != roleAI {
+		return ""
+	}
+	parts := []string{msg.time.Format("15:04")}
+	if msg.model != "" {
+		parts = append(parts, msg.model)
+	}
+	if msg.usage.Total > 0 {
+		parts = append(parts, fmt.Sprintf("%d tokens (%d in, %d out)", msg.usage.Total, msg.usage.Prompt, msg.usage.Output))
+	}
+	if len(msg.files) > 0 {
+		names := make([]string, len(msg.files))
+		for i, f := range msg.files {
+			names[i] = filepath.Base(f)
+		}
+		parts = append(parts, "files: "+strings.Join(names, ", "))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// renderMessage renders msg wrapped to width, margins included.
+func (m model) renderMessage(msg message, selected bool, width int) string {
+	if selected {
+		// Room for the selection marker.
+		width -= 2
+	}
+	wrap := func(style lipgloss.Style, text string) string {
+		if w := width - style.GetHorizontalFrameSize(); w > 0 {
+			style = style.Width(w)
+		}
+		return style.Render(text)
+	}
+	var out string
+	switch msg.role {
+	case roleUser:
+		out = wrap(m.styles.userMsg, "You: "+msg.content)
+	case roleAI:
+		out = wrap(m.styles.aiMsg, "🤖: "+msg.content)
+	case roleError:
+		out = wrap(m.styles.errorMsg, "❌ Error: "+msg.content)
+	case roleInfo:
+		out = wrap(m.styles.infoMsg, msg.content)
+	case roleCode:
+		out = wrap(lipgloss.NewStyle().MarginLeft(4), msg.content)
+	}
+	if meta := msg.meta(); meta != "" {
+		out = lipgloss.JoinVertical(lipgloss.Left, out, wrap(m.styles.statusText.MarginLeft(2), meta))
+	}
+	if selected {
+		out = m.styles.selected.Render(out)
+	}
+	return out
+}
+
+// codeBlock is a fenced code block found in a message.
+type codeBlock struct {
+	lang string
+	code string
+}
+
+var fenceOpen = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`\\s]*)")
+
+// codeBlocks extracts the fenced code blocks of a Markdown text. A block left
+// open runs to the end of the text, as happens with truncated replies.
+func codeBlocks(text string) []codeBlock {
+	var blocks []codeBlock
+	var current *codeBlock
+	var fence string
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if current == nil {
+			if match := fenceOpen.FindStringSubmatch(line); match != nil {
+				current = &codeBlock{lang: match[2]}
+				fence = match[1]
+				lines = nil
+			}
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			current.code = strings.Join(lines, "\n")
+			blocks = append(blocks, *current)
+			current = nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if current != nil {
+		current.code = strings.Join(lines, "\n")
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+func (b codeBlock) describe() string {
+	lang := b.lang
+	if lang == "" {
+		lang = "text"
+	}
+	lines := strings.Count(b.code, "\n") + 1
+	if lines == 1 {
+		return lang + ", 1 line"
+	}
+	return fmt.Sprintf("%s, %d lines", lang, lines)
+}
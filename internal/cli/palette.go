@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+)
+
+type paletteEntry struct {
+	label string
+	where string
+	run   func(m *model) tea.Cmd
+}
+
+// commandPalette is opened with Ctrl+K and fuzzy-searches every command and
+// explorer action by name and description.
+type commandPalette struct {
+	input      textinput.Model
+	entries    []paletteEntry
+	matches    fuzzy.Matches
+	cursor     int
+	returnMode int
+}
+
+func (p *commandPalette) String(i int) string { return p.entries[i].label }
+
+func (p *commandPalette) Len() int { return len(p.entries) }
+
+func (m *model) paletteEntries() []paletteEntry {
+	var entries []paletteEntry
+	for _, name := range m.commandNames() {
+		command := m.commands[name]
+		entries = append(entries, paletteEntry{
+			label: fmt.Sprintf("%-12s %s", name, command.Description),
+			where: "command",
+			run: func(m *model) tea.Cmd {
+				m.mode = modeChat
+				m.addMessage(roleUser, command.Name)
+				return m.runCommand(command, command.Name)
+			},
+		})
+	}
+	for _, a := range explorerActions {
+		entries = append(entries, paletteEntry{
+			label: fmt.Sprintf("%-12s %s", a.name, a.description),
+			where: "explorer " + m.keys.hint(scopeExplorer, a.name),
+			run: func(m *model) tea.Cmd {
+				m.mode = modeExplorer
+				return a.run(m)
+			},
+		})
+	}
+	return entries
+}
+
+func (m *model) openPalette() tea.Cmd {
+	ti := textinput.New()
+	ti.Prompt = "> "
+	ti.Placeholder = "Type a command or action..."
+	ti.Focus()
+
+	m.palette = &commandPalette{input: ti, entries: m.paletteEntries(), returnMode: m.mode}
+	m.palette.filter()
+	m.mode = modePalette
+	return textinput.Blink
+}
+
+func (p *commandPalette) filter() {
+	query := strings.TrimSpace(p.input.Value())
+	if query == "" {
+		p.matches = make(fuzzy.Matches, len(p.entries))
+		for i, e := range p.entries {
+			p.matches[i] = fuzzy.Match{Str: e.label, Index: i}
+		}
+	} else {
+		p.matches = fuzzy.FindFrom(query, p)
+	}
+	if p.cursor >= len(p.matches) {
+		p.cursor = len(p.matches) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+}
+
+func (m *model) updatePalette(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.palette
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	switch m.keys.action(scopePalette, msg.String()) {
+	case "back":
+		m.mode = p.returnMode
+		m.palette = nil
+		return m, nil
+	case "up":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+		return m, nil
+	case "down":
+		if p.cursor < len(p.matches)-1 {
+			p.cursor++
+		}
+		return m, nil
+	case "run":
+		if p.cursor >= len(p.matches) {
+			return m, nil
+		}
+		entry := p.entries[p.matches[p.cursor].Index]
+		m.palette = nil
+		return m, entry.run(m)
+	}
+
+	var cmd tea.Cmd
+	previous := p.input.Value()
+	p.input, cmd = p.input.Update(msg)
+	if p.input.Value() != previous {
+		p.cursor = 0
+		p.filter()
+	}
+	return m, cmd
+}
+
+func (m model) paletteView() string {
+	p := m.palette
+	var sb strings.Builder
+
+	visible := m.height - 8
+	if visible < 3 {
+		visible = 3
+	}
+	start := 0
+	if p.cursor >= visible {
+		start = p.cursor - visible + 1
+	}
+	end := start + visible
+	if end > len(p.matches) {
+		end = len(p.matches)
+	}
+
+	for i := start; i < end; i++ {
+		match := p.matches[i]
+		matched := map[int]bool{}
+		for _, idx := range match.MatchedIndexes {
+			matched[idx] = true
+		}
+		var line strings.Builder
+		for idx, r := range match.Str {
+			if matched[idx] {
+				line.WriteString(m.styles.finderMatch.Render(string(r)))
+			} else {
+				line.WriteRune(r)
+			}
+		}
+		where := m.styles.diffContext.Render("  (" + p.entries[match.Index].where + ")")
+		if i == p.cursor {
+			sb.WriteString(m.styles.diffCursor.Render("▶ ") + line.String() + where + "\n")
+		} else {
+			sb.WriteString("  " + line.String() + where + "\n")
+		}
+	}
+
+	return m.styles.app.Render(lipgloss.JoinVertical(lipgloss.Left,
+		m.styles.header.Render("Command Palette"),
+		p.input.View(),
+		"",
+		sb.String(),
+		m.styles.statusBar.Width(m.width-4).Render(m.styles.statusText.Render(m.keys.footer(scopePalette, "run", "run", "up/down", "select", "back", "back"))),
+	))
+}
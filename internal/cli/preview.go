@@ -0,0 +1,265 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	chromastyles "github.com/alecthomas/chroma/v2/styles"
+	"github.com/anthonycursewl/anx-agent/internal/utils"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	maxPreviewBytes   = 256 * 1024
+	maxLineCountBytes = 16 * 1024 * 1024
+	minPreviewWidth   = 100
+	previewTabSpacing = "    "
+)
+
+type filePreview struct {
+	path      string
+	isDir     bool
+	entries   int
+	size      int64
+	modTime   time.Time
+	lines     int
+	moreLines bool
+	binary    bool
+	truncated bool
+	rendered  []string
+}
+
+type previewLoadedMsg filePreview
+
+// loadPreview reads at most maxPreviewBytes of path for display. Line counts
+// are taken from the first maxLineCountBytes of the file, which are streamed
+// rather than loaded, so that selecting a huge log stays cheap.
+func loadPreview(path, syntax string) tea.Cmd {
+	return func() tea.Msg {
+		p := filePreview{path: path}
+		info, err := os.Stat(path)
+		if err != nil {
+			p.rendered = []string{"Cannot preview: " + err.Error()}
+			return previewLoadedMsg(p)
+		}
+		p.size = info.Size()
+		p.modTime = info.ModTime()
+
+		if info.IsDir() {
+			p.isDir = true
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				p.rendered = []string{"Cannot read directory: " + err.Error()}
+				return previewLoadedMsg(p)
+			}
+			p.entries = len(entries)
+			for _, entry := range entries {
+				name := entry.Name()
+				if entry.IsDir() {
+					name += "/"
+				}
+				p.rendered = append(p.rendered, name)
+			}
+			return previewLoadedMsg(p)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			p.rendered = []string{"Cannot preview: " + err.Error()}
+			return previewLoadedMsg(p)
+		}
+		defer f.Close()
+
+		head, err := io.ReadAll(io.LimitReader(f, maxPreviewBytes))
+		if err != nil {
+			p.rendered = []string{"Cannot preview: " + err.Error()}
+			return previewLoadedMsg(p)
+		}
+		p.truncated = int64(len(head)) < p.size
+		p.lines = bytes.Count(head, []byte("\n"))
+		if p.truncated {
+			p.lines += countLines(io.LimitReader(f, maxLineCountBytes-maxPreviewBytes))
+			p.moreLines = p.size > maxLineCountBytes
+		} else if len(head) > 0 && head[len(head)-1] != '\n' {
+			p.lines++
+		}
+
+		if utils.IsBinary(head) {
+			p.binary = true
+			p.rendered = []string{"Binary file, no preview available."}
+			return previewLoadedMsg(p)
+		}
+		p.rendered = highlight(path, string(head), syntax)
+		return previewLoadedMsg(p)
+	}
+}
+
+func countLines(r io.Reader) int {
+	n := 0
+	buf := make([]byte, 64*1024)
+	reader := bufio.NewReader(r)
+	for {
+		c, err := reader.Read(buf)
+		n += bytes.Count(buf[:c], []byte("\n"))
+		if err != nil {
+			return n
+		}
+	}
+}
+
+// highlight renders source with the chroma style syntax, or splits it into
+// plain lines when syntax is empty.
+func highlight(path, source, syntax string) []string {
+	source = strings.ReplaceAll(source, "\t", previewTabSpacing)
+	plain := strings.Split(strings.TrimSuffix(source, "\n"), "\n")
+	if syntax == "" {
+		return plain
+	}
+
+	lexer := lexers.Match(filepath.Base(path))
+	if lexer == nil {
+		lexer = lexers.Analyse(source)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, source)
+	if err != nil {
+		return plain
+	}
+	formatter := formatters.Get("terminal256")
+	style := chromastyles.Get(syntax)
+
+	var rendered []string
+	for _, line := range chroma.SplitTokensIntoLines(iterator.Tokens()) {
+		var buf bytes.Buffer
+		if err := formatter.Format(&buf, style, chroma.Literator(line...)); err != nil {
+			return plain
+		}
+		rendered = append(rendered, strings.TrimRight(buf.String(), "\n"))
+	}
+	return rendered
+}
+
+func (p filePreview) header() string {
+	if p.isDir {
+		return fmt.Sprintf("%s/ • %d entries • modified %s", filepath.Base(p.path), p.entries, p.modTime.Format("2006-01-02 15:04"))
+	}
+	lines := fmt.Sprintf("%d lines", p.lines)
+	if p.moreLines {
+		lines = fmt.Sprintf("over %d lines", p.lines)
+	}
+	header := fmt.Sprintf("%s • %s • %s • modified %s", filepath.Base(p.path), utils.FormatSize(p.size), lines, p.modTime.Format("2006-01-02 15:04"))
+	if p.truncated {
+		header += fmt.Sprintf(" • showing first %s", utils.FormatSize(maxPreviewBytes))
+	}
+	return header
+}
+
+// showPreview reports whether there is room for the preview. The split
+// layout gives that room to the chat.
+func (m *model) showPreview() bool { return !m.layout.enabled && m.width >= minPreviewWidth }
+
+// explorerWidths splits the explorer between the list and the preview pane.
+func (m *model) explorerWidths() (int, int) {
+	total := m.width - 4
+	if !m.showPreview() {
+		return total, 0
+	}
+	listWidth := total * 2 / 5
+	return listWidth, total - listWidth - 1
+}
+
+func (m *model) resizePreview() {
+	_, previewWidth := m.explorerWidths()
+	m.preview.Width = previewWidth - 2
+	m.preview.Height = m.height - 9
+	m.renderPreview()
+}
+
+func (m *model) renderPreview() {
+	width := m.preview.Width
+	numberWidth := len(fmt.Sprint(len(m.previewFile.rendered)))
+	var sb strings.Builder
+	for i, line := range m.previewFile.rendered {
+		prefix := ""
+		if !m.previewFile.isDir && !m.previewFile.binary && m.previewFile.lines > 0 {
+			prefix = m.styles.diffContext.Render(fmt.Sprintf("%*d ", numberWidth, i+1))
+		}
+		sb.WriteString(ansi.Truncate(prefix+line, width, "…") + "\n")
+	}
+	m.preview.SetContent(sb.String())
+}
+
+// syncPreview loads the preview of the highlighted explorer entry when it
+// changes.
+func (m *model) syncPreview() tea.Cmd {
+	if !m.showPreview() {
+		return nil
+	}
+	selected, ok := m.list.SelectedItem().(item)
+	if !ok {
+		return nil
+	}
+	path := filepath.Join(m.currentPath, selected.path)
+	if selected.path == ".." {
+		path = filepath.Dir(m.currentPath)
+	}
+	if path == m.previewPath {
+		return nil
+	}
+	m.previewPath = path
+	return loadPreview(path, m.styles.syntax)
+}
+
+func (m *model) handlePreviewLoaded(p filePreview) {
+	if p.path != m.previewPath {
+		return
+	}
+	m.previewFile = p
+	m.preview = viewport.New(m.preview.Width, m.preview.Height)
+	m.renderPreview()
+}
+
+func (m *model) scrollPreview(action string) bool {
+	switch action {
+	case "scroll-down":
+		m.preview.LineDown(1)
+	case "scroll-up":
+		m.preview.LineUp(1)
+	case "page-down":
+		m.preview.HalfPageDown()
+	case "page-up":
+		m.preview.HalfPageUp()
+	default:
+		return false
+	}
+	return true
+}
+
+func (m model) previewView() string {
+	_, width := m.explorerWidths()
+	header := m.styles.header.MarginBottom(0).Render(ansi.Truncate(m.previewFile.header(), width-2, "…"))
+	if m.previewFile.path == "" {
+		header = m.styles.infoMsg.Render("No file selected")
+	}
+	return lipgloss.NewStyle().
+		Width(width - 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.styles.border).
+		Render(lipgloss.JoinVertical(lipgloss.Left, header, m.preview.View()))
+}
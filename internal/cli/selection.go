@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+)
+
+// messageSelection is the state of the mode where a chat message is picked
+// to copy it, use one of its code blocks or branch the conversation from it.
+// block is -1 while the whole message is selected.
+type messageSelection struct {
+	cursor     int
+	block      int
+	returnMode int
+	saving     codeBlock
+	editing    int
+	status     string
+}
+
+type copiedMsg struct {
+	what  string
+	osc52 bool
+}
+
+func selectCommand(m *model, args Args) tea.Cmd {
+	return m.startSelection()
+}
+
+func (m *model) startSelection() tea.Cmd {
+	if len(m.messages) == 0 {
+		return nil
+	}
+	m.selection = messageSelection{cursor: len(m.messages) - 1, block: -1, returnMode: m.mode}
+	// The command that opened the selection is not worth selecting.
+	for i := len(m.messages) - 1; i > 0 && m.messages[i].role == roleUser; i-- {
+		m.selection.cursor = i - 1
+	}
+	m.textInput.Blur()
+	m.mode = modeSelect
+	return nil
+}
+
+func (m *model) selectedMessage() message { return m.messages[m.selection.cursor] }
+
+func (m *model) selectedBlock() (codeBlock, bool) {
+	blocks := codeBlocks(m.selectedMessage().content)
+	if m.selection.block < 0 || m.selection.block >= len(blocks) {
+		return codeBlock{}, false
+	}
+	return blocks[m.selection.block], true
+}
+
+func (m *model) endSelection() tea.Cmd {
+	m.mode = m.selection.returnMode
+	if m.mode == modeSelect {
+		m.mode = modeChat
+	}
+	return m.textInput.Focus()
+}
+
+func (m *model) updateSelection(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := &m.selection
+	s.status = ""
+	if s.cursor >= len(m.messages) {
+		s.cursor = len(m.messages) - 1
+	}
+	blocks := codeBlocks(m.selectedMessage().content)
+
+	key := msg.String()
+	switch key {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "0":
+		s.block = -1
+		return m, nil
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		if n, _ := strconv.Atoi(key); n <= len(blocks) {
+			s.block = n - 1
+		}
+		return m, nil
+	}
+	switch m.keys.action(scopeSelection, key) {
+	case "back":
+		return m, m.endSelection()
+	case "up":
+		if s.cursor > 0 {
+			s.cursor--
+			s.block = -1
+		}
+	case "down":
+		if s.cursor < len(m.messages)-1 {
+			s.cursor++
+			s.block = -1
+		}
+	case "first":
+		s.cursor, s.block = 0, -1
+	case "last":
+		s.cursor, s.block = len(m.messages)-1, -1
+	case "next-block":
+		if len(blocks) > 0 {
+			s.block++
+			if s.block >= len(blocks) {
+				s.block = -1
+			}
+		}
+	case "copy":
+		if block, ok := m.selectedBlock(); ok {
+			return m, copyToClipboard(block.code, fmt.Sprintf("code block %d", s.block+1))
+		}
+		return m, copyToClipboard(m.selectedMessage().content, "message")
+	case "save":
+		block, ok := m.selectedBlock()
+		if !ok {
+			return m, nil
+		}
+		s.saving = block
+		m.mode = modeSaveBlockInput
+		m.textInput.Placeholder = "File to save the code block to (relative to " + m.currentPath + ")"
+		return m, tea.Batch(m.textInput.Focus(), textarea.Blink)
+	case "edit":
+		if m.selectedMessage().role == roleUser {
+			return m, m.startEdit(s.cursor)
+		}
+	case "regenerate":
+		if m.selectedMessage().replyTo != 0 {
+			return m, m.regenerate(s.cursor)
+		}
+	case "fork":
+		previous := m.branch
+		b := m.fork(s.cursor+1, fmt.Sprintf("fork of #%d at message %d", previous, s.cursor+1))
+		m.addMessage(roleInfo, fmt.Sprintf("🌿 Forked branch #%d from #%d after the selected message ('branches' to switch).", b.id, previous))
+		return m, m.endSelection()
+	case "apply":
+		block, ok := m.selectedBlock()
+		if !ok {
+			return m, nil
+		}
+		path := m.openFile()
+		if path == "" {
+			m.addMessage(roleError, "No file is open. Use '/open <file>' or highlight one in the explorer first.")
+			return m, nil
+		}
+		return m, m.proposeBlock(path, block.code)
+	}
+	return m, nil
+}
+
+// openFile is the file the user is working on: the one last opened for
+// modification, or else the file previewed in the explorer.
+func (m *model) openFile() string {
+	if m.fileModificationPath != "" {
+		return m.fileModificationPath
+	}
+	p := m.previewFile
+	if p.path != "" && !p.isDir && !p.binary {
+		return p.path
+	}
+	return ""
+}
+
+// proposeBlock shows the changes that replacing path with code would make.
+// A file that does not exist yet is created directly.
+func (m *model) proposeBlock(path, code string) tea.Cmd {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		m.mode = modeChat
+		return m.createFileWithContent(path, code, "code block from the chat")
+	}
+	return func() tea.Msg {
+		original, err := os.ReadFile(path)
+		if err != nil {
+			return errMsg{err}
+		}
+		return aiModifiedContentMsg{path: path, prompt: "code block from the chat", original: string(original), content: code}
+	}
+}
+
+func (m *model) submitSaveBlock(input string) tea.Cmd {
+	block := m.selection.saving
+	m.selection.saving = codeBlock{}
+	m.textInput.Placeholder = "Write a message or command..."
+	if input == "" {
+		m.mode = modeSelect
+		return nil
+	}
+	m.mode = modeChat
+	return m.proposeBlock(filepath.Join(m.currentPath, input), block.code)
+}
+
+// copyToClipboard puts text on the system clipboard. Without a clipboard
+// tool, as over SSH, it falls back to the OSC 52 escape sequence, which most
+// terminals pass on to the local clipboard.
+func copyToClipboard(text, what string) tea.Cmd {
+	return func() tea.Msg {
+		if err := clipboard.WriteAll(text); err == nil {
+			return copiedMsg{what: what}
+		}
+		termenv.NewOutput(os.Stdout).Copy(text)
+		return copiedMsg{what: what, osc52: true}
+	}
+}
+
+func (m *model) handleCopied(msg copiedMsg) {
+	status := "📋 Copied the " + msg.what + " to the clipboard."
+	if msg.osc52 {
+		status = "📋 Sent the " + msg.what + " to the terminal clipboard (OSC 52)."
+	}
+	if m.mode == modeFindings {
+		m.findingsPanel.status = status
+		return
+	}
+	m.selection.status = status
+}
+
+func (m model) selectionStatus() string {
+	s := m.selection
+	if s.status != "" {
+		return s.status
+	}
+	key := func(name string) string { return keyLabel(m.keys.hint(scopeSelection, name)) }
+	blocks := codeBlocks(m.messages[s.cursor].content)
+	status := fmt.Sprintf("MODE: Select message %d/%d", s.cursor+1, len(m.messages))
+	if block, ok := m.selectedBlock(); ok {
+		status += fmt.Sprintf(" | code block %d/%d (%s) | '%s' copy | '%s' save to file | '%s' apply to %s", s.block+1, len(blocks), block.describe(), key("copy"), key("save"), key("apply"), m.openFileName())
+	} else if len(blocks) > 0 {
+		status += fmt.Sprintf(" | '%s' copy | 1-%d or '%s' pick one of %d code block(s)", key("copy"), len(blocks), key("next-block"), len(blocks))
+	} else {
+		status += fmt.Sprintf(" | '%s' copy", key("copy"))
+	}
+	switch msg := m.messages[s.cursor]; {
+	case msg.role == roleUser:
+		status += fmt.Sprintf(" | '%s' edit and resend", key("edit"))
+	case msg.replyTo != 0:
+		status += fmt.Sprintf(" | '%s' regenerate", key("regenerate"))
+	}
+	return status + fmt.Sprintf(" | '%s' branch here | %s/%s move | %s back", key("fork"), key("up"), key("down"), key("back"))
+}
+
+func (m model) openFileName() string {
+	if path := m.openFile(); path != "" {
+		return filepath.Base(path)
+	}
+	return "the open file"
+}
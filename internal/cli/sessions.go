@@ -0,0 +1,334 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/config"
+	"github.com/anthonycursewl/anx-agent/internal/prompts"
+	"github.com/anthonycursewl/anx-agent/internal/session"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const maxTitleLength = 60
+
+// sessionSaveDelay gathers the changes of a burst of messages, such as a
+// streamed answer, into one save.
+const sessionSaveDelay = 500 * time.Millisecond
+
+// Options change how the TUI starts.
+type Options struct {
+	// Resume reopens the last session instead of starting a new one.
+	Resume bool
+	// Keys remaps actions, as read from the config file.
+	Keys map[string]map[string]config.KeyList
+	// Layout is "single" or "split", see 'layout'.
+	Layout string
+}
+
+type sessionLoadedMsg struct {
+	session *session.Session
+}
+
+// saveSessionMsg asks for the save scheduled as seq, unless a later one
+// replaced it.
+type saveSessionMsg struct{ seq int }
+
+type sessionSavedMsg struct{ err error }
+
+// sessionWriter writes sessions off the UI loop one at a time, never
+// replacing a snapshot with an older one.
+type sessionWriter struct {
+	mu   sync.Mutex
+	last int
+}
+
+func (w *sessionWriter) save(store *session.Store, s *session.Session, seq int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if seq < w.last {
+		return nil
+	}
+	w.last = seq
+	return store.Save(s)
+}
+
+// chatResponseMsg is the answer to the user message inputID, sent on
+// branch.
+type chatResponseMsg struct {
+	branch  int
+	inputID int
+	input   string
+	reply   ai.Reply
+	files   []string
+}
+
+func openSessionStore() (*session.Store, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return session.NewStore(filepath.Join(dir, session.DirName)), nil
+}
+
+func workspaceDir() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return wd
+}
+
+// snapshot copies the transcript, context and conversation into the
+// current session. The slices are new each time, so that a copy of the
+// session can be saved in the background.
+func (m *model) snapshot() {
+	s := m.session
+	s.Updated = time.Now()
+	s.Messages = toSessionMessages(m.messages)
+	for _, msg := range m.messages {
+		if s.Title == "" && msg.role == roleUser {
+			s.Title = sessionTitle(msg.content)
+		}
+	}
+	// The current branch is the transcript above; the others are saved
+	// whole.
+	s.Branch = m.branch
+	s.Branches = nil
+	for _, b := range m.branches {
+		saved := session.Branch{ID: b.id, Parent: b.parent, Title: b.title, ForkedAt: b.forkedAt, Created: b.created}
+		if b.id != m.branch {
+			saved.Messages = toSessionMessages(b.messages)
+			saved.Conversation = b.conversation
+		}
+		s.Branches = append(s.Branches, saved)
+	}
+	s.Context = nil
+	for _, f := range m.contextFiles.files {
+		path, err := filepath.Abs(f.path)
+		if err != nil {
+			path = f.path
+		}
+		s.Context = append(s.Context, session.ContextFile{Path: path, Pinned: f.pinned})
+	}
+	s.Conversation = append([]ai.Turn(nil), m.conversation...)
+}
+
+func sessionTitle(input string) string {
+	title := strings.Join(strings.Fields(input), " ")
+	if len([]rune(title)) > maxTitleLength {
+		title = string([]rune(title)[:maxTitleLength]) + "…"
+	}
+	return title
+}
+
+// autoSave schedules a save of the session whenever the transcript changed
+// length or the session was marked dirty. Saves happen sessionSaveDelay
+// after the last change.
+func (m *model) autoSave(before int) tea.Cmd {
+	if len(m.messages) == before && !m.sessionDirty {
+		return nil
+	}
+	m.sessionDirty = false
+	m.saveSeq++
+	seq := m.saveSeq
+	return tea.Tick(sessionSaveDelay, func(time.Time) tea.Msg { return saveSessionMsg{seq: seq} })
+}
+
+// saveSessionCmd writes a snapshot of the session in the background, once
+// the user has written something.
+func (m *model) saveSessionCmd() tea.Cmd {
+	if m.sessions == nil {
+		return nil
+	}
+	m.snapshot()
+	if m.session.Title == "" {
+		return nil
+	}
+	store, writer, seq := m.sessions, m.sessionWriter, m.saveSeq
+	saved := *m.session
+	return func() tea.Msg {
+		return sessionSavedMsg{err: writer.save(store, &saved, seq)}
+	}
+}
+
+func (m *model) handleSessionSaved(err error) {
+	if err != nil && !m.sessionSaveFailed {
+		m.addMessage(roleError, err.Error()+". The session will not be saved.")
+	}
+	m.sessionSaveFailed = err != nil
+}
+
+// saveSession writes the session right away, as on exit.
+func (m *model) saveSession() {
+	if cmd := m.saveSessionCmd(); cmd != nil {
+		cmd()
+	}
+}
+
+func (m *model) loadSession(id string) tea.Cmd {
+	store := m.sessions
+	workspace := workspaceDir()
+	return func() tea.Msg {
+		if store == nil {
+			return errMsg{fmt.Errorf("sessions are not available: no user data directory")}
+		}
+		var sess *session.Session
+		var err error
+		if id == "" {
+			sess, err = store.Latest(workspace)
+		} else {
+			sess, err = store.Find(id)
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return sessionLoadedMsg{session: sess}
+	}
+}
+
+func (m *model) handleSessionLoaded(sess *session.Session) tea.Cmd {
+	m.loading = false
+	m.mode = modeChat
+	m.session = sess
+	m.conversation = append([]ai.Turn(nil), sess.Conversation...)
+	m.messages = m.fromSessionMessages(sess.Messages)
+	m.branches = nil
+	for _, saved := range sess.Branches {
+		b := &branch{id: saved.ID, parent: saved.Parent, title: saved.Title, forkedAt: saved.ForkedAt, created: saved.Created}
+		b.messages = m.fromSessionMessages(saved.Messages)
+		b.conversation = saved.Conversation
+		m.branches = append(m.branches, b)
+	}
+	m.branch = sess.Branch
+	if m.findBranch(m.branch) == nil {
+		m.branches = append(m.branches, mainBranch())
+		m.branch = 1
+	}
+	m.addMessage(roleInfo, fmt.Sprintf("↩ Resumed session %s, last updated %s.", sess.ID, sess.Updated.Format("2006-01-02 15:04")))
+	if sess.Workspace != "" && sess.Workspace != workspaceDir() {
+		m.addMessage(roleInfo, "⚠ The session was started in "+sess.Workspace+".")
+	}
+
+	m.contextFiles = contextSet{}
+	if len(sess.Context) == 0 {
+		return nil
+	}
+	var paths []string
+	pinned := map[string]bool{}
+	for _, f := range sess.Context {
+		path := displayPath(f.Path)
+		paths = append(paths, path)
+		pinned[filepath.Clean(path)] = f.Pinned
+	}
+	m.loading = true
+	return readContextFiles(paths, pinned)
+}
+
+// readContextFiles restores context files. Files that no longer exist are
+// reported instead of failing the whole restore.
+func readContextFiles(paths []string, pinned map[string]bool) tea.Cmd {
+	return func() tea.Msg {
+		msg := contextFilesReadMsg{pinned: pinned}
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				msg.missing = append(msg.missing, path)
+				continue
+			}
+			msg.files = append(msg.files, fileContextReadMsg{path: path, content: content})
+		}
+		return msg
+	}
+}
+
+func sessionsCommand(m *model, args Args) tea.Cmd {
+	if m.sessions == nil {
+		m.addMessage(roleError, "Sessions are not available: no user data directory.")
+		return nil
+	}
+	sessions, err := m.sessions.List()
+	if err != nil {
+		m.addMessage(roleError, err.Error())
+		return nil
+	}
+	if len(sessions) == 0 {
+		m.addMessage(roleInfo, "No saved sessions yet.")
+		return nil
+	}
+
+	workspace := workspaceDir()
+	var sb strings.Builder
+	sb.WriteString("Saved sessions ('/resume <id>' to reopen one):\n")
+	for _, s := range sessions {
+		marker := "  "
+		if s.ID == m.session.ID {
+			marker = "▶ "
+		}
+		title := s.Title
+		if s.Workspace != workspace {
+			title += "  (" + s.Workspace + ")"
+		}
+		fmt.Fprintf(&sb, "%s%s  %s  %3d msgs  %s\n", marker, s.ID, s.Updated.Format("2006-01-02 15:04"), len(s.Messages), title)
+	}
+	m.addMessage(roleInfo, strings.TrimSuffix(sb.String(), "\n"))
+	return nil
+}
+
+func resumeCommand(m *model, args Args) tea.Cmd {
+	m.loading = true
+	return tea.Batch(m.spinner.Tick, m.loadSession(args.String("id")))
+}
+
+// submitChat runs input as a command, or sends it to the chat.
+func (m *model) submitChat(input string) tea.Cmd {
+	id := m.addMessage(roleUser, input).id
+	if command, ok := m.matchCommand(input); ok {
+		return m.runCommand(command, input)
+	}
+	return m.chat(id, input)
+}
+
+// chat sends input, the user message inputID, with the conversation so far
+// and the context files.
+func (m *model) chat(inputID int, input string) tea.Cmd {
+	prompt := prompts.Chat(input, m.contextFiles.prompt())
+	history := append([]ai.Turn(nil), m.conversation...)
+	files := m.contextFiles.paths()
+	branch := m.branch
+	client := m.aiClient
+	return m.startJob("chat", sessionTitle(input), func(ctx context.Context, progress func(string)) tea.Msg {
+		progress("waiting for " + ai.Model)
+		reply, err := client.Chat(ctx, history, prompt)
+		if err != nil {
+			return errMsg{err}
+		}
+		return chatResponseMsg{branch: branch, inputID: inputID, input: input, reply: reply, files: files}
+	})
+}
+
+// handleChatResponse adds the reply to the branch it was asked on, which
+// may no longer be the current one.
+func (m *model) handleChatResponse(msg chatResponseMsg) {
+	turns := []ai.Turn{{Role: "user", Text: msg.input}, {Role: "model", Text: msg.reply.Text}}
+	reply := m.newReply(msg.reply, msg.files)
+	reply.replyTo = msg.inputID
+	if msg.branch == m.branch {
+		m.conversation = append(m.conversation, turns...)
+		m.messages = append(m.messages, reply)
+		return
+	}
+	b := m.findBranch(msg.branch)
+	if b == nil {
+		return
+	}
+	b.conversation = append(b.conversation, turns...)
+	b.messages = append(b.messages, reply)
+	m.addMessage(roleInfo, fmt.Sprintf("The answer on branch #%d (%s) arrived ('/branches %d' to switch).", b.id, b.title, b.id))
+}
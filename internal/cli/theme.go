@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/theme"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func listDelegate(st styles) list.DefaultDelegate {
+	delegate := list.NewDefaultDelegate()
+	delegate.Styles.SelectedTitle = st.selected
+	delegate.Styles.SelectedDesc = st.selected
+	return delegate
+}
+
+// applyTheme restyles the whole interface. The preview is reloaded so that
+// code is highlighted with the new syntax style.
+func (m *model) applyTheme(t *theme.Theme) tea.Cmd {
+	theme.Set(t)
+	m.styles = newStyles(t)
+	m.spinner.Style = m.styles.statusSpinner
+	m.list.SetDelegate(listDelegate(m.styles))
+	m.previewPath = ""
+	return m.syncPreview()
+}
+
+func themeCommand(m *model, args Args) tea.Cmd {
+	name := args.String("name")
+	if name == "" {
+		current := theme.Current().Name
+		var sb strings.Builder
+		sb.WriteString("Themes ('/theme <name>' to switch, 'theme: <name>' in config.yaml to keep it):\n")
+		for _, n := range theme.Names() {
+			marker := "  "
+			if n == current {
+				marker = "▶ "
+			}
+			sb.WriteString(marker + n + "\n")
+		}
+		if dir, err := theme.Dir(); err == nil {
+			sb.WriteString("User themes are read from " + dir)
+		}
+		m.addMessage(roleInfo, strings.TrimSuffix(sb.String(), "\n"))
+		return nil
+	}
+
+	t, err := theme.Load(name)
+	if err != nil {
+		m.addMessage(roleError, err.Error())
+		return nil
+	}
+	cmd := m.applyTheme(t)
+	m.addMessage(roleInfo, "🎨 Switched to the "+t.Name+" theme.")
+	return cmd
+}
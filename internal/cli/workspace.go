@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/ignore"
+	"github.com/anthonycursewl/anx-agent/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxContextAdd caps how many files a single 'add' may put in the context.
+const maxContextAdd = 100
+
+type fileShownMsg struct {
+	path string
+	text string
+}
+
+type contextFilesReadMsg struct {
+	files   []fileContextReadMsg
+	skipped []string
+	missing []string
+	pinned  map[string]bool
+}
+
+func cdCommand(m *model, args Args) tea.Cmd {
+	dir := args.String("dir")
+	if dir == "" {
+		dir = "."
+	}
+	m.addMessage(roleInfo, "📁 Current directory: "+dir)
+	return m.listDirectory(dir)
+}
+
+func openCommand(m *model, args Args) tea.Cmd {
+	path := args.String("file")
+	m.loading = true
+	m.addMessage(roleInfo, "Reading file "+path+" to modify...")
+	return m.readFileContent(path)
+}
+
+func catCommand(m *model, args Args) tea.Cmd {
+	path := args.String("file")
+	maxLines := args.Int("lines")
+	syntax := m.styles.syntax
+	return func() tea.Msg {
+		f, err := os.Open(path)
+		if err != nil {
+			return errMsg{err}
+		}
+		defer f.Close()
+		head, err := io.ReadAll(io.LimitReader(f, maxPreviewBytes))
+		if err != nil {
+			return errMsg{fmt.Errorf("error reading file '%s': %w", path, err)}
+		}
+		if utils.IsBinary(head) {
+			return fileShownMsg{path: path, text: "Binary file, nothing to show."}
+		}
+
+		lines := highlight(path, string(head), syntax)
+		total := len(lines)
+		if maxLines > 0 && total > maxLines {
+			lines = lines[:maxLines]
+		}
+		width := len(fmt.Sprint(len(lines)))
+		var sb strings.Builder
+		for i, line := range lines {
+			fmt.Fprintf(&sb, "%*d  %s\n", width, i+1, line)
+		}
+		if len(lines) < total {
+			fmt.Fprintf(&sb, "… %d more line(s); use --lines to show more", total-len(lines))
+		}
+		return fileShownMsg{path: path, text: strings.TrimSuffix(sb.String(), "\n")}
+	}
+}
+
+func addCommand(m *model, args Args) tea.Cmd {
+	return m.addToContext(args.Rest(), args.Bool("all"))
+}
+
+// addToContext reads every file matching patterns into the context. Plain
+// paths are taken as they are; glob patterns are expanded below the current
+// directory and skip ignored files unless all is set.
+func (m *model) addToContext(patterns []string, all bool) tea.Cmd {
+	dir := m.currentPath
+	m.loading = true
+	return func() tea.Msg {
+		var paths []string
+		seen := map[string]bool{}
+		for _, pattern := range patterns {
+			matches := []string{pattern}
+			if strings.ContainsAny(pattern, "*?[") {
+				found, err := ignore.Glob(dir, pattern, all)
+				if err != nil {
+					return errMsg{err}
+				}
+				if len(found) == 0 {
+					return errMsg{fmt.Errorf("no files match '%s'", pattern)}
+				}
+				matches = found
+			}
+			for _, match := range matches {
+				path := filepath.Join(dir, filepath.FromSlash(match))
+				if filepath.IsAbs(match) {
+					path = match
+				}
+				if !seen[path] {
+					seen[path] = true
+					paths = append(paths, path)
+				}
+			}
+		}
+		if len(paths) > maxContextAdd {
+			return errMsg{fmt.Errorf("%d files match; add at most %d at once", len(paths), maxContextAdd)}
+		}
+
+		var msg contextFilesReadMsg
+		for _, path := range paths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return errMsg{err}
+			}
+			if utils.IsBinary(content) {
+				msg.skipped = append(msg.skipped, path)
+				continue
+			}
+			msg.files = append(msg.files, fileContextReadMsg{path: path, content: content})
+		}
+		return msg
+	}
+}
+
+func (m *model) handleContextFilesRead(msg contextFilesReadMsg) {
+	m.loading = false
+	var size int64
+	for _, f := range msg.files {
+		m.contextFiles.add(f.path, f.content)
+		if msg.pinned[filepath.Clean(f.path)] {
+			m.contextFiles.setPinned(f.path, true)
+		}
+		size += int64(len(f.content))
+	}
+	info := fmt.Sprintf("✅ Added %d file(s), %s, to the context (%s).", len(msg.files), utils.FormatSize(size), m.contextFiles.summary())
+	if len(msg.skipped) > 0 {
+		info += fmt.Sprintf(" Skipped %d binary file(s).", len(msg.skipped))
+	}
+	if len(msg.missing) > 0 {
+		info += " Missing: " + strings.Join(msg.missing, ", ") + "."
+	}
+	m.addMessage(roleInfo, info)
+}
@@ -3,12 +3,39 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	GEMINI_API_KEY string `yaml:"gemini_api_key"`
+	Theme          string `yaml:"theme"`
+	// Layout is "single" to show the explorer and the chat in turn, or
+	// "split" to show them side by side.
+	Layout string `yaml:"layout"`
+	// LogLevel is debug, info, warn or error.
+	LogLevel string `yaml:"log_level"`
+	// Timeout bounds each AI request of the headless commands, e.g. "30s".
+	Timeout string `yaml:"timeout"`
+	// Keys remaps actions to other keys, by scope and action name.
+	Keys map[string]map[string]KeyList `yaml:"keys"`
+}
+
+// KeyList is one key or a list of keys in the config file.
+type KeyList []string
+
+func (k *KeyList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*k = KeyList{value.Value}
+		return nil
+	}
+	var keys []string
+	if err := value.Decode(&keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
 }
 
 func LoadConfig(configPath string) (*Config, error) {
@@ -30,6 +57,41 @@ func LoadConfig(configPath string) (*Config, error) {
 	if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
 		cfg.GEMINI_API_KEY = apiKey
 	}
+	if theme := os.Getenv("ANX_THEME"); theme != "" {
+		cfg.Theme = theme
+	}
+	if layout := os.Getenv("ANX_LAYOUT"); layout != "" {
+		cfg.Layout = layout
+	}
+	if level := os.Getenv("ANX_LOG_LEVEL"); level != "" {
+		cfg.LogLevel = level
+	}
 
 	return cfg, nil
 }
+
+// DataDir returns the directory where per-user data such as the input
+// history is kept: $XDG_DATA_HOME/anx, or ~/.local/share/anx.
+func DataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "anx"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding home directory: %v", err)
+	}
+	return filepath.Join(home, ".local", "share", "anx"), nil
+}
+
+// ConfigDir returns the directory of per-user configuration such as themes:
+// $XDG_CONFIG_HOME/anx, or ~/.config/anx.
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "anx"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding home directory: %v", err)
+	}
+	return filepath.Join(home, ".config", "anx"), nil
+}
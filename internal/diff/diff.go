@@ -0,0 +1,302 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Kind int
+
+const (
+	Equal Kind = iota
+	Delete
+	Insert
+)
+
+// Line is a single line of an edit script. Text keeps its trailing newline,
+// if any, so that applying a script reproduces the input byte for byte.
+type Line struct {
+	Kind Kind
+	Text string
+}
+
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	if lines == 0 {
+		start--
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// SplitLines splits s into lines, keeping the line terminators.
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines returns the shortest edit script that turns a into b.
+func Lines(a, b string) []Line {
+	return script(SplitLines(a), SplitLines(b))
+}
+
+func script(a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		lines = append(lines, Line{Kind: Equal, Text: l})
+	}
+	lines = append(lines, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Kind: Equal, Text: l})
+	}
+	return lines
+}
+
+// myers implements the greedy O(ND) algorithm from "An O(ND) Difference
+// Algorithm and Its Variations". Only the frontier of each round is kept, so
+// memory grows with the square of the edit distance instead of the inputs.
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	var trace [][]int
+	at := func(v []int, d, k int) int {
+		i := k + d
+		if i < 0 || i >= len(v) {
+			return -1
+		}
+		return v[i]
+	}
+
+	var prev []int
+	for d := 0; d <= n+m; d++ {
+		cur := make([]int, 2*d+1)
+		done := false
+		for k := -d; k <= d; k += 2 {
+			var x int
+			switch {
+			case d == 0:
+				x = 0
+			case k == -d || (k != d && at(prev, d-1, k-1) < at(prev, d-1, k+1)):
+				x = at(prev, d-1, k+1)
+			default:
+				x = at(prev, d-1, k-1) + 1
+			}
+			y := x - k
+			for x < n && y < m && x >= 0 && y >= 0 && a[x] == b[y] {
+				x++
+				y++
+			}
+			cur[k+d] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+		trace = append(trace, cur)
+		prev = cur
+		if done {
+			break
+		}
+	}
+
+	var rev []Line
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d-1]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(v, d-1, k-1) < at(v, d-1, k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(v, d-1, prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, Line{Kind: Equal, Text: a[x]})
+		}
+		if prevK == k+1 {
+			rev = append(rev, Line{Kind: Insert, Text: b[prevY]})
+		} else {
+			rev = append(rev, Line{Kind: Delete, Text: a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		rev = append(rev, Line{Kind: Equal, Text: a[x]})
+	}
+
+	lines := make([]Line, len(rev))
+	for i, l := range rev {
+		lines[len(rev)-1-i] = l
+	}
+	return lines
+}
+
+// Hunks groups the changes between a and b into hunks with the given number
+// of context lines. Hunks whose context would overlap are merged.
+func Hunks(a, b string, context int) []Hunk {
+	return group(Lines(a, b), context)
+}
+
+func group(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	oldLine, newLine := 1, 1
+	i := 0
+	for i < len(lines) {
+		if lines[i].Kind == Equal {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		h := Hunk{
+			OldStart: oldLine - (i - start),
+			NewStart: newLine - (i - start),
+		}
+
+		end := i
+		for end < len(lines) {
+			if lines[end].Kind != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Kind == Equal {
+				run++
+			}
+			if run == len(lines) || run-end > 2*context {
+				break
+			}
+			end = run
+		}
+		stop := end + context
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+
+		for _, l := range lines[start:stop] {
+			h.Lines = append(h.Lines, l)
+			if l.Kind != Insert {
+				h.OldLines++
+			}
+			if l.Kind != Delete {
+				h.NewLines++
+			}
+		}
+		for _, l := range lines[i:stop] {
+			if l.Kind != Insert {
+				oldLine++
+			}
+			if l.Kind != Delete {
+				newLine++
+			}
+		}
+		hunks = append(hunks, h)
+		i = stop
+	}
+	return hunks
+}
+
+// Apply rebuilds the new version of a keeping only the accepted hunks.
+// Rejected hunks leave the original lines in place.
+func Apply(a string, hunks []Hunk, accepted []bool) string {
+	old := SplitLines(a)
+	var sb strings.Builder
+	next := 0
+	for i, h := range hunks {
+		start := h.OldStart - 1
+		for next < start && next < len(old) {
+			sb.WriteString(old[next])
+			next++
+		}
+		for _, l := range h.Lines {
+			switch {
+			case l.Kind == Equal:
+				sb.WriteString(l.Text)
+			case l.Kind == Insert && accepted[i]:
+				sb.WriteString(l.Text)
+			case l.Kind == Delete && !accepted[i]:
+				sb.WriteString(l.Text)
+			}
+		}
+		next += h.OldLines
+	}
+	for next < len(old) {
+		sb.WriteString(old[next])
+		next++
+	}
+	return sb.String()
+}
+
+// Unified renders the hunks between a and b in unified diff format.
+func Unified(fromName, toName, a, b string, context int) string {
+	hunks := Hunks(a, b, context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks {
+		sb.WriteString(h.Header() + "\n")
+		for _, l := range h.Lines {
+			sb.WriteString(Prefix(l.Kind) + l.Text)
+			if !strings.HasSuffix(l.Text, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+func Prefix(k Kind) string {
+	switch k {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	}
+	return " "
+}
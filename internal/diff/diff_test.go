@@ -0,0 +1,222 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var pairs = []struct {
+	name string
+	a, b string
+}{
+	{"equal", "a\nb\n", "a\nb\n"},
+	{"empty to file", "", "a\nb\n"},
+	{"file to empty", "a\nb\n", ""},
+	{"change in middle", "a\nb\nc\nd\ne\n", "a\nb\nX\nd\ne\n"},
+	{"insert at start", "b\nc\n", "a\nb\nc\n"},
+	{"delete at end", "a\nb\nc\n", "a\nb\n"},
+	{"two distant hunks", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n", "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n"},
+	{"add final newline", "a\nb", "a\nb\n"},
+	{"drop final newline", "a\nb\n", "a\nb"},
+	{"change last line without newline", "a\nb", "a\nc"},
+	{"append after line without newline", "a\nb", "a\nb\nc"},
+	{"replace line without newline by lines", "b", "b\na\nc\nd"},
+	{"crlf", "a\r\nb\r\n", "a\r\nc\r\n"},
+}
+
+func TestApplyAllHunks(t *testing.T) {
+	for _, tt := range pairs {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks := Hunks(tt.a, tt.b, 3)
+			if tt.a == tt.b && len(hunks) != 0 {
+				t.Fatalf("equal inputs gave %d hunks", len(hunks))
+			}
+			if got := Apply(tt.a, hunks, all(len(hunks), true)); got != tt.b {
+				t.Errorf("accepting every hunk = %q, want %q", got, tt.b)
+			}
+			if got := Apply(tt.a, hunks, all(len(hunks), false)); got != tt.a {
+				t.Errorf("rejecting every hunk = %q, want %q", got, tt.a)
+			}
+		})
+	}
+}
+
+func TestApplySomeHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"
+	hunks := Hunks(a, b, 1)
+	if len(hunks) != 2 {
+		t.Fatalf("got %d hunks, want 2", len(hunks))
+	}
+	if got, want := Apply(a, hunks, []bool{true, false}), "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"; got != want {
+		t.Errorf("first hunk only = %q, want %q", got, want)
+	}
+	if got, want := Apply(a, hunks, []bool{false, true}), "1\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"; got != want {
+		t.Errorf("second hunk only = %q, want %q", got, want)
+	}
+}
+
+func TestHunksMergeCloseChanges(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n"
+	b := "one\n2\n3\n4\n5\n6\nseven\n"
+	if got := len(Hunks(a, b, 3)); got != 1 {
+		t.Errorf("with 3 lines of context: %d hunks, want 1", got)
+	}
+	if got := len(Hunks(a, b, 2)); got != 2 {
+		t.Errorf("with 2 lines of context: %d hunks, want 2", got)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	got := Unified("a/f.txt", "b/f.txt", "a\nb\nc\n", "a\nB\nc\n", 1)
+	want := "--- a/f.txt\n+++ b/f.txt\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"
+	if got != want {
+		t.Errorf("Unified =\n%s\nwant\n%s", got, want)
+	}
+	if got := Unified("a", "b", "same\n", "same\n", 3); got != "" {
+		t.Errorf("Unified of equal inputs = %q, want empty", got)
+	}
+
+	got = Unified("a", "b", "x\ny", "x\nz", 3)
+	want = "--- a\n+++ b\n@@ -1,2 +1,2 @@\n x\n-y\n\\ No newline at end of file\n+z\n\\ No newline at end of file\n"
+	if got != want {
+		t.Errorf("Unified without final newlines =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	tests := []struct {
+		h    Hunk
+		want string
+	}{
+		{Hunk{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 4}, "@@ -1,3 +1,4 @@"},
+		{Hunk{OldStart: 5, OldLines: 1, NewStart: 5, NewLines: 1}, "@@ -5 +5 @@"},
+		{Hunk{OldStart: 1, OldLines: 0, NewStart: 1, NewLines: 2}, "@@ -0,0 +1,2 @@"},
+	}
+	for _, tt := range tests {
+		if got := tt.h.Header(); got != tt.want {
+			t.Errorf("Header() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// The diffs written by Unified parse back into hunks that rebuild the new
+// version, or the old one when every hunk is rejected.
+func TestParsePatchRoundTrip(t *testing.T) {
+	for _, tt := range pairs {
+		if tt.a == tt.b {
+			continue
+		}
+		for _, context := range []int{0, 3} {
+			t.Run(fmt.Sprintf("%s/context %d", tt.name, context), func(t *testing.T) {
+				patches, err := ParsePatch(Unified("a/f.txt", "b/f.txt", tt.a, tt.b, context))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(patches) != 1 || patches[0].Path() != "f.txt" {
+					t.Fatalf("unexpected patches %+v", patches)
+				}
+				hunks := patches[0].Hunks
+				if got := Apply(tt.a, hunks, all(len(hunks), true)); got != tt.b {
+					t.Errorf("applying the parsed patch = %q, want %q", got, tt.b)
+				}
+				if got := Apply(tt.a, hunks, all(len(hunks), false)); got != tt.a {
+					t.Errorf("rejecting every parsed hunk = %q, want %q", got, tt.a)
+				}
+			})
+		}
+	}
+}
+
+func TestParsePatchGit(t *testing.T) {
+	text := strings.Join([]string{
+		"diff --git a/old.go b/old.go",
+		"index 1111111..2222222 100644",
+		"--- a/old.go",
+		"+++ b/old.go",
+		"@@ -1,2 +1,2 @@",
+		" package p",
+		"-var x = 1",
+		"+var x = 2",
+		"diff --git a/new.go b/new.go",
+		"new file mode 100644",
+		"--- /dev/null",
+		"+++ b/new.go",
+		"@@ -0,0 +1 @@",
+		"+package p",
+		"diff --git a/gone.go b/gone.go",
+		"deleted file mode 100644",
+		"--- a/gone.go",
+		"+++ /dev/null",
+		"@@ -1 +0,0 @@",
+		"-package p",
+		"\\ No newline at end of file",
+		"",
+	}, "\n")
+	patches, err := ParsePatch(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 3 {
+		t.Fatalf("got %d patches, want 3", len(patches))
+	}
+
+	tests := []struct {
+		old, new, path string
+		newStart       int
+		lines          []Line
+	}{
+		{"old.go", "old.go", "old.go", 1, []Line{{Equal, "package p\n"}, {Delete, "var x = 1\n"}, {Insert, "var x = 2\n"}}},
+		{"", "new.go", "new.go", 1, []Line{{Insert, "package p\n"}}},
+		{"gone.go", "", "gone.go", 1, []Line{{Delete, "package p"}}},
+	}
+	for i, tt := range tests {
+		p := patches[i]
+		if p.OldPath != tt.old || p.NewPath != tt.new || p.Path() != tt.path {
+			t.Errorf("patch %d: paths %q, %q, %q; want %q, %q, %q", i, p.OldPath, p.NewPath, p.Path(), tt.old, tt.new, tt.path)
+		}
+		if len(p.Hunks) != 1 {
+			t.Fatalf("patch %d: got %d hunks, want 1", i, len(p.Hunks))
+		}
+		h := p.Hunks[0]
+		if h.NewStart != tt.newStart {
+			t.Errorf("patch %d: NewStart = %d, want %d", i, h.NewStart, tt.newStart)
+		}
+		if !equalLines(h.Lines, tt.lines) {
+			t.Errorf("patch %d: lines %q, want %q", i, h.Lines, tt.lines)
+		}
+	}
+}
+
+func TestParsePatchMalformed(t *testing.T) {
+	for _, text := range []string{
+		"--- a/f\n+++ b/f\n@@ -1 +1 @@\n?what\n",
+		"@@ -1 +1 @@\n-a\n+b\n",
+		"--- a/f\n+++ b/f\n@@ bad @@\n",
+	} {
+		if _, err := ParsePatch(text); err == nil {
+			t.Errorf("ParsePatch(%q) should fail", text)
+		}
+	}
+}
+
+func all(n int, v bool) []bool {
+	accepted := make([]bool, n)
+	for i := range accepted {
+		accepted[i] = v
+	}
+	return accepted
+}
+
+func equalLines(a, b []Line) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
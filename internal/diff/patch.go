@@ -0,0 +1,141 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FilePatch is the part of a unified diff that changes one file. OldPath is
+// empty for a created file and NewPath for a deleted one.
+type FilePatch struct {
+	OldPath string
+	NewPath string
+	Hunks   []Hunk
+}
+
+// Path is the name of the file the patch is about.
+func (p FilePatch) Path() string {
+	if p.NewPath != "" {
+		return p.NewPath
+	}
+	return p.OldPath
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch splits a unified diff, as written by git diff or diff -u, into
+// the patches of each file. Hunk lines keep the newline that ends them in
+// the file, so a "\ No newline at end of file" marker removes it.
+func ParsePatch(text string) ([]FilePatch, error) {
+	var patches []FilePatch
+	var current *FilePatch
+	var hunk *Hunk
+	oldLeft, newLeft := 0, 0
+	start := func() {
+		patches = append(patches, FilePatch{})
+		current = &patches[len(patches)-1]
+		hunk = nil
+	}
+
+	for n, line := range SplitLines(text) {
+		body := strings.TrimRight(line, "\r\n")
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			kind := Equal
+			switch {
+			case strings.HasPrefix(body, "-"):
+				kind = Delete
+				oldLeft--
+			case strings.HasPrefix(body, "+"):
+				kind = Insert
+				newLeft--
+			case strings.HasPrefix(body, " "), body == "":
+				oldLeft--
+				newLeft--
+			case strings.HasPrefix(body, `\`):
+				// The marker can come before the end of the hunk, after
+				// the old last line when new lines follow it.
+				if len(hunk.Lines) > 0 {
+					last := &hunk.Lines[len(hunk.Lines)-1]
+					last.Text = strings.TrimSuffix(last.Text, "\n")
+				}
+				continue
+			default:
+				return nil, fmt.Errorf("line %d: unexpected line in hunk: %q", n+1, body)
+			}
+			text := strings.TrimSuffix(line, "\n")
+			if len(text) > 0 {
+				text = text[1:]
+			}
+			hunk.Lines = append(hunk.Lines, Line{Kind: kind, Text: text + "\n"})
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(body, `\`):
+			// "\ No newline at end of file" after the last line of a hunk.
+			if hunk != nil && len(hunk.Lines) > 0 {
+				last := &hunk.Lines[len(hunk.Lines)-1]
+				last.Text = strings.TrimSuffix(last.Text, "\n")
+			}
+		case strings.HasPrefix(body, "diff --git "):
+			start()
+			if a, b, ok := strings.Cut(strings.TrimPrefix(body, "diff --git "), " b/"); ok {
+				current.OldPath, current.NewPath = patchPath(a), b
+			}
+		case strings.HasPrefix(body, "--- "):
+			if current == nil || len(current.Hunks) > 0 {
+				start()
+			}
+			current.OldPath = patchPath(strings.TrimPrefix(body, "--- "))
+		case strings.HasPrefix(body, "+++ ") && current != nil:
+			current.NewPath = patchPath(strings.TrimPrefix(body, "+++ "))
+		case strings.HasPrefix(body, "@@"):
+			m := hunkHeader.FindStringSubmatch(body)
+			if m == nil || current == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header %q", n+1, body)
+			}
+			h := Hunk{OldStart: atoi(m[1]), OldLines: count(m[2]), NewStart: atoi(m[3]), NewLines: count(m[4])}
+			// An empty range names the line before it.
+			if h.OldLines == 0 {
+				h.OldStart++
+			}
+			if h.NewLines == 0 {
+				h.NewStart++
+			}
+			current.Hunks = append(current.Hunks, h)
+			hunk = &current.Hunks[len(current.Hunks)-1]
+			oldLeft, newLeft = h.OldLines, h.NewLines
+		}
+	}
+	return patches, nil
+}
+
+// patchPath strips the a/ or b/ prefix git adds and the timestamp diff -u
+// adds; /dev/null becomes empty.
+func patchPath(name string) string {
+	if i := strings.Index(name, "\t"); i >= 0 {
+		name = name[:i]
+	}
+	if name == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(name, "a/") || strings.HasPrefix(name, "b/") {
+		return name[2:]
+	}
+	return name
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// count is the length of a hunk range, which is 1 when omitted.
+func count(s string) int {
+	if s == "" {
+		return 1
+	}
+	return atoi(s)
+}
@@ -0,0 +1,155 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Run executes git in dir and returns its standard output.
+func Run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+	return stdout.String(), nil
+}
+
+// Root returns the top-level directory of the repository containing dir.
+func Root(dir string) (string, error) {
+	out, err := Run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(strings.TrimSpace(out)), nil
+}
+
+// Diff returns the unified diff of the changes in the repository containing
+// dir: the staged changes when rev is empty, or those of a revision range
+// such as "main..HEAD". Paths are relative to the top of the repository and
+// each hunk has context lines around the changes.
+func Diff(dir, rev string, context int) (string, error) {
+	if err := checkRev(rev); err != nil {
+		return "", err
+	}
+	args := []string{"diff", "--no-color", "--no-ext-diff", fmt.Sprintf("--unified=%d", context)}
+	if rev == "" {
+		args = append(args, "--cached", "--")
+	} else {
+		args = append(args, rev, "--")
+	}
+	return Run(dir, args...)
+}
+
+// DiffStat summarizes the changes Diff would show, one line per file.
+func DiffStat(dir, rev string) (string, error) {
+	if err := checkRev(rev); err != nil {
+		return "", err
+	}
+	args := []string{"diff", "--no-color", "--stat"}
+	if rev == "" {
+		args = append(args, "--cached", "--")
+	} else {
+		args = append(args, rev, "--")
+	}
+	return Run(dir, args...)
+}
+
+// Log returns the subjects of the commits in a revision range, oldest first.
+func Log(dir, rev string) ([]string, error) {
+	if err := checkRev(rev); err != nil {
+		return nil, err
+	}
+	out, err := Run(dir, "log", "--reverse", "--format=%s", rev, "--")
+	if err != nil {
+		return nil, err
+	}
+	var subjects []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			subjects = append(subjects, line)
+		}
+	}
+	return subjects, nil
+}
+
+// checkRev rejects a revision that git would take for an option, such as
+// "--output=file".
+func checkRev(rev string) error {
+	if strings.HasPrefix(rev, "-") {
+		return fmt.Errorf("invalid revision '%s'", rev)
+	}
+	return nil
+}
+
+// HooksDir returns the directory git runs the hooks of the repository
+// containing dir from, which core.hooksPath may move.
+func HooksDir(dir string) (string, error) {
+	out, err := Run(dir, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	hooks := filepath.FromSlash(strings.TrimSpace(out))
+	if !filepath.IsAbs(hooks) {
+		hooks = filepath.Join(dir, hooks)
+	}
+	return hooks, nil
+}
+
+// Status returns the two-letter porcelain status of every changed path in
+// the repository containing dir, keyed by absolute path with symlinks
+// resolved.
+func Status(dir string) (map[string]string, error) {
+	root, err := Root(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Callers resolve symlinks too, so that the keys match under a
+	// symlinked checkout.
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		root = real
+	}
+	out, err := Run(root, "status", "--porcelain=v1", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+
+	status := map[string]string{}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		entry := fields[i]
+		if len(entry) < 4 {
+			continue
+		}
+		code, path := entry[:2], entry[3:]
+		status[filepath.Join(root, filepath.FromSlash(path))] = code
+		if code[0] == 'R' || code[0] == 'C' {
+			i++
+		}
+	}
+	return status, nil
+}
+
+// Marker condenses a porcelain status code into a single letter.
+func Marker(code string) string {
+	switch {
+	case code == "??":
+		return "?"
+	case strings.Contains(code, "U") || code == "AA" || code == "DD":
+		return "!"
+	case code[0] != ' ':
+		return string(code[0])
+	default:
+		return string(code[1])
+	}
+}
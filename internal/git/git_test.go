@@ -0,0 +1,153 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newRepo creates a repository with one commit of a.txt, isolated from the
+// configuration of the user.
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	dir := t.TempDir()
+	run(t, dir, "init", "-q")
+	run(t, dir, "config", "user.name", "Test")
+	run(t, dir, "config", "user.email", "test@example.com")
+	commit(t, dir, "a.txt", "one\n", "first")
+	return dir
+}
+
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := Run(dir, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func write(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commit(t *testing.T, dir, name, content, subject string) {
+	t.Helper()
+	write(t, dir, name, content)
+	run(t, dir, "add", name)
+	run(t, dir, "commit", "-q", "-m", subject)
+}
+
+func TestDiffStaged(t *testing.T) {
+	dir := newRepo(t)
+	write(t, dir, "a.txt", "two\n")
+	if out, err := Diff(dir, "", 3); err != nil || out != "" {
+		t.Fatalf("Diff before staging = %q, %v; want no changes", out, err)
+	}
+	run(t, dir, "add", "a.txt")
+	out, err := Diff(dir, "", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "+++ b/a.txt") || !strings.Contains(out, "-one\n+two\n") {
+		t.Errorf("unexpected staged diff:\n%s", out)
+	}
+	stat, err := DiffStat(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stat, "a.txt") {
+		t.Errorf("unexpected stat %q", stat)
+	}
+}
+
+func TestDiffRangeAndLog(t *testing.T) {
+	dir := newRepo(t)
+	commit(t, dir, "b.txt", "b\n", "second")
+	commit(t, dir, "a.txt", "changed\n", "third")
+
+	out, err := Diff(dir, "HEAD~2..HEAD", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "+++ b/a.txt") || !strings.Contains(out, "+++ b/b.txt") {
+		t.Errorf("unexpected diff of the range:\n%s", out)
+	}
+	subjects, err := Log(dir, "HEAD~2..HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"second", "third"}; !reflect.DeepEqual(subjects, want) {
+		t.Errorf("Log = %q, want %q", subjects, want)
+	}
+}
+
+func TestRejectsOptionsAsRevisions(t *testing.T) {
+	dir := newRepo(t)
+	out := filepath.Join(dir, "out")
+	rev := "--output=" + out
+	if _, err := Diff(dir, rev, 3); err == nil {
+		t.Error("Diff accepted an option as the revision")
+	}
+	if _, err := DiffStat(dir, rev); err == nil {
+		t.Error("DiffStat accepted an option as the revision")
+	}
+	if _, err := Log(dir, rev); err == nil {
+		t.Error("Log accepted an option as the revision")
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("git wrote the file named by the option")
+	}
+}
+
+// A revision is never read as a path, even when a file has its name.
+func TestRevisionIsNotAPath(t *testing.T) {
+	dir := newRepo(t)
+	write(t, dir, "a.txt", "two\n")
+	if out, err := Diff(dir, "a.txt", 3); err == nil {
+		t.Errorf("Diff took a.txt as a path:\n%s", out)
+	}
+	if out, err := DiffStat(dir, "a.txt"); err == nil {
+		t.Errorf("DiffStat took a.txt as a path: %q", out)
+	}
+	if subjects, err := Log(dir, "a.txt"); err == nil {
+		t.Errorf("Log took a.txt as a path: %q", subjects)
+	}
+}
+
+func TestHooksDir(t *testing.T) {
+	dir := newRepo(t)
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, from := range []string{dir, sub} {
+		got, err := HooksDir(from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join(dir, ".git", "hooks"); got != want {
+			t.Errorf("HooksDir(%s) = %s, want %s", from, got, want)
+		}
+	}
+
+	run(t, dir, "config", "core.hooksPath", "custom")
+	got, err := HooksDir(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "custom"); got != want {
+		t.Errorf("HooksDir with core.hooksPath = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+package headless
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/agent/analyzer"
+	"github.com/anthonycursewl/anx-agent/internal/agent/project"
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/reporting"
+	"github.com/anthonycursewl/anx-agent/internal/utils"
+)
+
+var analyzeCmd = &Command{
+	Name:    "analyze",
+	Summary: "Analyze a project file by file and report an overview, its technologies, file summaries, suggestions and issues",
+	Usage:   "analyze [flags] [--path] [path]",
+}
+
+func init() { analyzeCmd.Run = runAnalyze }
+
+func runAnalyze(ctx context.Context, env *Env, args []string) error {
+	fs := env.flags(analyzeCmd)
+	root := fs.String("path", "", "project directory or file to analyze (default: the argument, or .)")
+	var extensions, ignorePaths fileList
+	fs.Var(&extensions, "extensions", "only analyze files with these extensions, e.g. go,md")
+	fs.Var(&ignorePaths, "ignore-paths", "gitignore patterns of paths to leave out, e.g. vendor,testdata")
+	maxSize := fs.Int("max-file-size", 100, "leave out files larger than this many KB; 0 for no limit")
+	chunkTokens := fs.Int("chunk-tokens", analyzer.DefaultMaxTokensPerChunk, "split files into chunks of about this many tokens")
+	focus := fs.String("question", "", "a question or topic the analysis should concentrate on")
+	fs.StringVar(focus, "q", "", "shorthand for --question")
+	output := fs.String("output", "", "file to write the report to, its format taken from the extension; or md, json or html for standard output")
+	format := fs.String("format", "", "md, json or html (default md, or json with --json)")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 || (len(args) == 1 && *root != "") {
+		fs.Usage()
+		return usageErrorf("analyze takes a single path")
+	}
+	if *root == "" && len(args) == 1 {
+		*root = args[0]
+	}
+
+	outFile, outFormat, err := reportTarget(*output, *format, env.JSON)
+	if err != nil {
+		return usageError{err}
+	}
+
+	var files []project.File
+	if *root == "" && env.StdinPiped {
+		stdin, err := env.readStdin()
+		if err != nil {
+			return err
+		}
+		*root = stdinName
+		files = []project.File{{Path: stdinName, Content: []byte(stdin)}}
+	} else {
+		if *root == "" {
+			*root = "."
+		}
+		var skipped []project.Skipped
+		files, skipped, err = project.DiscoverFiles(*root, extensions, ignorePaths, *maxSize)
+		if err != nil {
+			return err
+		}
+		for _, s := range skipped {
+			env.progress("Skipping %s (%s)", s.Path, s.Reason)
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files to analyze in '%s'", *root)
+	}
+	tokens := 0
+	for _, f := range files {
+		tokens += ai.EstimateTokens(string(f.Content))
+	}
+	env.progress("Analyzing %d file(s) in %s, ~%s tokens, with %s", len(files), *root, utils.FormatCount(tokens), ai.Model)
+
+	if err := env.connect(); err != nil {
+		return err
+	}
+	a := &analyzer.ProjectAnalyzer{
+		Client:            env,
+		MaxTokensPerChunk: *chunkTokens,
+		Focus:             *focus,
+		Progress: func(e analyzer.Event) {
+			switch {
+			case e.File == "":
+				env.progress("Consolidating %d file summaries...", e.Total)
+			case e.Chunks > 1:
+				env.progress("[%d/%d] %s (part %d/%d)", e.Index, e.Total, e.File, e.Chunk, e.Chunks)
+			default:
+				env.progress("[%d/%d] %s", e.Index, e.Total, e.File)
+			}
+		},
+	}
+	analysis, err := a.AnalyzeProject(ctx, *root, files)
+	if err != nil {
+		return err
+	}
+	for _, s := range analysis.FileSummaries {
+		if s.Error != "" {
+			env.progress("Could not analyze all of %s: %s", s.FilePath, s.Error)
+		}
+	}
+
+	report, err := reporting.GenerateReport(analysis, outFormat)
+	if err != nil {
+		return err
+	}
+	if outFile == "" {
+		_, err := env.Stdout.Write(report)
+		return err
+	}
+	if err := os.WriteFile(outFile, report, 0644); err != nil {
+		return fmt.Errorf("error writing '%s': %w", outFile, err)
+	}
+	env.progress("Wrote %s (%d tokens used)", outFile, analysis.Usage.Total)
+	if env.JSON {
+		return env.writeJSON(map[string]any{"command": analyzeCmd.Name, "model": ai.Model, "report": outFile, "format": outFormat, "files": len(analysis.FileSummaries), "usage": analysis.Usage})
+	}
+	return nil
+}
+
+// reportTarget decides where a report goes and in which format. output is a
+// file, whose extension gives the format, or the name of a format to write
+// to standard output.
+func reportTarget(output, format string, json bool) (string, reporting.Format, error) {
+	file := output
+	if output != "" && !strings.ContainsAny(output, "./\\") {
+		if f, err := reporting.ParseFormat(output); err == nil {
+			file, format = "", string(f)
+		}
+	}
+	switch {
+	case format != "":
+		f, err := reporting.ParseFormat(format)
+		return file, f, err
+	case file != "":
+		return file, reporting.FormatOf(file), nil
+	case json:
+		return "", reporting.JSON, nil
+	}
+	return "", reporting.Markdown, nil
+}
@@ -0,0 +1,55 @@
+package headless
+
+import (
+	"context"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/prompts"
+)
+
+var askCmd = &Command{
+	Name:    "ask",
+	Summary: "Ask a question, with files and piped input as context",
+	Usage:   "ask [flags] [question...]",
+}
+
+func init() { askCmd.Run = runAsk }
+
+func runAsk(ctx context.Context, env *Env, args []string) error {
+	fs := env.flags(askCmd)
+	var files fileList
+	fs.Var(&files, "file", "file or directory to share as context; can be repeated")
+	fs.Var(&files, "f", "shorthand for --file")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	shared, err := env.collect(files)
+	if err != nil {
+		return err
+	}
+	question := strings.TrimSpace(strings.Join(args, " "))
+	stdin, err := env.readStdin()
+	if err != nil {
+		return err
+	}
+	switch {
+	case question == "" && strings.TrimSpace(stdin) == "":
+		fs.Usage()
+		return usageErrorf("a question is required, as arguments or on standard input")
+	case question == "":
+		// What was piped is the question itself.
+		question = stdin
+	case stdin != "":
+		shared = append(shared, prompts.File{Path: stdinName, Content: stdin})
+	}
+
+	env.progress("Asking %s...", ai.Model)
+	reply, err := env.Generate(ctx, prompts.Chat(question, prompts.Files(shared)))
+	if err != nil {
+		return err
+	}
+	return env.answer(askCmd.Name, reply, paths(shared))
+}
@@ -0,0 +1,409 @@
+package headless
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/batch"
+	"github.com/anthonycursewl/anx-agent/internal/prompts"
+)
+
+var batchCmd = &Command{
+	Name:    "batch",
+	Summary: "Run the generate, modify and analyze tasks of a YAML task file and summarize the results",
+	Usage:   "batch [flags] tasks.yaml",
+}
+
+func init() { batchCmd.Run = runBatch }
+
+// defaultBatchJobs is how many tasks run at the same time when neither the
+// task file nor --jobs say.
+const defaultBatchJobs = 4
+
+// Statuses of a task in the summary, besides those of batch.
+const (
+	statusSkipped = "skipped"
+	statusPending = "not run"
+	statusPlanned = "would run"
+)
+
+// taskResult is the outcome of one task in this run.
+type taskResult struct {
+	Name   string   `json:"name"`
+	Kind   string   `json:"kind"`
+	Status string   `json:"status"`
+	Error  string   `json:"error,omitempty"`
+	Files  []string `json:"files,omitempty"`
+	Output string   `json:"output,omitempty"`
+	Usage  ai.Usage `json:"usage"`
+}
+
+func runBatch(ctx context.Context, env *Env, args []string) error {
+	fs := env.flags(batchCmd)
+	jobs := fs.Int("jobs", 0, fmt.Sprintf("run this many tasks at the same time (default: jobs in the task file, or %d)", defaultBatchJobs))
+	resume := fs.Bool("resume", false, "only run the tasks that failed, did not run or changed since the last run")
+	statePath := fs.String("state", "", "file keeping the outcome of each task (default: tasks.state.json next to tasks.yaml)")
+	dryRun := fs.Bool("dry-run", false, "list the tasks and their files without running them")
+	var only fileList
+	fs.Var(&only, "only", "only run the tasks with these names")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		fs.Usage()
+		return usageErrorf("batch takes one task file")
+	}
+	if *jobs < 0 {
+		fs.Usage()
+		return usageErrorf("--jobs must be at least 1")
+	}
+
+	spec, err := batch.Load(args[0])
+	if err != nil {
+		return err
+	}
+	if err := checkTargets(spec); err != nil {
+		return err
+	}
+	if *statePath == "" {
+		*statePath = batch.StatePath(spec.Path)
+	}
+	state, err := batch.LoadState(*statePath)
+	if err != nil {
+		return err
+	}
+	workers := *jobs
+	if workers == 0 {
+		workers = spec.Jobs
+	}
+	if workers == 0 {
+		workers = defaultBatchJobs
+	}
+
+	selected, unknown := map[string]bool{}, map[string]bool{}
+	for _, name := range only {
+		selected[name], unknown[name] = true, true
+	}
+	results := make([]taskResult, len(spec.Tasks))
+	var todo []int
+	for i, t := range spec.Tasks {
+		results[i] = taskResult{Name: t.Name, Kind: t.Kind(), Status: statusPending}
+		if len(selected) > 0 && !selected[t.Name] {
+			results[i].Status = statusSkipped
+			continue
+		}
+		delete(unknown, t.Name)
+		if *resume && state.Done(t) {
+			results[i].Status = statusSkipped
+			continue
+		}
+		todo = append(todo, i)
+	}
+	for name := range unknown {
+		return usageErrorf("no task named '%s' in %s", name, spec.Path)
+	}
+
+	if *dryRun {
+		return env.planBatch(spec, results)
+	}
+	if len(todo) > 0 {
+		if err := env.connect(); err != nil {
+			return err
+		}
+	}
+	env.progress("Running %d of %d task(s), %d at a time, with %s", len(todo), len(spec.Tasks), workers, ai.Model)
+
+	var mu sync.Mutex
+	forEach(ctx, workers, len(todo), func(n int) {
+		i := todo[n]
+		t := spec.Tasks[i]
+		env.progress("%s: started", t.Name)
+		res := env.runTask(ctx, spec.Dir, t)
+		if ctx.Err() != nil {
+			// An interrupted task stays to be run again.
+			return
+		}
+		if res.Status == batch.StatusOK {
+			env.progress("%s: done", t.Name)
+		} else {
+			env.progress("%s: failed: %s", t.Name, res.Error)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		results[i] = res
+		state.Tasks[t.Name] = batch.TaskState{Status: res.Status, Hash: t.Hash(), Error: res.Error, Tokens: res.Usage.Total, Files: res.Files, Finished: time.Now()}
+		if err := state.Save(*statePath); err != nil {
+			env.progress("%v", err)
+		}
+	})
+
+	if err := env.summarizeBatch(results); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	failed := 0
+	for _, res := range results {
+		if res.Status == batch.StatusFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d task(s) failed; run again with --resume to retry them", failed, len(todo))
+	}
+	return nil
+}
+
+// checkTargets rejects a task file where two tasks write the same file:
+// tasks run at the same time, so one would overwrite the other.
+func checkTargets(spec *batch.Spec) error {
+	owners := map[string]string{}
+	for _, t := range spec.Tasks {
+		var targets []string
+		switch t.Kind() {
+		case batch.KindGenerate:
+			targets = []string{resolve(spec.Dir, t.Generate)}
+		case batch.KindModify:
+			// Patterns that match nothing make the task fail when it runs.
+			targets, _ = expandPaths(spec.Dir, t.Modify, false)
+		case batch.KindAnalyze:
+			if t.Output != "" {
+				targets = []string{resolve(spec.Dir, t.Output)}
+			}
+		}
+		for _, path := range targets {
+			if real, err := resolvePath(path); err == nil {
+				path = real
+			}
+			if owner, ok := owners[path]; ok && owner != t.Name {
+				return fmt.Errorf("%s: tasks '%s' and '%s' both write '%s'; merge them or move one to another task file", spec.Path, owner, t.Name, path)
+			}
+			owners[path] = t.Name
+		}
+	}
+	return nil
+}
+
+// runTask runs one task, its paths relative to dir.
+func (e *Env) runTask(ctx context.Context, dir string, t batch.Task) taskResult {
+	res := taskResult{Name: t.Name, Kind: t.Kind(), Status: batch.StatusOK}
+	var err error
+	switch t.Kind() {
+	case batch.KindGenerate:
+		err = e.generateTask(ctx, dir, t, &res)
+	case batch.KindModify:
+		err = e.modifyTask(ctx, dir, t, &res)
+	case batch.KindAnalyze:
+		err = e.analyzeTask(ctx, dir, t, &res)
+	}
+	if err != nil {
+		res.Status = batch.StatusFailed
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// taskContext reads the context files of a task.
+func (e *Env) taskContext(dir string, t batch.Task) (string, error) {
+	paths, err := expandPaths(dir, t.Context, false)
+	if err != nil {
+		return "", err
+	}
+	files, err := e.collect(paths)
+	if err != nil {
+		return "", err
+	}
+	return prompts.Files(files), nil
+}
+
+func (e *Env) generateTask(ctx context.Context, dir string, t batch.Task, res *taskResult) error {
+	path := resolve(dir, t.Generate)
+	if _, err := os.Stat(path); err == nil && !t.Force {
+		return fmt.Errorf("'%s' already exists; set force: true to overwrite it", path)
+	}
+	refs, err := e.taskContext(dir, t)
+	if err != nil {
+		return err
+	}
+	reply, err := e.Generate(ctx, prompts.Generate(path, t.Instruction, refs))
+	res.Usage = reply.Usage
+	if err != nil {
+		return err
+	}
+	if err := e.writeFile(path, []byte(prompts.Unfence(reply.Text)), t.Instruction); err != nil {
+		return err
+	}
+	res.Files = []string{path}
+	return nil
+}
+
+// modifyTask rewrites every file of the task, or none if one of them fails.
+func (e *Env) modifyTask(ctx context.Context, dir string, t batch.Task, res *taskResult) error {
+	targets, err := expandPaths(dir, t.Modify, false)
+	if err != nil {
+		return err
+	}
+	if err := regularFiles(targets); err != nil {
+		return err
+	}
+	refs, err := e.taskContext(dir, t)
+	if err != nil {
+		return err
+	}
+	edits := make([]editResult, len(targets))
+	for i, path := range targets {
+		e.progress("%s: [%d/%d] %s", t.Name, i+1, len(targets), path)
+		edits[i] = editFile(ctx, e, path, t.Instruction, refs)
+		addUsage(&res.Usage, edits[i].Usage)
+		if edits[i].Error != "" {
+			return fmt.Errorf("%s: %s; nothing was written", path, edits[i].Error)
+		}
+	}
+	if err := e.applyEdits(edits, t.Instruction); err != nil {
+		return err
+	}
+	for _, edit := range edits {
+		if edit.Written {
+			res.Files = append(res.Files, edit.Path)
+		}
+	}
+	return nil
+}
+
+// analyzeTask answers the question of the task about its files, writing
+// the answer to the output file if there is one.
+func (e *Env) analyzeTask(ctx context.Context, dir string, t batch.Task, res *taskResult) error {
+	paths, err := expandPaths(dir, t.Analyze, false)
+	if err != nil {
+		return err
+	}
+	files, err := e.collect(paths)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files to analyze")
+	}
+	prompt := prompts.Analyze(prompts.Files(files), t.Question)
+	if refs, err := e.taskContext(dir, t); err != nil {
+		return err
+	} else if refs != "" {
+		prompt += "\n\n--- REFERENCE FILES ---\n" + refs
+	}
+	reply, err := e.Generate(ctx, prompt)
+	res.Usage = reply.Usage
+	if err != nil {
+		return err
+	}
+	if t.Output == "" {
+		res.Output = reply.Text
+		return nil
+	}
+	path := resolve(dir, t.Output)
+	if err := e.writeFile(path, []byte(strings.TrimRight(reply.Text, "\n")+"\n"), t.Question); err != nil {
+		return err
+	}
+	res.Files = []string{path}
+	return nil
+}
+
+// planBatch shows what a run would do.
+func (e *Env) planBatch(spec *batch.Spec, results []taskResult) error {
+	for i, t := range spec.Tasks {
+		var patterns []string
+		switch t.Kind() {
+		case batch.KindGenerate:
+			patterns = []string{t.Generate}
+		case batch.KindModify:
+			patterns = t.Modify
+		case batch.KindAnalyze:
+			patterns = t.Analyze
+		}
+		files := patterns
+		if t.Kind() != batch.KindGenerate {
+			expanded, err := expandPaths(spec.Dir, patterns, false)
+			if err != nil {
+				results[i].Status = batch.StatusFailed
+				results[i].Error = err.Error()
+			}
+			files = expanded
+		}
+		if results[i].Status == statusPending {
+			results[i].Status = statusPlanned
+		}
+		results[i].Files = files
+	}
+	return e.summarizeBatch(results)
+}
+
+// summarizeBatch writes the results as a table, or as JSON with --json.
+func (e *Env) summarizeBatch(results []taskResult) error {
+	var total ai.Usage
+	counts := map[string]int{}
+	files := 0
+	for _, res := range results {
+		addUsage(&total, res.Usage)
+		counts[res.Status]++
+		files += len(res.Files)
+	}
+	if e.JSON {
+		return e.writeJSON(map[string]any{"command": batchCmd.Name, "model": ai.Model, "tasks": results, "usage": total})
+	}
+
+	var sb strings.Builder
+	for _, res := range results {
+		if res.Output != "" {
+			fmt.Fprintf(&sb, "=== %s ===\n%s\n\n", res.Name, strings.TrimRight(res.Output, "\n"))
+		}
+	}
+	nameWidth := len("TASK")
+	for _, res := range results {
+		nameWidth = max(nameWidth, len(res.Name))
+	}
+	row := func(name, kind, status, tokens, files, detail string) {
+		line := fmt.Sprintf("%-*s  %-8s  %-9s  %7s  %5s  %s", nameWidth, name, kind, status, tokens, files, detail)
+		sb.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	row("TASK", "KIND", "STATUS", "TOKENS", "FILES", "DETAIL")
+	for _, res := range results {
+		detail := res.Error
+		if detail == "" && len(res.Files) > 0 {
+			detail = strings.Join(res.Files, ", ")
+		}
+		row(res.Name, res.Kind, res.Status, fmt.Sprint(res.Usage.Total), fmt.Sprint(len(res.Files)), detail)
+	}
+	var parts []string
+	for _, status := range []string{batch.StatusOK, batch.StatusFailed, statusSkipped, statusPending, statusPlanned} {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	summary := strings.Join(parts, ", ")
+	if counts[statusPlanned] == 0 {
+		summary += fmt.Sprintf(" · %d tokens · %d file(s) changed", total.Total, files)
+	}
+	fmt.Fprintf(&sb, "\n%s\n", summary)
+	_, err := e.Stdout.Write([]byte(sb.String()))
+	return err
+}
+
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, filepath.FromSlash(path))
+}
+
+func addUsage(total *ai.Usage, u ai.Usage) {
+	total.Prompt += u.Prompt
+	total.Output += u.Output
+	total.Total += u.Total
+}
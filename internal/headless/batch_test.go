@@ -0,0 +1,107 @@
+package headless
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthonycursewl/anx-agent/internal/batch"
+)
+
+const batchSpec = `tasks:
+  - name: gen
+    generate: out/new.go
+    instruction: write it
+  - name: ask
+    analyze: src/a.go
+    question: what does it do?
+`
+
+func TestBatchResumeOnlySucceededTask(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "src", "a.go"), "package a\n")
+	spec := filepath.Join(dir, "tasks.yaml")
+	writeFile(t, spec, batchSpec)
+
+	g := reply("package out")
+	env, _, _ := newEnv(t, g)
+	if err := runBatch(context.Background(), env, []string{spec}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(dir, "out", "new.go")); got != "package out" {
+		t.Errorf("generated %q", got)
+	}
+	if entries := history(t, env); len(entries) != 1 || entries[0].Prompt != "write it" {
+		t.Errorf("the generated file is not in the history: %+v", entries)
+	}
+	state, err := batch.LoadState(batch.StatePath(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Tasks["gen"].Status != batch.StatusOK || state.Tasks["ask"].Status != batch.StatusOK {
+		t.Fatalf("unexpected state %+v", state)
+	}
+
+	// A selected task that already succeeded is skipped, not unknown.
+	calls := len(g.prompts)
+	env, stdout, _ := newEnv(t, g)
+	if err := runBatch(context.Background(), env, []string{"--resume", "--only", "gen", spec}); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.prompts) != calls {
+		t.Error("a task that already succeeded ran again")
+	}
+	if !strings.Contains(stdout.String(), "gen") {
+		t.Errorf("the summary does not list the task:\n%s", stdout)
+	}
+
+	env, _, _ = newEnv(t, g)
+	err = runBatch(context.Background(), env, []string{"--resume", "--only", "nope", spec})
+	if ExitCode(err) != ExitUsage || !strings.Contains(err.Error(), "no task named 'nope'") {
+		t.Errorf("got %v, want a usage error about 'nope'", err)
+	}
+}
+
+func TestBatchRejectsOverlappingTargets(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"same generated file", `tasks:
+  - name: one
+    generate: out/new.go
+    instruction: write it
+  - name: two
+    generate: ./out/../out/new.go
+    instruction: write it again
+`},
+		{"output over a modified file", `tasks:
+  - name: rewrite
+    modify: "src/*.go"
+    instruction: rewrite it
+  - name: explain
+    analyze: src/a.go
+    question: what does it do?
+    output: src/a.go
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "src", "a.go"), "package a\n")
+			spec := filepath.Join(dir, "tasks.yaml")
+			writeFile(t, spec, tt.spec)
+
+			g := reply("package out")
+			env, _, _ := newEnv(t, g)
+			err := runBatch(context.Background(), env, []string{spec})
+			if err == nil || !strings.Contains(err.Error(), "both write") {
+				t.Fatalf("got %v, want an error about the overlap", err)
+			}
+			if len(g.prompts) != 0 {
+				t.Error("tasks ran despite the overlap")
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package headless
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/diff"
+	"github.com/anthonycursewl/anx-agent/internal/git"
+	"github.com/anthonycursewl/anx-agent/internal/prompts"
+)
+
+var describeCmd = &Command{
+	Name:    "describe",
+	Summary: "Draft a conventional commit message, or a pull request description, for the staged changes, a revision range or a piped diff",
+	Usage:   "describe [flags] [--staged | --range rev]",
+}
+
+func init() { describeCmd.Run = runDescribe }
+
+// describeDiffTokens bounds the part of the diff sent to draft a
+// description; the list of changed files is always sent whole.
+const describeDiffTokens = 24000
+
+func runDescribe(ctx context.Context, env *Env, args []string) error {
+	fs := env.flags(describeCmd)
+	staged := fs.Bool("staged", false, "describe the staged changes (the default unless a diff is piped)")
+	rev := fs.String("range", "", "describe the changes of a git revision range, e.g. main..HEAD")
+	pr := fs.Bool("pr", false, "write a pull request description instead of a commit message")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		fs.Usage()
+		return usageErrorf("describe takes no arguments; use --range to name the commits")
+	}
+	if *staged && *rev != "" {
+		fs.Usage()
+		return usageErrorf("--staged and --range cannot be combined")
+	}
+
+	var text, stat string
+	var commits []string
+	if !*staged && *rev == "" && env.StdinPiped {
+		if text, err = env.readStdin(); err != nil {
+			return err
+		}
+	} else {
+		if text, err = git.Diff(".", *rev, 3); err != nil {
+			return err
+		}
+		if stat, err = git.DiffStat(".", *rev); err != nil {
+			return err
+		}
+		if *pr && *rev != "" {
+			if commits, err = git.Log(".", *rev); err != nil {
+				return err
+			}
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		if *rev != "" {
+			return fmt.Errorf("no changes in %s", *rev)
+		}
+		return fmt.Errorf("no changes to describe; stage them with git add, or use --range")
+	}
+
+	what, prompt := "commit message", prompts.CommitMessage(stat, truncateDiff(text, describeDiffTokens))
+	if *pr {
+		what, prompt = "pull request description", prompts.PullRequest(commits, stat, truncateDiff(text, describeDiffTokens))
+	}
+	env.progress("Drafting a %s with %s...", what, ai.Model)
+	reply, err := env.Generate(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	reply.Text = strings.TrimSpace(prompts.Unfence(reply.Text))
+	return env.answer(describeCmd.Name, reply, nil)
+}
+
+// truncateDiff keeps the lines of text that fit in maxTokens.
+func truncateDiff(text string, maxTokens int) string {
+	if ai.EstimateTokens(text) <= maxTokens {
+		return text
+	}
+	var sb strings.Builder
+	tokens := 0
+	for _, line := range diff.SplitLines(text) {
+		tokens += ai.EstimateTokens(line)
+		if tokens > maxTokens {
+			sb.WriteString("\n[The diff is cut short here; the list of changed files covers the rest.]\n")
+			break
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
@@ -0,0 +1,61 @@
+package headless
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDescribeStagedChanges(t *testing.T) {
+	dir := newRepo(t)
+	writeFile(t, filepath.Join(dir, "a.txt"), "two\n")
+	gitRun(t, dir, "add", "a.txt")
+
+	g := reply("```\nfix: change a\n```")
+	env, stdout, _ := newEnv(t, g)
+	if err := runDescribe(context.Background(), env, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "fix: change a\n" {
+		t.Errorf("output = %q, want the message without its fence", got)
+	}
+	if len(g.prompts) != 1 || !strings.Contains(g.prompts[0], "+two") || !strings.Contains(g.prompts[0], "a.txt | ") {
+		t.Errorf("the prompt lacks the diff or its stat: %q", g.prompts)
+	}
+}
+
+func TestDescribePullRequest(t *testing.T) {
+	dir := newRepo(t)
+	writeFile(t, filepath.Join(dir, "b.txt"), "b\n")
+	gitRun(t, dir, "add", "b.txt")
+	gitRun(t, dir, "commit", "-q", "-m", "add b")
+
+	g := reply("Adds b.")
+	env, stdout, _ := newEnv(t, g)
+	if err := runDescribe(context.Background(), env, []string{"--pr", "--range", "HEAD~1..HEAD"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "Adds b.\n" {
+		t.Errorf("output = %q", got)
+	}
+	if len(g.prompts) != 1 || !strings.Contains(g.prompts[0], "add b") || !strings.Contains(g.prompts[0], "+b") {
+		t.Errorf("the prompt lacks the commits or the diff: %q", g.prompts)
+	}
+}
+
+func TestDescribeWithoutChanges(t *testing.T) {
+	newRepo(t)
+	g := reply("unused")
+	env, _, _ := newEnv(t, g)
+	err := runDescribe(context.Background(), env, nil)
+	if err == nil || !strings.Contains(err.Error(), "no changes to describe") {
+		t.Errorf("got error %v, want no changes to describe", err)
+	}
+	if len(g.prompts) != 0 {
+		t.Error("the model was asked without changes")
+	}
+	if err := runDescribe(context.Background(), env, []string{"--staged", "--range", "HEAD"}); ExitCode(err) != ExitUsage {
+		t.Errorf("--staged with --range: got %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,456 @@
+package headless
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/diff"
+	"github.com/anthonycursewl/anx-agent/internal/ignore"
+	"github.com/anthonycursewl/anx-agent/internal/journal"
+	"github.com/anthonycursewl/anx-agent/internal/prompts"
+	"github.com/anthonycursewl/anx-agent/internal/utils"
+)
+
+var editCmd = &Command{
+	Name:    "edit",
+	Summary: "Rewrite files following an instruction and show the changes as a diff",
+	Usage:   "edit [flags] -i instruction [file|glob...]",
+}
+
+func init() { editCmd.Run = runEdit }
+
+// editResult is the outcome of editing one file.
+type editResult struct {
+	Path    string   `json:"path"`
+	Changed bool     `json:"changed"`
+	Written bool     `json:"written"`
+	Skipped string   `json:"skipped,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Diff    string   `json:"diff,omitempty"`
+	Usage   ai.Usage `json:"usage"`
+
+	original []byte
+	content  string
+	mode     os.FileMode
+}
+
+func runEdit(ctx context.Context, env *Env, args []string) error {
+	fs := env.flags(editCmd)
+	instruction := fs.String("instruction", "", "what to change")
+	fs.StringVar(instruction, "i", "", "shorthand for --instruction")
+	write := fs.Bool("write", false, "write the changes, all of them or none, instead of only showing them")
+	jobs := fs.Int("jobs", 1, "edit this many files at the same time")
+	filesFrom := fs.String("files-from", "", "read the files to edit from this file, one per line; - for standard input")
+	all := fs.Bool("all", false, "let glob patterns match files ignored by .gitignore and .anxignore")
+	var reference fileList
+	fs.Var(&reference, "context", "file or directory to share as reference; can be repeated")
+	fs.Var(&reference, "c", "shorthand for --context")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if *instruction == "" {
+		fs.Usage()
+		return usageErrorf("an instruction is required (-i)")
+	}
+	if *jobs < 1 {
+		fs.Usage()
+		return usageErrorf("--jobs must be at least 1")
+	}
+	shared, err := env.collect(reference)
+	if err != nil {
+		return err
+	}
+	refs := prompts.Files(shared)
+
+	if len(args) == 0 && *filesFrom == "" {
+		if !env.StdinPiped {
+			fs.Usage()
+			return usageErrorf("give the files to edit, or pipe the content in")
+		}
+		return editStdin(ctx, env, *instruction, refs)
+	}
+	targets, err := editTargets(env, args, *filesFrom, *all)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no files to edit")
+	}
+
+	if err := env.connect(); err != nil {
+		return err
+	}
+	env.progress("Editing %d file(s) with %s...", len(targets), ai.Model)
+	results := editFiles(ctx, env, targets, *instruction, refs, *jobs)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, res := range results {
+		if res.Error != "" {
+			failed++
+			fmt.Fprintf(env.Stderr, "anx-agent edit: %s: %s\n", res.Path, res.Error)
+		}
+	}
+	var writeErr error
+	switch {
+	case failed > 0 && *write:
+		writeErr = fmt.Errorf("%d of %d file(s) failed; nothing was written", failed, len(results))
+	case failed > 0:
+		writeErr = fmt.Errorf("%d of %d file(s) failed", failed, len(results))
+	case *write:
+		writeErr = env.applyEdits(results, *instruction)
+	}
+
+	if env.JSON {
+		if err := env.writeJSON(map[string]any{"command": editCmd.Name, "model": ai.Model, "files": results}); err != nil {
+			return err
+		}
+	} else {
+		for _, res := range results {
+			io.WriteString(env.Stdout, res.Diff)
+		}
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	changed, written := 0, 0
+	for _, res := range results {
+		if res.Changed {
+			changed++
+		}
+		if res.Written {
+			written++
+		}
+	}
+	if *write {
+		env.progress("Wrote %d of %d file(s).", written, len(results))
+	} else {
+		env.progress("%d of %d file(s) would change; use --write to apply.", changed, len(results))
+	}
+	return nil
+}
+
+// editTargets lists the files named by args, expanding glob patterns below
+// the current directory, and those listed in filesFrom.
+func editTargets(env *Env, args []string, filesFrom string, all bool) ([]string, error) {
+	if filesFrom != "" {
+		list, err := env.readInput(filesFrom)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(strings.NewReader(list))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				args = append(args, line)
+			}
+		}
+	}
+
+	targets, err := expandPaths(".", args, all)
+	if err != nil {
+		return nil, err
+	}
+	return targets, regularFiles(targets)
+}
+
+// expandPaths resolves paths and glob patterns against dir. Patterns match
+// the files below dir that are not ignored, unless all is set, and must
+// match at least one.
+func expandPaths(dir string, patterns []string, all bool) ([]string, error) {
+	var paths []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			found, err := ignore.Glob(dir, pattern, all)
+			if err != nil {
+				return nil, err
+			}
+			if len(found) == 0 {
+				return nil, fmt.Errorf("no files match '%s'", pattern)
+			}
+			matches = found
+		}
+		for _, path := range matches {
+			path = filepath.FromSlash(path)
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			if path = filepath.Clean(path); !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// regularFiles checks that paths name existing files rather than
+// directories.
+func regularFiles(paths []string) error {
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("error accessing '%s': %w", path, err)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("'%s' is a directory; use a pattern such as '%s/**/*.go'", path, filepath.ToSlash(path))
+		}
+	}
+	return nil
+}
+
+// editFiles edits targets with at most jobs requests at a time. The results
+// are in the order of targets; a file that failed has its Error set.
+func editFiles(ctx context.Context, env *Env, targets []string, instruction, refs string, jobs int) []editResult {
+	results := make([]editResult, len(targets))
+	forEach(ctx, jobs, len(targets), func(i int) {
+		env.progress("[%d/%d] %s", i+1, len(targets), targets[i])
+		results[i] = editFile(ctx, env, targets[i], instruction, refs)
+	})
+	return results
+}
+
+func editFile(ctx context.Context, env *Env, path, instruction, refs string) editResult {
+	res := editResult{Path: path}
+	fail := func(err error) editResult {
+		res.Error = err.Error()
+		return res
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fail(fmt.Errorf("error accessing file: %w", err))
+	}
+	res.mode = info.Mode().Perm()
+	if res.original, err = os.ReadFile(path); err != nil {
+		return fail(fmt.Errorf("error reading file: %w", err))
+	}
+	if utils.IsBinary(res.original) {
+		res.Skipped = "binary"
+		env.progress("Skipping binary file %s", path)
+		return res
+	}
+
+	reply, err := env.Generate(ctx, prompts.Modify(string(res.original), instruction, refs))
+	if err != nil {
+		return fail(err)
+	}
+	res.Usage = reply.Usage
+	res.content = prompts.Unfence(reply.Text)
+	res.Diff = diff.Unified(diffName("a", path), diffName("b", path), string(res.original), res.content, 3)
+	res.Changed = res.Diff != ""
+	return res
+}
+
+// rename replaces files; tests make it fail.
+var rename = os.Rename
+
+// applyEdits writes every changed file, or none of them. The new contents
+// are written to temporary files next to the originals, which replace them
+// only once all were written; a file that changed on disk while it was
+// being edited stops the whole write. Symlinks are followed, so that the
+// file they point to is replaced rather than the link. Each write is
+// recorded in the journal with prompt, and only once all succeeded.
+func (e *Env) applyEdits(results []editResult, prompt string) error {
+	type staged struct {
+		res   *editResult
+		path  string
+		tmp   string
+		entry journal.Entry
+	}
+	var pending []staged
+	discard := func() {
+		for _, s := range pending {
+			os.Remove(s.tmp)
+			e.Journal.Abort(s.entry)
+		}
+	}
+	for i := range results {
+		res := &results[i]
+		if !res.Changed {
+			continue
+		}
+		path, err := resolvePath(res.Path)
+		if err != nil {
+			discard()
+			return fmt.Errorf("%w; nothing was written", err)
+		}
+		current, err := os.ReadFile(path)
+		if err != nil {
+			discard()
+			return fmt.Errorf("error reading '%s': %w; nothing was written", res.Path, err)
+		}
+		if !bytes.Equal(current, res.original) {
+			discard()
+			return fmt.Errorf("'%s' changed while it was being edited; nothing was written", res.Path)
+		}
+		entry, err := e.Journal.Prepare(path, prompt)
+		if err != nil {
+			discard()
+			return fmt.Errorf("error recording '%s' in the history: %w; nothing was written", res.Path, err)
+		}
+		tmp, err := writeTemp(path, []byte(res.content), res.mode)
+		if err != nil {
+			e.Journal.Abort(entry)
+			discard()
+			return fmt.Errorf("%w; nothing was written", err)
+		}
+		pending = append(pending, staged{res: res, path: path, tmp: tmp, entry: entry})
+	}
+
+	for i, s := range pending {
+		if err := rename(s.tmp, s.path); err != nil {
+			for _, rest := range pending[i:] {
+				os.Remove(rest.tmp)
+				e.Journal.Abort(rest.entry)
+			}
+			// Put back the files already replaced. A file that cannot be
+			// put back keeps its entry, so that undo can still restore it.
+			for _, done := range pending[:i] {
+				if tmp, err := writeTemp(done.path, done.res.original, done.res.mode); err == nil {
+					if rename(tmp, done.path) == nil {
+						done.res.Written = false
+					} else {
+						os.Remove(tmp)
+					}
+				}
+				if done.res.Written {
+					e.Journal.Commit(done.entry)
+				} else {
+					e.Journal.Abort(done.entry)
+				}
+			}
+			return fmt.Errorf("error replacing '%s': %w; the files already written were restored", s.res.Path, err)
+		}
+		s.res.Written = true
+	}
+	var commitErr error
+	for _, s := range pending {
+		if err := e.Journal.Commit(s.entry); err != nil && commitErr == nil {
+			commitErr = fmt.Errorf("'%s' was written but not recorded in the history: %w", s.res.Path, err)
+		}
+	}
+	return commitErr
+}
+
+// writeTemp writes content to a new hidden file in the directory of path,
+// ready to be renamed over it.
+func writeTemp(path string, content []byte, mode os.FileMode) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".anx-*")
+	if err != nil {
+		return "", fmt.Errorf("error creating a temporary file for '%s': %w", path, err)
+	}
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), mode)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("error writing a temporary file for '%s': %w", path, err)
+	}
+	return f.Name(), nil
+}
+
+// replaceFile writes content to path through a temporary file, creating the
+// missing directories, so that path never holds a partial write.
+func replaceFile(path string, content []byte) error {
+	path, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating the directory of '%s': %w", path, err)
+	}
+	tmp, err := writeTemp(path, content, mode)
+	if err != nil {
+		return err
+	}
+	if err := rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing '%s': %w", path, err)
+	}
+	return nil
+}
+
+// writeFile replaces path like replaceFile and records the write in the
+// journal, so that it can be undone.
+func (e *Env) writeFile(path string, content []byte, prompt string) error {
+	real, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+	entry, err := e.Journal.Prepare(real, prompt)
+	if err != nil {
+		return fmt.Errorf("error recording '%s' in the history: %w", path, err)
+	}
+	if err := replaceFile(real, content); err != nil {
+		e.Journal.Abort(entry)
+		return err
+	}
+	return e.Journal.Commit(entry)
+}
+
+// resolvePath follows the symlinks of path, which does not need to exist
+// yet. A symlink to a missing file is refused rather than replaced.
+func resolvePath(path string) (string, error) {
+	real, err := filepath.EvalSymlinks(path)
+	switch {
+	case err == nil:
+		return real, nil
+	case !os.IsNotExist(err):
+		return "", fmt.Errorf("error resolving '%s': %w", path, err)
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("'%s' is a symlink to a missing file", path)
+	}
+	return path, nil
+}
+
+// editStdin rewrites what was piped in and writes the result to standard
+// output, as a filter.
+func editStdin(ctx context.Context, env *Env, instruction, refs string) error {
+	original, err := env.readStdin()
+	if err != nil {
+		return err
+	}
+	env.progress("Editing %s with %s...", stdinName, ai.Model)
+	reply, err := env.Generate(ctx, prompts.Modify(original, instruction, refs))
+	if err != nil {
+		return err
+	}
+	content := prompts.Unfence(reply.Text)
+	if env.JSON {
+		return env.writeJSON(result{Command: editCmd.Name, Model: ai.Model, Text: content, Usage: reply.Usage})
+	}
+	_, err = io.WriteString(env.Stdout, content)
+	return err
+}
+
+// diffName prefixes relative paths the way git does, so that the diffs can
+// be applied with 'git apply'.
+func diffName(prefix, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.ToSlash(path)
+	}
+	return prefix + "/" + filepath.ToSlash(filepath.Clean(path))
+}
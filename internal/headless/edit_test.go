@@ -0,0 +1,193 @@
+package headless
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthonycursewl/anx-agent/internal/journal"
+)
+
+// edited is the result of editing path, read now, into content.
+func edited(t *testing.T, path, content string) editResult {
+	t.Helper()
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return editResult{Path: path, Changed: true, original: original, content: content, mode: info.Mode().Perm()}
+}
+
+// leftovers lists the temporary files left in dir.
+func leftovers(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, ".*.anx-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+// history returns the journal entries of env.
+func history(t *testing.T, env *Env) []journal.Entry {
+	t.Helper()
+	entries, err := env.Journal.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestApplyEdits(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	writeFile(t, a, "a\n")
+	writeFile(t, b, "b\n")
+	if err := os.Chmod(b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	results := []editResult{edited(t, a, "A\n"), {Path: filepath.Join(dir, "same.txt")}, edited(t, b, "B\n")}
+	env, _, _ := newEnv(t, reply(""))
+	if err := env.applyEdits(results, "capitalize"); err != nil {
+		t.Fatal(err)
+	}
+	if readFile(t, a) != "A\n" || readFile(t, b) != "B\n" {
+		t.Errorf("contents %q, %q", readFile(t, a), readFile(t, b))
+	}
+	if !results[0].Written || results[1].Written || !results[2].Written {
+		t.Errorf("unexpected Written flags in %+v", results)
+	}
+	if info, err := os.Stat(b); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode of b.txt: %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+	if left := leftovers(t, dir); len(left) != 0 {
+		t.Errorf("temporary files left: %q", left)
+	}
+
+	// Each write is recorded and can be undone.
+	if entries := history(t, env); len(entries) != 2 || entries[0].Prompt != "capitalize" {
+		t.Fatalf("unexpected history %+v", entries)
+	}
+	for range 2 {
+		if _, err := env.Journal.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if readFile(t, a) != "a\n" || readFile(t, b) != "b\n" {
+		t.Errorf("undo restored %q, %q", readFile(t, a), readFile(t, b))
+	}
+}
+
+func TestApplyEditsStopsOnAConflict(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	writeFile(t, a, "a\n")
+	writeFile(t, b, "b\n")
+	results := []editResult{edited(t, a, "A\n"), edited(t, b, "B\n")}
+	writeFile(t, b, "changed meanwhile\n")
+
+	env, _, _ := newEnv(t, reply(""))
+	err := env.applyEdits(results, "capitalize")
+	if err == nil || !strings.Contains(err.Error(), "changed while it was being edited") {
+		t.Fatalf("got %v, want a conflict on b.txt", err)
+	}
+	if readFile(t, a) != "a\n" || readFile(t, b) != "changed meanwhile\n" {
+		t.Errorf("files were written: %q, %q", readFile(t, a), readFile(t, b))
+	}
+	if results[0].Written || results[1].Written {
+		t.Error("results are marked written")
+	}
+	if left := leftovers(t, dir); len(left) != 0 {
+		t.Errorf("temporary files left: %q", left)
+	}
+	if entries := history(t, env); len(entries) != 0 {
+		t.Errorf("nothing was written but the history has %+v", entries)
+	}
+}
+
+func TestApplyEditsRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	writeFile(t, a, "a\n")
+	writeFile(t, b, "b\n")
+	results := []editResult{edited(t, a, "A\n"), edited(t, b, "B\n")}
+
+	t.Cleanup(func() { rename = os.Rename })
+	rename = func(from, to string) error {
+		if filepath.Base(to) == "b.txt" {
+			return errors.New("disk full")
+		}
+		return os.Rename(from, to)
+	}
+	env, _, _ := newEnv(t, reply(""))
+	err := env.applyEdits(results, "capitalize")
+	if err == nil || !strings.Contains(err.Error(), "restored") {
+		t.Fatalf("got %v, want the error on b.txt", err)
+	}
+	if readFile(t, a) != "a\n" || readFile(t, b) != "b\n" {
+		t.Errorf("files not restored: %q, %q", readFile(t, a), readFile(t, b))
+	}
+	if results[0].Written || results[1].Written {
+		t.Errorf("results are marked written: %+v", results)
+	}
+	if left := leftovers(t, dir); len(left) != 0 {
+		t.Errorf("temporary files left: %q", left)
+	}
+	if entries := history(t, env); len(entries) != 0 {
+		t.Errorf("the files were restored but the history has %+v", entries)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(env.Journal.Dir(), "*")); len(backups) != 0 {
+		t.Errorf("backups left in the journal: %q", backups)
+	}
+}
+
+func TestEditsFollowSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	real, link := filepath.Join(dir, "real.txt"), filepath.Join(dir, "link.txt")
+	writeFile(t, real, "old\n")
+	if err := os.Symlink("real.txt", link); err != nil {
+		t.Skip("symlinks are not supported:", err)
+	}
+
+	env, _, _ := newEnv(t, reply(""))
+	if err := env.applyEdits([]editResult{edited(t, link, "edited\n")}, "edit"); err != nil {
+		t.Fatal(err)
+	}
+	assertLink(t, link, real, "edited\n")
+
+	if err := replaceFile(link, []byte("replaced\n")); err != nil {
+		t.Fatal(err)
+	}
+	assertLink(t, link, real, "replaced\n")
+
+	dangling := filepath.Join(dir, "dangling.txt")
+	if err := os.Symlink("missing.txt", dangling); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceFile(dangling, []byte("x\n")); err == nil {
+		t.Error("replaceFile replaced a symlink to a missing file")
+	}
+	if info, err := os.Lstat(dangling); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("the dangling symlink was replaced")
+	}
+}
+
+func assertLink(t *testing.T, link, real, content string) {
+	t.Helper()
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Fatal("the symlink was replaced by a regular file")
+	}
+	if got := readFile(t, real); got != content {
+		t.Errorf("content of the target = %q, want %q", got, content)
+	}
+}
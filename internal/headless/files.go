@@ -0,0 +1,95 @@
+package headless
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/anthonycursewl/anx-agent/internal/ignore"
+	"github.com/anthonycursewl/anx-agent/internal/prompts"
+	"github.com/anthonycursewl/anx-agent/internal/utils"
+)
+
+// stdinName is the name piped input is shared with the model under.
+const stdinName = "<stdin>"
+
+// maxFileBytes is the size above which files found in directories are left
+// out; files named on the command line are always read.
+const maxFileBytes = 512 * 1024
+
+// collect reads the files named by paths. A directory contributes the files
+// under it that are not ignored, leaving out binary and very large files.
+func (e *Env) collect(paths []string) ([]prompts.File, error) {
+	var files []prompts.File
+	seen := map[string]bool{}
+	add := func(path string, named bool) error {
+		path = filepath.Clean(path)
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading file '%s': %w", path, err)
+		}
+		if utils.IsBinary(data) {
+			e.progress("Skipping binary file %s", path)
+			return nil
+		}
+		if !named && len(data) > maxFileBytes {
+			e.progress("Skipping %s (%s)", path, utils.FormatSize(int64(len(data))))
+			return nil
+		}
+		files = append(files, prompts.File{Path: path, Content: string(data)})
+		return nil
+	}
+
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("error accessing '%s': %w", p, err)
+		}
+		if !info.IsDir() {
+			if err := add(p, true); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = ignore.Walk(p, func(rel string, d fs.DirEntry) error {
+			if d.IsDir() {
+				return nil
+			}
+			return add(filepath.Join(p, filepath.FromSlash(rel)), false)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error walking '%s': %w", p, err)
+		}
+	}
+	return files, nil
+}
+
+// collectWithStdin is collect followed by what was piped to the command, if
+// anything.
+func (e *Env) collectWithStdin(paths []string) ([]prompts.File, error) {
+	files, err := e.collect(paths)
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := e.readStdin()
+	if err != nil {
+		return nil, err
+	}
+	if stdin != "" {
+		files = append(files, prompts.File{Path: stdinName, Content: stdin})
+	}
+	return files, nil
+}
+
+func paths(files []prompts.File) []string {
+	out := make([]string, len(files))
+	for i, f := range files {
+		out[i] = f.Path
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package headless
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/prompts"
+)
+
+var generateCmd = &Command{
+	Name:    "generate",
+	Summary: "Generate a new file from a description",
+	Usage:   "generate [flags] [-o file] description...",
+}
+
+func init() { generateCmd.Run = runGenerate }
+
+func runGenerate(ctx context.Context, env *Env, args []string) error {
+	fs := env.flags(generateCmd)
+	output := fs.String("output", "", "file to create; standard output when empty")
+	fs.StringVar(output, "o", "", "shorthand for --output")
+	force := fs.Bool("force", false, "overwrite the output file if it exists")
+	var files fileList
+	fs.Var(&files, "context", "file or directory to share as context; can be repeated")
+	fs.Var(&files, "c", "shorthand for --context")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	description := strings.TrimSpace(strings.Join(args, " "))
+	if description == "" {
+		fs.Usage()
+		return usageErrorf("a description of the file is required")
+	}
+	if *output != "" && !*force {
+		if _, err := os.Stat(*output); err == nil {
+			return fmt.Errorf("'%s' already exists; use --force to overwrite it or 'edit' to change it", *output)
+		}
+	}
+
+	shared, err := env.collectWithStdin(files)
+	if err != nil {
+		return err
+	}
+	name := *output
+	if name == "" {
+		name = "output"
+	}
+	env.progress("Generating %s with %s...", name, ai.Model)
+	reply, err := env.Generate(ctx, prompts.Generate(name, description, prompts.Files(shared)))
+	if err != nil {
+		return err
+	}
+	content := prompts.Unfence(reply.Text)
+
+	if *output == "" {
+		if env.JSON {
+			return env.writeJSON(result{Command: generateCmd.Name, Model: ai.Model, Text: content, Usage: reply.Usage, Files: paths(shared)})
+		}
+		return env.writeText(content)
+	}
+	if err := env.writeFile(*output, []byte(content), description); err != nil {
+		return err
+	}
+	env.progress("Wrote %s", *output)
+	if env.JSON {
+		return env.writeJSON(map[string]any{"command": generateCmd.Name, "model": ai.Model, "path": *output, "usage": reply.Usage, "files": paths(shared)})
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package headless
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateOutputCanBeUndone(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "pkg", "new.go")
+
+	env, _, _ := newEnv(t, reply("package pkg"))
+	if err := runGenerate(context.Background(), env, []string{"-o", out, "a", "package"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, out); got != "package pkg" {
+		t.Errorf("generated %q", got)
+	}
+
+	writeFile(t, out, "package old\n")
+	history := env.Journal
+	env, _, _ = newEnv(t, reply("package replaced"))
+	env.Journal = history
+	if err := runGenerate(context.Background(), env, []string{"--force", "-o", out, "again"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := env.Journal.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Prompt != "again" {
+		t.Fatalf("unexpected history %+v", entries)
+	}
+	if _, err := env.Journal.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, out); got != "package old\n" {
+		t.Errorf("undo restored %q, want the content before --force", got)
+	}
+	if left := leftovers(t, filepath.Dir(out)); len(left) != 0 {
+		t.Errorf("temporary files left: %q", left)
+	}
+}
@@ -0,0 +1,57 @@
+package headless
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/anthonycursewl/anx-agent/internal/diff"
+	"github.com/anthonycursewl/anx-agent/internal/git"
+)
+
+// defaultContextLines is how much unchanged code surrounds each change
+// taken from git, more than git's 3 so that the model sees what the change
+// is about.
+const defaultContextLines = 10
+
+// gitChanges reads the staged changes of the repository in the current
+// directory, or those of the revision range rev. The paths of the patches
+// are made relative to the current directory, so that findings can be
+// opened from there.
+func gitChanges(rev string, context int) (string, []diff.FilePatch, error) {
+	target := "staged changes"
+	if rev != "" {
+		target = rev
+	}
+	root, err := git.Root(".")
+	if err != nil {
+		return "", nil, err
+	}
+	text, err := git.Diff(".", rev, context)
+	if err != nil {
+		return "", nil, err
+	}
+	patches, err := diff.ParsePatch(text)
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing git diff: %w", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", nil, err
+	}
+	rel := func(path string) string {
+		if path == "" {
+			return ""
+		}
+		r, err := filepath.Rel(wd, filepath.Join(root, filepath.FromSlash(path)))
+		if err != nil {
+			return path
+		}
+		return filepath.ToSlash(r)
+	}
+	for i := range patches {
+		patches[i].OldPath = rel(patches[i].OldPath)
+		patches[i].NewPath = rel(patches[i].NewPath)
+	}
+	return target, patches, nil
+}
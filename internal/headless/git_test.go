@@ -0,0 +1,43 @@
+package headless
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGitChangesAreRelativeToTheCurrentDirectory(t *testing.T) {
+	dir := newRepo(t)
+	writeFile(t, filepath.Join(dir, "a.txt"), "two\n")
+	writeFile(t, filepath.Join(dir, "sub", "b.txt"), "b\n")
+	gitRun(t, dir, "add", ".")
+	t.Chdir(filepath.Join(dir, "sub"))
+
+	target, patches, err := gitChanges("", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "staged changes" {
+		t.Errorf("target = %q", target)
+	}
+	if len(patches) != 2 {
+		t.Fatalf("got %d patches, want 2", len(patches))
+	}
+	if p := patches[0]; p.OldPath != "../a.txt" || p.NewPath != "../a.txt" {
+		t.Errorf("paths of the change at the root: %q, %q", p.OldPath, p.NewPath)
+	}
+	if p := patches[1]; p.OldPath != "" || p.NewPath != "b.txt" {
+		t.Errorf("paths of the new file: %q, %q", p.OldPath, p.NewPath)
+	}
+
+	gitRun(t, dir, "commit", "-q", "-m", "second")
+	target, patches, err = gitChanges("HEAD~1..HEAD", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "HEAD~1..HEAD" || len(patches) != 2 {
+		t.Errorf("range: target %q with %d patches", target, len(patches))
+	}
+	if _, _, err := gitChanges("--output=x", 3); err == nil {
+		t.Error("an option was accepted as the revision range")
+	}
+}
@@ -0,0 +1,299 @@
+// Package headless runs the agent without the TUI, for shell pipelines and
+// CI. Every command reads its input from arguments, files or standard input,
+// writes its result to standard output (as JSON with --json) and reports
+// failure through its exit code.
+package headless
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/journal"
+)
+
+// Exit codes of the headless commands.
+const (
+	ExitOK          = 0
+	ExitFailure     = 1
+	ExitUsage       = 2
+	ExitFindings    = 3
+	ExitInterrupted = 130
+)
+
+// Globals are the flags accepted before the command name as well as after
+// it.
+type Globals struct {
+	Config   string
+	LogLevel string
+	JSON     bool
+	Quiet    bool
+	Timeout  time.Duration
+}
+
+// Register adds the global flags to fs. Values already set, as by the flags
+// before the command name, are kept as defaults.
+func (g *Globals) Register(fs *flag.FlagSet) {
+	fs.StringVar(&g.Config, "config", g.Config, "path of the config file (default config.yaml)")
+	fs.StringVar(&g.LogLevel, "log-level", g.LogLevel, "debug, info, warn or error (default warn)")
+	fs.BoolVar(&g.JSON, "json", g.JSON, "write the result as JSON")
+	fs.BoolVar(&g.Quiet, "quiet", g.Quiet, "do not report progress on standard error")
+	fs.DurationVar(&g.Timeout, "timeout", g.Timeout, "give up on each AI request after this long, e.g. 2m")
+}
+
+// Env is what a command runs with.
+type Env struct {
+	*Globals
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+	// StdinPiped reports whether standard input is a pipe or a file rather
+	// than a terminal, in which case it is read as input.
+	StdinPiped bool
+	// Connect creates the AI client on first use, so that help and usage
+	// errors do not need an API key.
+	Connect func() (ai.Generator, error)
+	// Journal records the files written, so that undo and the change
+	// history of the interface can restore them.
+	Journal *journal.Journal
+
+	client ai.Generator
+	// mu keeps the progress lines of parallel work whole.
+	mu sync.Mutex
+}
+
+// NewEnv returns an environment on the standard streams of the process.
+func NewEnv(g *Globals, connect func() (ai.Generator, error)) *Env {
+	env := &Env{Globals: g, Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr, Connect: connect, Journal: journal.Open(journal.DefaultDir)}
+	if info, err := os.Stdin.Stat(); err == nil {
+		env.StdinPiped = info.Mode()&os.ModeCharDevice == 0
+	}
+	return env
+}
+
+// Command is a headless subcommand.
+type Command struct {
+	Name    string
+	Summary string
+	Usage   string
+	Run     func(ctx context.Context, env *Env, args []string) error
+}
+
+// Commands lists the headless subcommands in the order help shows them.
+var Commands = []*Command{askCmd, analyzeCmd, reviewCmd, describeCmd, editCmd, generateCmd, batchCmd, hookCmd}
+
+// Lookup finds a command by name.
+func Lookup(name string) *Command {
+	for _, c := range Commands {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// usageError is a mistake in the command line; its message has already
+// been shown along with the usage.
+type usageError struct{ err error }
+
+func (e usageError) Error() string { return e.err.Error() }
+
+func usageErrorf(format string, args ...any) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
+
+// ExitCode maps the error a command returned to the exit code of the
+// process.
+func ExitCode(err error) int {
+	var usage usageError
+	var findings findingsError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &usage):
+		return ExitUsage
+	case errors.As(err, &findings):
+		return ExitFindings
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	}
+	return ExitFailure
+}
+
+// Report shows the error a command failed with, on standard error and, with
+// --json, as a JSON object on standard output. A review that failed on its
+// findings has already written its report there.
+func (e *Env) Report(name string, err error) {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	fmt.Fprintf(e.Stderr, "anx-agent %s: %v\n", name, err)
+	if e.JSON && !errors.As(err, new(findingsError)) {
+		e.writeJSON(map[string]any{"command": name, "error": err.Error(), "exit_code": ExitCode(err)})
+	}
+}
+
+// flags returns the flag set of cmd, with the global flags registered.
+func (e *Env) flags(cmd *Command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.SetOutput(e.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.Stderr, "Usage: anx-agent %s\n\n%s.\n\nFlags:\n", cmd.Usage, cmd.Summary)
+		fs.PrintDefaults()
+	}
+	e.Globals.Register(fs)
+	return fs
+}
+
+// parse parses args, allowing flags after the positional arguments, which
+// it returns. "--" ends the flags.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		in := args
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, usageError{err}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		// Parse consumes the "--" it stops at.
+		if consumed := in[:len(in)-len(args)]; len(consumed) > 0 && consumed[len(consumed)-1] == "--" {
+			return append(positional, args...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func (e *Env) connect() error {
+	if e.client != nil {
+		return nil
+	}
+	client, err := e.Connect()
+	if err != nil {
+		return err
+	}
+	e.client = client
+	return nil
+}
+
+// Generate sends prompt to the model, connecting on first use and giving up
+// after --timeout. It makes Env an ai.Generator for the pipelines that talk
+// to the model themselves.
+func (e *Env) Generate(ctx context.Context, prompt string) (ai.Reply, error) {
+	if err := e.connect(); err != nil {
+		return ai.Reply{}, err
+	}
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+	reply, err := e.client.Generate(ctx, prompt)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return reply, fmt.Errorf("no answer within %s: %w", e.Timeout, err)
+	}
+	return reply, err
+}
+
+// progress tells what the command is doing on standard error, unless
+// --quiet is set.
+func (e *Env) progress(format string, args ...any) {
+	if !e.Quiet {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		fmt.Fprintf(e.Stderr, format+"\n", args...)
+	}
+}
+
+// readStdin returns what was piped to the command, or "" when standard
+// input is a terminal.
+func (e *Env) readStdin() (string, error) {
+	if !e.StdinPiped {
+		return "", nil
+	}
+	data, err := io.ReadAll(e.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("error reading standard input: %w", err)
+	}
+	return string(data), nil
+}
+
+func (e *Env) writeJSON(v any) error {
+	enc := json.NewEncoder(e.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeText writes text ending with a newline.
+func (e *Env) writeText(text string) error {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	_, err := io.WriteString(e.Stdout, text)
+	return err
+}
+
+// result is the JSON output of the commands that answer with text.
+type result struct {
+	Command string   `json:"command"`
+	Model   string   `json:"model"`
+	Text    string   `json:"text"`
+	Usage   ai.Usage `json:"usage"`
+	Files   []string `json:"files,omitempty"`
+}
+
+// answer writes a reply as text, or as a result with --json.
+func (e *Env) answer(command string, reply ai.Reply, files []string) error {
+	if e.JSON {
+		return e.writeJSON(result{Command: command, Model: ai.Model, Text: reply.Text, Usage: reply.Usage, Files: files})
+	}
+	return e.writeText(reply.Text)
+}
+
+// forEach calls fn for 0 to n-1 with at most jobs calls running at the same
+// time. Once ctx is done, the calls not started yet are skipped.
+func forEach(ctx context.Context, jobs, n int, fn func(i int)) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// fileList is a flag that can be repeated or given a comma-separated list.
+type fileList []string
+
+func (f *fileList) String() string { return strings.Join(*f, ",") }
+
+func (f *fileList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*f = append(*f, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package headless
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/git"
+	"github.com/anthonycursewl/anx-agent/internal/journal"
+)
+
+// generator answers every prompt with answer and keeps the prompts.
+type generator struct {
+	answer  func(prompt string) (string, error)
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (g *generator) Generate(ctx context.Context, prompt string) (ai.Reply, error) {
+	g.mu.Lock()
+	g.prompts = append(g.prompts, prompt)
+	g.mu.Unlock()
+	text, err := g.answer(prompt)
+	return ai.Reply{Text: text, Usage: ai.Usage{Total: 1}}, err
+}
+
+func reply(text string) *generator {
+	return &generator{answer: func(string) (string, error) { return text, nil }}
+}
+
+// newEnv runs commands with g, without standard input, and returns what
+// they write to standard output and error.
+func newEnv(t *testing.T, g ai.Generator) (*Env, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	env := &Env{
+		Globals: &Globals{},
+		Stdin:   strings.NewReader(""),
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+		Connect: func() (ai.Generator, error) { return g, nil },
+		Journal: journal.Open(filepath.Join(t.TempDir(), "history")),
+	}
+	return env, &stdout, &stderr
+}
+
+// newRepo creates a repository with one commit of a.txt, isolated from the
+// configuration of the user, and makes it the current directory.
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, dir, "init", "-q")
+	gitRun(t, dir, "config", "user.name", "Test")
+	gitRun(t, dir, "config", "user.email", "test@example.com")
+	writeFile(t, filepath.Join(dir, "a.txt"), "one\n")
+	gitRun(t, dir, "add", "a.txt")
+	gitRun(t, dir, "commit", "-q", "-m", "first")
+	t.Chdir(dir)
+	return dir
+}
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := git.Run(dir, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
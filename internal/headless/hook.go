@@ -0,0 +1,133 @@
+package headless
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/agent/reviewer"
+	"github.com/anthonycursewl/anx-agent/internal/git"
+)
+
+var hookCmd = &Command{
+	Name:    "hook",
+	Summary: "Install or remove a git pre-commit hook that reviews the staged changes",
+	Usage:   "hook [flags] install|uninstall",
+}
+
+func init() { hookCmd.Run = runHook }
+
+// hookMarker identifies the hooks written by this command, which are the
+// only ones it replaces or removes without --force.
+const hookMarker = "# Installed by anx-agent hook install."
+
+func runHook(ctx context.Context, env *Env, args []string) error {
+	fs := env.flags(hookCmd)
+	failOn := fs.String("fail-on", "high", "block the commit on findings of this severity or higher")
+	minSeverity := fs.String("min-severity", "low", "leave out findings below this severity from the report")
+	focus := fs.String("focus", "", "what the review should concentrate on, e.g. security")
+	force := fs.Bool("force", false, "replace or remove a pre-commit hook that anx-agent did not install")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 || (args[0] != "install" && args[0] != "uninstall") {
+		fs.Usage()
+		return usageErrorf("hook takes install or uninstall")
+	}
+	for _, s := range []string{*failOn, *minSeverity} {
+		if _, err := reviewer.ParseSeverity(s); err != nil {
+			fs.Usage()
+			return usageError{err}
+		}
+	}
+
+	dir, err := git.HooksDir(".")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, "pre-commit")
+	existing, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		existing = nil
+	case err != nil:
+		return fmt.Errorf("error reading '%s': %w", path, err)
+	}
+	ours := strings.Contains(string(existing), hookMarker)
+
+	if args[0] == "uninstall" {
+		if existing == nil {
+			env.progress("No pre-commit hook in %s.", dir)
+			return env.hookResult("uninstall", path)
+		}
+		if !ours && !*force {
+			return fmt.Errorf("'%s' was not installed by anx-agent; use --force to remove it anyway", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("error removing '%s': %w", path, err)
+		}
+		env.progress("Removed %s", path)
+		return env.hookResult("uninstall", path)
+	}
+
+	if existing != nil && !ours && !*force {
+		return fmt.Errorf("'%s' already exists; use --force to replace it", path)
+	}
+	script, err := hookScript(*failOn, *minSeverity, *focus)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating directory '%s': %w", dir, err)
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return fmt.Errorf("error writing '%s': %w", path, err)
+	}
+	// WriteFile keeps the mode of a file it replaces.
+	if err := os.Chmod(path, 0755); err != nil {
+		return fmt.Errorf("error making '%s' executable: %w", path, err)
+	}
+	env.progress("Installed %s; commits with %s findings or worse are blocked (git commit --no-verify skips the review).", path, *failOn)
+	return env.hookResult("install", path)
+}
+
+// hookScript runs this executable on the staged changes.
+func hookScript(failOn, minSeverity, focus string) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("error finding the anx-agent executable: %w", err)
+	}
+	command := []string{shellQuote(exe), "review", "--staged", "--fail-on", failOn, "--min-severity", minSeverity}
+	if focus != "" {
+		command = append(command, "--focus", shellQuote(focus))
+	}
+	return fmt.Sprintf(`#!/bin/sh
+%s
+# Reviews the staged changes; 'git commit --no-verify' skips it.
+%s
+status=$?
+# Findings block the commit; a review that could not run, including when
+# the executable is missing (127) or not executable (126), does not.
+case $status in
+1|2|126|127)
+	echo "anx-agent: the review failed; committing without it." >&2
+	exit 0
+	;;
+esac
+exit $status
+`, hookMarker, strings.Join(command, " ")), nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (e *Env) hookResult(action, path string) error {
+	if e.JSON {
+		return e.writeJSON(map[string]any{"command": hookCmd.Name, "action": action, "path": path})
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package headless
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runHookCommand(t *testing.T, args ...string) error {
+	t.Helper()
+	env, _, _ := newEnv(t, reply(""))
+	return runHook(context.Background(), env, args)
+}
+
+func TestHookInstallAndUninstall(t *testing.T) {
+	dir := newRepo(t)
+	path := filepath.Join(dir, ".git", "hooks", "pre-commit")
+
+	if err := runHookCommand(t, "install"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+	script := readFile(t, path)
+	if !strings.Contains(script, hookMarker) || !strings.Contains(script, "review --staged --fail-on high") {
+		t.Errorf("unexpected hook:\n%s", script)
+	}
+	// A hook installed by anx-agent is replaced without --force.
+	if err := runHookCommand(t, "--fail-on", "critical", "install"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(readFile(t, path), "critical") {
+		t.Error("the hook was not replaced")
+	}
+
+	if err := runHookCommand(t, "uninstall"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the hook is still there: %v", err)
+	}
+	if err := runHookCommand(t, "uninstall"); err != nil {
+		t.Errorf("uninstalling without a hook: %v", err)
+	}
+}
+
+func TestHookLeavesOtherHooksAlone(t *testing.T) {
+	dir := newRepo(t)
+	path := filepath.Join(dir, ".git", "hooks", "pre-commit")
+	writeFile(t, path, "#!/bin/sh\nexit 0\n")
+
+	if err := runHookCommand(t, "install"); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("install over another hook: got %v, want an error suggesting --force", err)
+	}
+	if err := runHookCommand(t, "uninstall"); err == nil {
+		t.Error("uninstall removed a hook anx-agent did not install")
+	}
+	if got := readFile(t, path); got != "#!/bin/sh\nexit 0\n" {
+		t.Fatalf("the other hook was changed to %q", got)
+	}
+
+	if err := runHookCommand(t, "--force", "install"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(readFile(t, path), hookMarker) {
+		t.Error("--force did not replace the hook")
+	}
+
+	writeFile(t, path, "#!/bin/sh\nexit 0\n")
+	if err := runHookCommand(t, "--force", "uninstall"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("--force did not remove the hook: %v", err)
+	}
+}
+
+func TestHookUsage(t *testing.T) {
+	newRepo(t)
+	for _, args := range [][]string{nil, {"remove"}, {"--fail-on", "severe", "install"}} {
+		if err := runHookCommand(t, args...); ExitCode(err) != ExitUsage {
+			t.Errorf("hook %q: got %v, want a usage error", args, err)
+		}
+	}
+}
+
+// The hook only blocks the commit on findings; a review that cannot run,
+// even because the executable is gone, lets it through.
+func TestHookScriptExitStatus(t *testing.T) {
+	script, err := hookScript("high", "low", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		command string
+		want    int
+	}{
+		{"true", 0},
+		{"sh -c 'exit 3'", ExitFindings},
+		{"sh -c 'exit 1'", 0},
+		{"sh -c 'exit 2'", 0},
+		{"'" + filepath.Join(t.TempDir(), "missing") + "'", 0},
+		{"'" + os.DevNull + "'", 0},
+	}
+	for _, tt := range tests {
+		s := strings.Replace(script, shellQuote(exe), tt.command, 1)
+		err := exec.Command("sh", "-c", s).Run()
+		status := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			status = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if status != tt.want {
+			t.Errorf("with %s: exit status %d, want %d", tt.command, status, tt.want)
+		}
+	}
+}
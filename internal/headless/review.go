@@ -0,0 +1,277 @@
+package headless
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/agent/project"
+	"github.com/anthonycursewl/anx-agent/internal/agent/reviewer"
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/diff"
+	"github.com/anthonycursewl/anx-agent/internal/reporting"
+)
+
+var reviewCmd = &Command{
+	Name:    "review",
+	Summary: "Review files, directories or a diff and report findings with their location, severity, category and fix",
+	Usage:   "review [flags] [--staged | --range rev | --diff file | path...]",
+}
+
+func init() { reviewCmd.Run = runReview }
+
+// findingsError fails a review that found problems at or above the
+// --fail-on threshold.
+type findingsError struct {
+	count     int
+	threshold reviewer.Severity
+}
+
+func (e findingsError) Error() string {
+	return fmt.Sprintf("%d finding(s) of severity %s or higher", e.count, e.threshold)
+}
+
+type reviewOptions struct {
+	focus       *string
+	chunkTokens *int
+	minSeverity *string
+	failOn      *string
+	output      *string
+	format      *string
+}
+
+// reviewFlags registers the flags shared by the commands that report a
+// review.
+func reviewFlags(env *Env, cmd *Command) (*reviewOptions, *flag.FlagSet) {
+	fs := env.flags(cmd)
+	return &reviewOptions{
+		focus:       fs.String("focus", "", "what the review should concentrate on, e.g. security"),
+		chunkTokens: fs.Int("chunk-tokens", reviewer.DefaultMaxTokensPerChunk, "review about this many tokens of code per request"),
+		minSeverity: fs.String("min-severity", "info", "leave out findings below this severity"),
+		failOn:      fs.String("fail-on", "", "exit with code 3 if there are findings of this severity or higher: info, low, medium, high or critical"),
+		output:      fs.String("output", "", "file to write the report to, its format taken from the extension; or md, json or html for standard output"),
+		format:      fs.String("format", "", "md, json or html (default md, or json with --json)"),
+	}, fs
+}
+
+func runReview(ctx context.Context, env *Env, args []string) error {
+	opts, fs := reviewFlags(env, reviewCmd)
+	diffFile := fs.String("diff", "", "review the changes of this unified diff; - for standard input")
+	staged := fs.Bool("staged", false, "review the changes staged in the git repository")
+	rev := fs.String("range", "", "review the changes of a git revision range, e.g. main..HEAD")
+	contextLines := fs.Int("context", defaultContextLines, "lines of context around the changes with --staged and --range")
+	var extensions, ignorePaths fileList
+	fs.Var(&extensions, "extensions", "only review files with these extensions, e.g. go,ts")
+	fs.Var(&ignorePaths, "ignore-paths", "gitignore patterns of paths to leave out")
+	maxSize := fs.Int("max-file-size", 100, "leave out files larger than this many KB; 0 for no limit")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := opts.check(); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	if *staged && *rev != "" {
+		fs.Usage()
+		return usageErrorf("--staged and --range cannot be combined")
+	}
+
+	r := env.reviewer(opts)
+	var review *reviewer.Review
+	switch {
+	case *staged || *rev != "":
+		target, patches, err := gitChanges(*rev, *contextLines)
+		if err != nil {
+			return err
+		}
+		if len(patches) == 0 {
+			// Nothing to review is not a failure, so that the pre-commit
+			// hook lets empty commits through.
+			env.progress("No changes to review (%s).", target)
+			review, _ = r.ReviewPatches(ctx, target, nil)
+			break
+		}
+		if review, err = reviewPatches(ctx, env, r, target, patches); err != nil {
+			return err
+		}
+	case *diffFile != "":
+		text, err := env.readInput(*diffFile)
+		if err != nil {
+			return err
+		}
+		review, err = reviewDiff(ctx, env, r, *diffFile, text)
+		if err != nil {
+			return err
+		}
+	case len(args) == 1 && isPatchFile(args[0]):
+		text, err := env.readInput(args[0])
+		if err != nil {
+			return err
+		}
+		review, err = reviewDiff(ctx, env, r, args[0], text)
+		if err != nil {
+			return err
+		}
+	case len(args) == 0 && env.StdinPiped:
+		text, err := env.readStdin()
+		if err != nil {
+			return err
+		}
+		if looksLikeDiff(text) {
+			review, err = reviewDiff(ctx, env, r, stdinName, text)
+		} else {
+			review, err = env.reviewFiles(ctx, r, stdinName, []project.File{{Path: stdinName, Content: []byte(text)}})
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		files, skipped, err := project.CollectFiles(args, extensions, ignorePaths, *maxSize)
+		if err != nil {
+			return err
+		}
+		for _, s := range skipped {
+			env.progress("Skipping %s (%s)", s.Path, s.Reason)
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no files to review in %s", strings.Join(args, ", "))
+		}
+		review, err = env.reviewFiles(ctx, r, strings.Join(args, " "), files)
+		if err != nil {
+			return err
+		}
+	}
+	return env.reportReview(review, opts)
+}
+
+func (o *reviewOptions) check() error {
+	if _, err := reviewer.ParseSeverity(*o.minSeverity); err != nil {
+		return usageError{err}
+	}
+	if *o.failOn != "" {
+		if _, err := reviewer.ParseSeverity(*o.failOn); err != nil {
+			return usageError{err}
+		}
+	}
+	if _, _, err := reportTarget(*o.output, *o.format, false); err != nil {
+		return usageError{err}
+	}
+	return nil
+}
+
+func (e *Env) reviewer(opts *reviewOptions) *reviewer.Reviewer {
+	return &reviewer.Reviewer{
+		Client:            e,
+		MaxTokensPerChunk: *opts.chunkTokens,
+		Focus:             *opts.focus,
+		Progress: func(ev reviewer.Event) {
+			if ev.Chunks > 1 {
+				e.progress("[%d/%d] %s (part %d/%d)", ev.Index, ev.Total, ev.File, ev.Chunk, ev.Chunks)
+				return
+			}
+			e.progress("[%d/%d] %s", ev.Index, ev.Total, ev.File)
+		},
+	}
+}
+
+func (e *Env) reviewFiles(ctx context.Context, r *reviewer.Reviewer, target string, files []project.File) (*reviewer.Review, error) {
+	if err := e.connect(); err != nil {
+		return nil, err
+	}
+	e.progress("Reviewing %d file(s) with %s", len(files), ai.Model)
+	return r.ReviewFiles(ctx, target, files)
+}
+
+func reviewDiff(ctx context.Context, env *Env, r *reviewer.Reviewer, target, text string) (*reviewer.Review, error) {
+	patches, err := diff.ParsePatch(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the diff: %w", err)
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("the diff has no changes")
+	}
+	return reviewPatches(ctx, env, r, target, patches)
+}
+
+func reviewPatches(ctx context.Context, env *Env, r *reviewer.Reviewer, target string, patches []diff.FilePatch) (*reviewer.Review, error) {
+	if err := env.connect(); err != nil {
+		return nil, err
+	}
+	env.progress("Reviewing the changes to %d file(s) with %s", len(patches), ai.Model)
+	return r.ReviewPatches(ctx, target, patches)
+}
+
+// reportReview writes the findings at or above --min-severity and fails the
+// command when --fail-on is reached.
+func (e *Env) reportReview(review *reviewer.Review, opts *reviewOptions) error {
+	min, _ := reviewer.ParseSeverity(*opts.minSeverity)
+	review.Findings = review.AtLeast(min)
+	if review.Findings == nil {
+		review.Findings = []reviewer.Finding{}
+	}
+
+	file, format, _ := reportTarget(*opts.output, *opts.format, e.JSON)
+	report, err := reporting.ReviewReport(review, format)
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		if _, err := e.Stdout.Write(report); err != nil {
+			return err
+		}
+	} else {
+		if err := os.WriteFile(file, report, 0644); err != nil {
+			return fmt.Errorf("error writing '%s': %w", file, err)
+		}
+		e.progress("Wrote %s", file)
+	}
+	e.progress("Review: %s (%d tokens)", review.Counts(), review.Usage.Total)
+
+	if *opts.failOn != "" {
+		threshold, _ := reviewer.ParseSeverity(*opts.failOn)
+		if n := len(review.AtLeast(threshold)); n > 0 {
+			return findingsError{count: n, threshold: threshold}
+		}
+	}
+	return nil
+}
+
+// readInput reads a file, or standard input for "-".
+func (e *Env) readInput(path string) (string, error) {
+	if path == "-" {
+		data, err := io.ReadAll(e.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("error reading standard input: %w", err)
+		}
+		return string(data), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading file '%s': %w", path, err)
+	}
+	return string(data), nil
+}
+
+func isPatchFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".diff" || ext == ".patch"
+}
+
+// looksLikeDiff reports whether text starts like a unified diff.
+func looksLikeDiff(text string) bool {
+	for _, line := range strings.SplitN(text, "\n", 20) {
+		if strings.HasPrefix(line, "diff --git ") || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "@@ ") {
+			return true
+		}
+	}
+	return false
+}
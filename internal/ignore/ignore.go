@@ -0,0 +1,296 @@
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Files lists the ignore files honored in every directory, in the order they
+// are applied. Rules in .anxignore can therefore re-include files that
+// .gitignore excludes.
+var Files = []string{".gitignore", ".anxignore"}
+
+// Defaults are ignored everywhere, whatever the ignore files say.
+var Defaults = []string{".git/", ".anx/"}
+
+type rule struct {
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher decides whether a path is ignored using gitignore semantics.
+// Paths are relative to the root the matcher was created for.
+type Matcher struct {
+	root   string
+	rules  []rule
+	loaded map[string]bool
+}
+
+func New(root string) *Matcher {
+	m := &Matcher{root: root, loaded: map[string]bool{}}
+	for _, p := range Defaults {
+		m.Add("", p)
+	}
+	return m
+}
+
+// Load creates a matcher for root with the ignore files found in root.
+func Load(root string) (*Matcher, error) {
+	m := New(root)
+	if err := m.LoadDir(""); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ForDir creates a matcher for root with the ignore files of every directory
+// from root down to dir, so that dir can be listed on its own.
+func ForDir(root, dir string) (*Matcher, error) {
+	m, err := Load(root)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return m, nil
+	}
+	current := ""
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		current = path.Join(current, part)
+		if err := m.LoadDir(current); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// LoadDir reads the ignore files of the directory rel, once.
+func (m *Matcher) LoadDir(rel string) error {
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		rel = ""
+	}
+	if m.loaded[rel] {
+		return nil
+	}
+	m.loaded[rel] = true
+
+	for _, name := range Files {
+		f, err := os.Open(filepath.Join(m.root, filepath.FromSlash(rel), name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", name, err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			m.Add(rel, scanner.Text())
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("error reading %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Add parses a single gitignore line relative to the directory base.
+func (m *Matcher) Add(base, line string) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+	line = strings.TrimRight(line, " ")
+
+	r := rule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return
+	}
+
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if base != "" {
+		expr.WriteString(regexp.QuoteMeta(base) + "/")
+	}
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	expr.WriteString(globToRegexp(line))
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return
+	}
+	r.re = re
+	m.rules = append(m.rules, r)
+}
+
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+func (m *Matcher) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.base != "" && !strings.HasPrefix(rel, r.base+"/") {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// Ignored reports whether rel, or any directory containing it, is ignored.
+func (m *Matcher) Ignored(rel string, isDir bool) bool {
+	rel = strings.TrimPrefix(filepath.ToSlash(rel), "./")
+	if rel == "" || rel == "." {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.match(rel, isDir)
+}
+
+// Walk visits every file and directory under root that is not ignored,
+// honoring the ignore files of each directory as it descends. Paths passed
+// to fn are relative to root and use forward slashes.
+func Walk(root string, fn func(rel string, d fs.DirEntry) error) error {
+	m, err := Load(root)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if m.match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if err := m.LoadDir(rel); err != nil {
+				return err
+			}
+		}
+		return fn(rel, d)
+	})
+}
+
+// Glob returns the files under root matching pattern, relative to root with
+// forward slashes. Patterns use gitignore syntax, so '**' crosses
+// directories and a pattern without a slash matches at any depth. Ignored
+// files are skipped unless all is set.
+func Glob(root, pattern string, all bool) ([]string, error) {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	expr := "^" + globToRegexp(pattern) + "$"
+	if !strings.Contains(pattern, "/") {
+		expr = "^(?:.*/)?" + globToRegexp(pattern) + "$"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+
+	var matches []string
+	visit := func(rel string, d fs.DirEntry) error {
+		if !d.IsDir() && re.MatchString(rel) {
+			matches = append(matches, rel)
+		}
+		return nil
+	}
+	if !all {
+		return matches, Walk(root, visit)
+	}
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		return visit(filepath.ToSlash(rel), d)
+	})
+	return matches, err
+}
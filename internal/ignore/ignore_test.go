@@ -0,0 +1,169 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"defaults", nil, ".git/config", false, true},
+		{"default directory", nil, ".anx", true, true},
+		{"name at any depth", []string{"*.log"}, "a/b/debug.log", false, true},
+		{"other extension", []string{"*.log"}, "a/b/debug.txt", false, false},
+		{"star stays in a segment", []string{"a/*.go"}, "a/b/c.go", false, false},
+		{"question mark", []string{"file?.txt"}, "file1.txt", false, true},
+		{"character class", []string{"file[0-9].txt"}, "fileA.txt", false, false},
+		{"negated class", []string{"file[!0-9].txt"}, "fileA.txt", false, true},
+		{"negation", []string{"*.log", "!keep.log"}, "keep.log", false, false},
+		{"later rule wins", []string{"!keep.log", "*.log"}, "keep.log", false, true},
+		{"negation inside an ignored directory", []string{"build/", "!build/keep.txt"}, "build/keep.txt", false, true},
+		{"directory only matches a directory", []string{"out/"}, "out", false, false},
+		{"directory only matches a directory", []string{"out/"}, "out", true, true},
+		{"directory only ignores its files", []string{"out/"}, "src/out/a.txt", false, true},
+		{"leading slash anchors", []string{"/todo.txt"}, "docs/todo.txt", false, false},
+		{"leading slash at the root", []string{"/todo.txt"}, "todo.txt", false, true},
+		{"middle slash anchors", []string{"docs/*.md"}, "src/docs/a.md", false, false},
+		{"leading double star", []string{"**/logs"}, "a/b/logs", true, true},
+		{"middle double star", []string{"a/**/b"}, "a/x/y/b", false, true},
+		{"middle double star matches no directory", []string{"a/**/b"}, "a/b", false, true},
+		{"trailing double star", []string{"vendor/**"}, "vendor/x/y.go", false, true},
+		{"trailing double star leaves the directory", []string{"vendor/**"}, "vendor", true, false},
+		{"comment", []string{"# *.go"}, "main.go", false, false},
+		{"escaped hash", []string{`\#notes`}, "#notes", false, true},
+		{"escaped bang", []string{`\!important`}, "!important", false, true},
+		{"trailing spaces", []string{"*.tmp   "}, "a.tmp", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(t.TempDir())
+			for _, r := range tt.rules {
+				m.Add("", r)
+			}
+			if got := m.Ignored(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Ignored(%q) with %q = %v, want %v", tt.path, tt.rules, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesOfASubdirectory(t *testing.T) {
+	m := New(t.TempDir())
+	m.Add("sub", "*.gen.go")
+	m.Add("sub", "/local.txt")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"sub/a.gen.go", true},
+		{"sub/deep/a.gen.go", true},
+		{"a.gen.go", false},
+		{"other/a.gen.go", false},
+		{"sub/local.txt", true},
+		{"sub/deep/local.txt", false},
+	}
+	for _, tt := range tests {
+		if got := m.Ignored(tt.path, false); got != tt.want {
+			t.Errorf("Ignored(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func write(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWalkHonorsNestedIgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, map[string]string{
+		".gitignore":          "*.log\nbuild/\n",
+		".anxignore":          "!keep.log\n",
+		"main.go":             "",
+		"debug.log":           "",
+		"keep.log":            "",
+		"build/out.bin":       "",
+		"sub/.gitignore":      "*.tmp\n!important.log\n",
+		"sub/a.go":            "",
+		"sub/a.tmp":           "",
+		"sub/important.log":   "",
+		"sub/other.log":       "",
+		"other/a.tmp":         "",
+		".git/HEAD":           "",
+		".anx/history/x.json": "",
+	})
+	var files []string
+	err := Walk(root, func(rel string, d os.DirEntry) error {
+		if !d.IsDir() {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	want := []string{".anxignore", ".gitignore", "keep.log", "main.go", "other/a.tmp", "sub/.gitignore", "sub/a.go", "sub/important.log"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Walk visited %q, want %q", files, want)
+	}
+
+	m, err := ForDir(root, filepath.Join(root, "sub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Ignored("sub/a.tmp", false) || m.Ignored("sub/important.log", false) {
+		t.Error("ForDir did not load the ignore file of sub")
+	}
+}
+
+func TestGlob(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, map[string]string{
+		".gitignore":       "vendor/\n",
+		"a.go":             "",
+		"pkg/b.go":         "",
+		"pkg/c.txt":        "",
+		"vendor/v.go":      "",
+		"pkg/deep/d_x.go":  "",
+		"pkg/deep/d_y.txt": "",
+	})
+	tests := []struct {
+		pattern string
+		all     bool
+		want    []string
+	}{
+		{"*.go", false, []string{"a.go", "pkg/b.go", "pkg/deep/d_x.go"}},
+		{"*.go", true, []string{"a.go", "pkg/b.go", "pkg/deep/d_x.go", "vendor/v.go"}},
+		{"pkg/*.go", false, []string{"pkg/b.go"}},
+		{"pkg/**/*.go", false, []string{"pkg/b.go", "pkg/deep/d_x.go"}},
+		{"./pkg/deep/d_?.*", false, []string{"pkg/deep/d_x.go", "pkg/deep/d_y.txt"}},
+	}
+	for _, tt := range tests {
+		got, err := Glob(root, tt.pattern, tt.all)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Glob(%q, all=%v) = %q, want %q", tt.pattern, tt.all, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package inputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileName is the name of the history file inside the user data directory.
+const FileName = "inputs.json"
+
+// DefaultLimit is how many entries are kept for each kind of input.
+const DefaultLimit = 500
+
+// History remembers submitted inputs grouped by kind, such as "chat" or
+// "modify", oldest first. It is saved after every change.
+type History struct {
+	path    string
+	limit   int
+	entries map[string][]string
+}
+
+// Open loads the history stored at path. A missing file is an empty
+// history, and an empty path keeps the history in memory only.
+func Open(path string, limit int) (*History, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	h := &History{path: path, limit: limit, entries: map[string][]string{}}
+	if path == "" {
+		return h, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return h, fmt.Errorf("error reading input history: %w", err)
+	}
+	if err := json.Unmarshal(data, &h.entries); err != nil {
+		return h, fmt.Errorf("error parsing input history: %w", err)
+	}
+	return h, nil
+}
+
+// Add appends entry to the history of kind. Repeating an earlier entry
+// moves it to the end instead of storing it twice.
+func (h *History) Add(kind, entry string) error {
+	if strings.TrimSpace(entry) == "" {
+		return nil
+	}
+	list := h.entries[kind]
+	for i, e := range list {
+		if e == entry {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	list = append(list, entry)
+	if len(list) > h.limit {
+		list = list[len(list)-h.limit:]
+	}
+	h.entries[kind] = list
+	return h.save()
+}
+
+// List returns the entries of kind, oldest first.
+func (h *History) List(kind string) []string {
+	return append([]string(nil), h.entries[kind]...)
+}
+
+// Kinds returns the kinds that have entries, sorted.
+func (h *History) Kinds() []string {
+	var kinds []string
+	for kind, list := range h.entries {
+		if len(list) > 0 {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Search looks for the newest entry of kind before position from that
+// contains query, ignoring case. It returns the position of the match.
+func (h *History) Search(kind, query string, from int) (int, bool) {
+	list := h.entries[kind]
+	if from > len(list) {
+		from = len(list)
+	}
+	query = strings.ToLower(query)
+	for i := from - 1; i >= 0; i-- {
+		if strings.Contains(strings.ToLower(list[i]), query) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Clear forgets the entries of kind, or of every kind when kind is empty.
+func (h *History) Clear(kind string) error {
+	if kind == "" {
+		h.entries = map[string][]string{}
+	} else {
+		delete(h.entries, kind)
+	}
+	return h.save()
+}
+
+func (h *History) save() error {
+	if h.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(h.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding input history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return fmt.Errorf("error creating data directory: %w", err)
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("error writing input history: %w", err)
+	}
+	if err := os.Rename(tmp, h.path); err != nil {
+		return fmt.Errorf("error writing input history: %w", err)
+	}
+	return nil
+}
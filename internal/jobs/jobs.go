@@ -0,0 +1,200 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Status int
+
+const (
+	Running Status = iota
+	Done
+	Failed
+	Canceled
+)
+
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Done:
+		return "done"
+	case Failed:
+		return "failed"
+	case Canceled:
+		return "canceled"
+	}
+	return "unknown"
+}
+
+// DefaultKeep is how many finished jobs a Manager remembers.
+const DefaultKeep = 50
+
+// Job is a snapshot of a piece of background work.
+type Job struct {
+	ID       int
+	Kind     string
+	Title    string
+	Status   Status
+	Progress string
+	Started  time.Time
+	Finished time.Time
+	Err      error
+}
+
+// Elapsed is how long the job ran, or has been running.
+func (j Job) Elapsed() time.Duration {
+	if j.Finished.IsZero() {
+		return time.Since(j.Started).Truncate(time.Second)
+	}
+	return j.Finished.Sub(j.Started).Truncate(time.Second)
+}
+
+type entry struct {
+	Job
+	cancel context.CancelFunc
+}
+
+// Manager tracks running and finished jobs. It is safe for concurrent use,
+// so jobs may report progress from their own goroutine.
+type Manager struct {
+	mu   sync.Mutex
+	next int
+	jobs []*entry
+	keep int
+}
+
+func NewManager() *Manager { return &Manager{keep: DefaultKeep} }
+
+// Start registers a running job. The returned context is canceled by Cancel.
+func (m *Manager) Start(kind, title string) (int, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	m.jobs = append(m.jobs, &entry{
+		Job:    Job{ID: m.next, Kind: kind, Title: title, Status: Running, Started: time.Now()},
+		cancel: cancel,
+	})
+	return m.next, ctx
+}
+
+func (m *Manager) find(id int) *entry {
+	for _, e := range m.jobs {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// SetProgress describes what a running job is doing.
+func (m *Manager) SetProgress(id int, progress string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e := m.find(id); e != nil && e.Status == Running {
+		e.Progress = progress
+	}
+}
+
+// Finish records the outcome of a job and returns it. A job that was
+// canceled stays canceled whatever err is.
+func (m *Manager) Finish(id int, err error) Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.find(id)
+	if e == nil {
+		return Job{ID: id, Status: Failed, Err: err}
+	}
+	if e.Status == Running {
+		e.Finished = time.Now()
+		e.Progress = ""
+		switch {
+		case errors.Is(err, context.Canceled):
+			e.Status = Canceled
+		case err != nil:
+			e.Status = Failed
+			e.Err = err
+		default:
+			e.Status = Done
+		}
+	}
+	e.cancel()
+	m.prune()
+	return e.Job
+}
+
+// prune forgets the oldest finished jobs beyond the keep limit.
+func (m *Manager) prune() {
+	finished := 0
+	for _, e := range m.jobs {
+		if e.Status != Running {
+			finished++
+		}
+	}
+	kept := m.jobs[:0]
+	for _, e := range m.jobs {
+		if e.Status != Running && finished > m.keep {
+			finished--
+			continue
+		}
+		kept = append(kept, e)
+	}
+	m.jobs = kept
+}
+
+// Cancel stops a running job. Its result, if it still arrives, is dropped.
+func (m *Manager) Cancel(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.find(id)
+	if e == nil {
+		return fmt.Errorf("no job #%d", id)
+	}
+	if e.Status != Running {
+		return fmt.Errorf("job #%d is already %s", id, e.Status)
+	}
+	e.Status = Canceled
+	e.Finished = time.Now()
+	e.Progress = ""
+	e.cancel()
+	return nil
+}
+
+// Get returns a snapshot of the job with id.
+func (m *Manager) Get(id int) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e := m.find(id); e != nil {
+		return e.Job, true
+	}
+	return Job{}, false
+}
+
+// List returns a snapshot of every job, oldest first.
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Job, len(m.jobs))
+	for i, e := range m.jobs {
+		list[i] = e.Job
+	}
+	return list
+}
+
+// Running returns how many jobs are still running.
+func (m *Manager) Running() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, e := range m.jobs {
+		if e.Status == Running {
+			n++
+		}
+	}
+	return n
+}
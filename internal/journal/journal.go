@@ -0,0 +1,492 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultDir = ".anx/history"
+
+const (
+	OpCreate = "create"
+	OpWrite  = "write"
+	OpRevert = "revert"
+	OpDelete = "delete"
+	OpMove   = "move"
+	OpCopy   = "copy"
+	OpMkdir  = "mkdir"
+)
+
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// Entry describes one change made to the workspace. The content the file had
+// before the change is kept next to the entry so it can be restored. Deleted
+// files are moved to the trash instead, and moves remember their source.
+type Entry struct {
+	ID       string      `json:"id"`
+	Time     time.Time   `json:"time"`
+	Op       string      `json:"op"`
+	Path     string      `json:"path"`
+	From     string      `json:"from,omitempty"`
+	Trash    string      `json:"trash,omitempty"`
+	Existed  bool        `json:"existed"`
+	Mode     os.FileMode `json:"mode,omitempty"`
+	Prompt   string      `json:"prompt,omitempty"`
+	Reverted bool        `json:"reverted,omitempty"`
+}
+
+type Journal struct {
+	dir   string
+	trash string
+	mu    sync.Mutex
+	// pending holds the IDs of prepared entries that are not saved yet.
+	pending map[string]bool
+}
+
+// Open returns the journal stored in dir. Deleted files are kept in a trash
+// directory next to it.
+func Open(dir string) *Journal {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	return &Journal{dir: dir, trash: filepath.Join(filepath.Dir(dir), "trash"), pending: map[string]bool{}}
+}
+
+func (j *Journal) Dir() string { return j.dir }
+
+// Write records the current state of path and then replaces its content.
+func (j *Journal) Write(path string, content []byte, prompt string) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, err := j.record(path, OpWrite, prompt)
+	if err != nil {
+		return Entry{}, err
+	}
+	mode := os.FileMode(0644)
+	if entry.Existed {
+		mode = entry.Mode
+	}
+	if err := os.WriteFile(entry.Path, content, mode); err != nil {
+		j.discard(entry)
+		return Entry{}, fmt.Errorf("error writing file '%s': %w", path, err)
+	}
+	return entry, j.save(entry)
+}
+
+// Prepare records the current state of path before the caller replaces its
+// content, for writes that are staged with others so that all or none of
+// them happen. The entry must be committed once the file was written, or
+// aborted if the write was given up.
+func (j *Journal) Prepare(path, prompt string) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, err := j.record(path, OpWrite, prompt)
+	if err != nil {
+		return Entry{}, err
+	}
+	j.pending[entry.ID] = true
+	return entry, nil
+}
+
+// Commit saves an entry returned by Prepare, once its file was written.
+func (j *Journal) Commit(entry Entry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.pending, entry.ID)
+	return j.save(entry)
+}
+
+// Abort drops an entry returned by Prepare whose write did not happen.
+func (j *Journal) Abort(entry Entry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.pending, entry.ID)
+	j.discard(entry)
+}
+
+// Create records and creates a new empty file. It fails if path exists.
+func (j *Journal) Create(path, prompt string) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := os.Stat(path); err == nil {
+		return Entry{}, fmt.Errorf("file '%s' already exists", path)
+	}
+	entry, err := j.record(path, OpCreate, prompt)
+	if err != nil {
+		return Entry{}, err
+	}
+	f, err := os.OpenFile(entry.Path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return Entry{}, fmt.Errorf("error creating file '%s': %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return Entry{}, fmt.Errorf("error creating file '%s': %w", path, err)
+	}
+	return entry, j.save(entry)
+}
+
+// Delete moves path, file or directory, to the trash.
+func (j *Journal) Delete(path string) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, err := j.newEntry(path, OpDelete)
+	if err != nil {
+		return Entry{}, err
+	}
+	if err := j.moveToTrash(&entry); err != nil {
+		return Entry{}, err
+	}
+	return entry, j.save(entry)
+}
+
+// moveToTrash moves the path of entry to a trash directory named after it.
+func (j *Journal) moveToTrash(entry *Entry) error {
+	if _, err := os.Lstat(entry.Path); err != nil {
+		return fmt.Errorf("error accessing '%s': %w", entry.Path, err)
+	}
+	entry.Existed = true
+	entry.Trash = filepath.Join(j.trash, entry.ID, filepath.Base(entry.Path))
+	if err := os.MkdirAll(filepath.Dir(entry.Trash), 0755); err != nil {
+		return fmt.Errorf("error creating trash directory: %w", err)
+	}
+	if err := os.Rename(entry.Path, entry.Trash); err != nil {
+		return fmt.Errorf("error moving '%s' to the trash: %w", entry.Path, err)
+	}
+	return nil
+}
+
+// Move renames from to to. It never overwrites an existing path.
+func (j *Journal) Move(from, to string) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, err := j.newEntry(to, OpMove)
+	if err != nil {
+		return Entry{}, err
+	}
+	if entry.From, err = filepath.Abs(from); err != nil {
+		return Entry{}, fmt.Errorf("error getting absolute path: %w", err)
+	}
+	if err := ensureFree(to); err != nil {
+		return Entry{}, err
+	}
+	if err := os.Rename(entry.From, entry.Path); err != nil {
+		return Entry{}, fmt.Errorf("error moving '%s': %w", from, err)
+	}
+	return entry, j.save(entry)
+}
+
+// Copy copies the file or directory from to to. It never overwrites an
+// existing path.
+func (j *Journal) Copy(from, to string) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, err := j.newEntry(to, OpCopy)
+	if err != nil {
+		return Entry{}, err
+	}
+	if entry.From, err = filepath.Abs(from); err != nil {
+		return Entry{}, fmt.Errorf("error getting absolute path: %w", err)
+	}
+	if err := ensureFree(to); err != nil {
+		return Entry{}, err
+	}
+	if err := copyPath(entry.From, entry.Path); err != nil {
+		os.RemoveAll(entry.Path)
+		return Entry{}, fmt.Errorf("error copying '%s': %w", from, err)
+	}
+	return entry, j.save(entry)
+}
+
+// Mkdir creates the directory path and any missing parents.
+func (j *Journal) Mkdir(path string) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, err := j.newEntry(path, OpMkdir)
+	if err != nil {
+		return Entry{}, err
+	}
+	if err := ensureFree(path); err != nil {
+		return Entry{}, err
+	}
+	if err := os.MkdirAll(entry.Path, 0755); err != nil {
+		return Entry{}, fmt.Errorf("error creating directory '%s': %w", path, err)
+	}
+	return entry, j.save(entry)
+}
+
+func ensureFree(path string) error {
+	if _, err := os.Lstat(path); err == nil {
+		return fmt.Errorf("'%s' already exists", path)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("error accessing '%s': %w", path, err)
+	}
+	return nil
+}
+
+func copyPath(from, to string) error {
+	info, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(from)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(to, data, info.Mode().Perm())
+	}
+
+	if err := os.Mkdir(to, info.Mode().Perm()); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := copyPath(filepath.Join(from, e.Name()), filepath.Join(to, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *Journal) newEntry(path, op string) (Entry, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return Entry{}, fmt.Errorf("error getting absolute path: %w", err)
+	}
+	if err := os.MkdirAll(j.dir, 0755); err != nil {
+		return Entry{}, fmt.Errorf("error creating journal directory: %w", err)
+	}
+
+	now := time.Now()
+	id := now.UTC().Format("20060102T150405.000000000")
+	for i := 1; ; i++ {
+		if _, err := os.Stat(j.entryPath(id)); os.IsNotExist(err) && !j.pending[id] {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", now.UTC().Format("20060102T150405.000000000"), i)
+	}
+	return Entry{ID: id, Time: now, Op: op, Path: absPath}, nil
+}
+
+// record prepares an entry for a change to the content of path, keeping a
+// backup of the current content. The entry is only saved by the caller, once
+// the change was made.
+func (j *Journal) record(path, op, prompt string) (Entry, error) {
+	entry, err := j.newEntry(path, op)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry.Prompt = prompt
+
+	info, err := os.Stat(entry.Path)
+	switch {
+	case err == nil && info.IsDir():
+		return Entry{}, fmt.Errorf("path '%s' is a directory", path)
+	case err == nil:
+		entry.Existed = true
+		entry.Mode = info.Mode().Perm()
+		previous, err := os.ReadFile(entry.Path)
+		if err != nil {
+			return Entry{}, fmt.Errorf("error reading file '%s': %w", path, err)
+		}
+		if err := os.WriteFile(j.backupPath(entry.ID), previous, 0644); err != nil {
+			return Entry{}, fmt.Errorf("error saving backup: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return Entry{}, fmt.Errorf("error accessing file '%s': %w", path, err)
+	}
+	return entry, nil
+}
+
+// discard drops the backup of an entry whose change failed.
+func (j *Journal) discard(entry Entry) {
+	os.Remove(j.backupPath(entry.ID))
+}
+
+func (j *Journal) save(entry Entry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding journal entry: %w", err)
+	}
+	if err := os.WriteFile(j.entryPath(entry.ID), data, 0644); err != nil {
+		return fmt.Errorf("error saving journal entry: %w", err)
+	}
+	return nil
+}
+
+func (j *Journal) entryPath(id string) string  { return filepath.Join(j.dir, id+".json") }
+func (j *Journal) backupPath(id string) string { return filepath.Join(j.dir, id+".bak") }
+
+// List returns all entries, newest first.
+func (j *Journal) List() ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.list()
+}
+
+func (j *Journal) list() ([]Entry, error) {
+	files, err := os.ReadDir(j.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read journal: %w", err)
+	}
+
+	var entries []Entry
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(j.dir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("could not read journal entry: %w", err)
+		}
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("invalid journal entry '%s': %w", f.Name(), err)
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].ID > entries[b].ID })
+	return entries, nil
+}
+
+// Undo reverts the most recent change that has not been reverted yet.
+func (j *Journal) Undo() (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries, err := j.list()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, entry := range entries {
+		if !entry.Reverted && entry.Op != OpRevert {
+			return j.revert(entry)
+		}
+	}
+	return Entry{}, ErrNothingToUndo
+}
+
+// Revert restores the file of the given entry to the state it had before
+// that change. The current state is recorded first, so a revert can itself
+// be reverted. An entry is only reverted once.
+func (j *Journal) Revert(id string) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	data, err := os.ReadFile(j.entryPath(id))
+	if err != nil {
+		return Entry{}, fmt.Errorf("unknown journal entry '%s': %w", id, err)
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Entry{}, fmt.Errorf("invalid journal entry '%s': %w", id, err)
+	}
+	if entry.Reverted {
+		return Entry{}, fmt.Errorf("journal entry '%s' was already reverted", id)
+	}
+	return j.revert(entry)
+}
+
+func (j *Journal) revert(entry Entry) (Entry, error) {
+	switch {
+	case entry.Trash != "":
+		// Deletes, and the copies moved to the trash when undone.
+		if err := ensureFree(entry.Path); err != nil {
+			return Entry{}, err
+		}
+		if err := os.MkdirAll(filepath.Dir(entry.Path), 0755); err != nil {
+			return Entry{}, fmt.Errorf("error restoring directory: %w", err)
+		}
+		if err := os.Rename(entry.Trash, entry.Path); err != nil {
+			return Entry{}, fmt.Errorf("error restoring '%s' from the trash: %w", entry.Path, err)
+		}
+		os.Remove(filepath.Dir(entry.Trash))
+	case entry.Op == OpMove:
+		if err := ensureFree(entry.From); err != nil {
+			return Entry{}, err
+		}
+		if err := os.Rename(entry.Path, entry.From); err != nil {
+			return Entry{}, fmt.Errorf("error moving '%s' back: %w", entry.Path, err)
+		}
+	case entry.Op == OpCopy:
+		// The copy may have been edited since, so it goes to the trash.
+		undo, err := j.newEntry(entry.Path, OpRevert)
+		if err != nil {
+			return Entry{}, err
+		}
+		undo.Prompt = "revert " + entry.ID
+		if err := j.moveToTrash(&undo); err != nil {
+			return Entry{}, err
+		}
+		if err := j.save(undo); err != nil {
+			return Entry{}, err
+		}
+	case entry.Op == OpMkdir:
+		if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+			return Entry{}, fmt.Errorf("error removing directory '%s': %w", entry.Path, err)
+		}
+	default:
+		if err := j.restoreContent(entry); err != nil {
+			return Entry{}, err
+		}
+	}
+
+	entry.Reverted = true
+	if err := j.save(entry); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+func (j *Journal) restoreContent(entry Entry) error {
+	revert, err := j.record(entry.Path, OpRevert, "revert "+entry.ID)
+	if err != nil {
+		return err
+	}
+	if err := restore(j.backupPath(entry.ID), entry); err != nil {
+		j.discard(revert)
+		return err
+	}
+	return j.save(revert)
+}
+
+// restore puts back the content a file had before entry, from backup.
+func restore(backup string, entry Entry) error {
+	if !entry.Existed {
+		if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing file '%s': %w", entry.Path, err)
+		}
+		return nil
+	}
+	previous, err := os.ReadFile(backup)
+	if err != nil {
+		return fmt.Errorf("backup for '%s' is missing: %w", entry.Path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(entry.Path), 0755); err != nil {
+		return fmt.Errorf("error restoring directory: %w", err)
+	}
+	if err := os.WriteFile(entry.Path, previous, entry.Mode); err != nil {
+		return fmt.Errorf("error restoring file '%s': %w", entry.Path, err)
+	}
+	return nil
+}
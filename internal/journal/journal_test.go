@@ -0,0 +1,305 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newJournal(t *testing.T) (*Journal, string) {
+	t.Helper()
+	dir := t.TempDir()
+	return Open(filepath.Join(dir, ".anx", "history")), dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func TestWriteAndRevert(t *testing.T) {
+	j, dir := newJournal(t)
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := j.Write(path, []byte("new\n"), "rewrite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entry.Existed || entry.Op != OpWrite || entry.Prompt != "rewrite" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if got := readFile(t, path); got != "new\n" {
+		t.Errorf("content after write = %q", got)
+	}
+
+	if _, err := j.Revert(entry.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "old\n" {
+		t.Errorf("content after revert = %q", got)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode after revert = %v, want 0600", info.Mode().Perm())
+	}
+
+	// The revert is recorded, and reverting it brings the new content back.
+	entries, err := j.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Op != OpRevert || !entries[1].Reverted {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if _, err := j.Revert(entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "new\n" {
+		t.Errorf("content after reverting the revert = %q", got)
+	}
+}
+
+func TestWriteNewFileAndUndo(t *testing.T) {
+	j, dir := newJournal(t)
+	path := filepath.Join(dir, "new.txt")
+	if _, err := j.Write(path, []byte("hello"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if exists(path) {
+		t.Error("undoing the write of a new file should remove it")
+	}
+	if _, err := j.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("second undo: got %v, want ErrNothingToUndo", err)
+	}
+}
+
+func TestFailedWriteLeavesNoEntry(t *testing.T) {
+	j, dir := newJournal(t)
+	path := filepath.Join(dir, "missing", "a.txt")
+	if _, err := j.Write(path, []byte("x"), ""); err == nil {
+		t.Fatal("writing into a missing directory should fail")
+	}
+	entries, err := j.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("a failed write left entries %+v", entries)
+	}
+	if _, err := j.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("undo after a failed write: got %v, want ErrNothingToUndo", err)
+	}
+}
+
+func TestPrepareCommitAndAbort(t *testing.T) {
+	j, dir := newJournal(t)
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(a, []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries prepared together get distinct IDs though none is saved yet.
+	ea, err := j.Prepare(a, "staged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eb, err := j.Prepare(b, "staged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ea.ID == eb.ID {
+		t.Fatalf("prepared entries share the ID %s", ea.ID)
+	}
+	if entries, _ := j.List(); len(entries) != 0 {
+		t.Errorf("prepared entries are listed before being committed: %+v", entries)
+	}
+
+	if err := os.WriteFile(a, []byte("A\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Commit(ea); err != nil {
+		t.Fatal(err)
+	}
+	j.Abort(eb)
+	if exists(j.backupPath(eb.ID)) {
+		t.Error("the backup of an aborted entry was kept")
+	}
+
+	entries, err := j.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != ea.ID || entries[0].Prompt != "staged" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if _, err := j.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, a); got != "a\n" {
+		t.Errorf("undo restored %q, want the content before the write", got)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	j, dir := newJournal(t)
+	path := filepath.Join(dir, "c.txt")
+	entry, err := j.Create(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "" {
+		t.Errorf("created file has content %q", got)
+	}
+	if _, err := j.Create(path, ""); err == nil {
+		t.Error("creating an existing file should fail")
+	}
+	if _, err := j.Revert(entry.ID); err != nil {
+		t.Fatal(err)
+	}
+	if exists(path) {
+		t.Error("reverting a create should remove the file")
+	}
+}
+
+func TestDeleteAndRevert(t *testing.T) {
+	j, dir := newJournal(t)
+	sub := filepath.Join(dir, "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "f.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := j.Delete(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists(sub) {
+		t.Fatal("deleted directory still exists")
+	}
+	if !exists(filepath.Join(entry.Trash, "f.txt")) {
+		t.Fatalf("deleted directory is not in the trash at %s", entry.Trash)
+	}
+
+	if _, err := j.Revert(entry.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(sub, "f.txt")); got != "keep" {
+		t.Errorf("restored content = %q", got)
+	}
+	if _, err := j.Delete(filepath.Join(dir, "nothing")); err == nil {
+		t.Error("deleting a missing path should fail")
+	}
+}
+
+func TestRevertRefusesToOverwrite(t *testing.T) {
+	j, dir := newJournal(t)
+	path := filepath.Join(dir, "d.txt")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := j.Delete(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Revert(entry.ID); err == nil {
+		t.Error("restoring over an existing file should fail")
+	}
+	if got := readFile(t, path); got != "other" {
+		t.Errorf("content = %q, want the file left alone", got)
+	}
+}
+
+func TestRevertCopyKeepsLaterEdits(t *testing.T) {
+	j, dir := newJournal(t)
+	from, to := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(from, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := j.Copy(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := j.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if exists(to) {
+		t.Fatal("undone copy still exists")
+	}
+	if _, err := j.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("second undo: got %v, want ErrNothingToUndo", err)
+	}
+	if _, err := j.Revert(entry.ID); err == nil {
+		t.Error("reverting an entry twice should fail")
+	}
+	if got := readFile(t, from); got != "a" {
+		t.Errorf("source content = %q", got)
+	}
+
+	// The copy went to the trash, and reverting the undo brings back the
+	// edited file.
+	entries, err := j.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Op != OpRevert || entries[0].Trash == "" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if _, err := j.Revert(entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, to); got != "edited" {
+		t.Errorf("content after reverting the undo = %q", got)
+	}
+}
+
+func TestRevertOnlyOnce(t *testing.T) {
+	j, dir := newJournal(t)
+	path := filepath.Join(dir, "a.txt")
+	entry, err := j.Write(path, []byte("one"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Revert(entry.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("later"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Revert(entry.ID); err == nil {
+		t.Error("reverting an entry twice should fail")
+	}
+	if got := readFile(t, path); got != "later" {
+		t.Errorf("content = %q, want the later file left alone", got)
+	}
+}
@@ -0,0 +1,134 @@
+// Package prompts builds the prompts shared by the TUI and the headless
+// commands, so that both ask the model the same way.
+package prompts
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// File is a file shared with the model as context.
+type File struct {
+	Path    string
+	Content string
+}
+
+// Files renders files as clearly delimited sections.
+func Files(files []File) string {
+	var sb strings.Builder
+	for i, f := range files {
+		fmt.Fprintf(&sb, "=== CONTEXT FILE %d/%d: %s ===\n", i+1, len(files), f.Path)
+		sb.WriteString(f.Content)
+		if !strings.HasSuffix(f.Content, "\n") {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "=== END OF CONTEXT FILE: %s ===\n\n", f.Path)
+	}
+	return sb.String()
+}
+
+// Chat asks the model to answer input, with the rendered context files if
+// there are any.
+func Chat(input, context string) string {
+	if context == "" {
+		return input
+	}
+	return fmt.Sprintf(
+		"Answer the user's message. The following files were shared as context:\n\n%s--- USER MESSAGE ---\n%s",
+		context,
+		input,
+	)
+}
+
+// Generate asks for the content of a new file named after path.
+func Generate(path, instructions, context string) string {
+	if context != "" {
+		return fmt.Sprintf(
+			"You are an expert file creator. Create a new file based on user instructions and context from other files.\n\n--- FILE CONTEXT ---\n%s\n--- USER INSTRUCTIONS FOR NEW FILE '%s' ---\n%s\n\nIMPORTANT: Only output the raw, complete content for the new file. Do not include any explanations, greetings, or markdown code fences.",
+			context,
+			filepath.Base(path),
+			instructions,
+		)
+	}
+	return fmt.Sprintf("Generate the complete file content for a file named `%s`. The file should accomplish the following: %s. Only output the raw file content, without any explanation or markdown formatting.", filepath.Base(path), instructions)
+}
+
+// Modify asks for the whole new content of a file; reference holds other
+// files that may help but must not be output.
+func Modify(original, instructions, reference string) string {
+	prompt := fmt.Sprintf(
+		"You are an expert file editor. The user wants to modify a file. Below is the original content of the file and the user's instructions. Your task is to return the *entire*, *new* content of the file with the modifications applied. \n\nIMPORTANT: Only output the raw, complete, modified file content. Do not include any explanations, greetings, or markdown code fences like ```go ... ```.\n\n--- ORIGINAL FILE CONTENT ---\n%s\n\n--- USER INSTRUCTIONS ---\n%s",
+		original,
+		instructions,
+	)
+	if reference != "" {
+		prompt += "\n\n--- REFERENCE FILES (do not output these) ---\n" + reference
+	}
+	return prompt
+}
+
+// Analyze asks for an analysis of fileContext following instructions.
+func Analyze(fileContext, instructions string) string {
+	return fmt.Sprintf(
+		"You are an expert file analyzer. The user has provided the content of one or more files and wants you to analyze them based on their instructions. Your task is to provide a comprehensive analysis. \n\n--- FILE CONTENT TO ANALYZE ---\n%s\n--- USER INSTRUCTIONS FOR ANALYSIS ---\n%s",
+		fileContext,
+		instructions,
+	)
+}
+
+// CommitMessage asks for a Conventional Commits message describing a diff.
+// stat, which may be empty, lists every changed file in case the diff was
+// cut short.
+func CommitMessage(stat, diff string) string {
+	return fmt.Sprintf(
+		"Write a git commit message for the changes below, following the Conventional Commits format: a subject line of the form 'type(scope): summary' (type is feat, fix, refactor, perf, docs, test, build, ci or chore; the scope is optional), at most 72 characters, in the imperative mood and without a final period; then a blank line and a short body explaining what changed and why, wrapped at 72 characters. Leave the body out for trivial changes. Only output the message, without code fences or comments.\n\n%s--- DIFF ---\n%s",
+		section("CHANGED FILES", stat),
+		diff,
+	)
+}
+
+// PullRequest asks for the title and description of a pull request made of
+// commits, given by their subjects, and diff.
+func PullRequest(commits []string, stat, diff string) string {
+	var log string
+	if len(commits) > 0 {
+		log = "- " + strings.Join(commits, "\n- ")
+	}
+	return fmt.Sprintf(
+		"Write the description of a pull request for the changes below, in Markdown. Start with a title line of the form '# title', under 72 characters. Then one or two sentences on what the change does and why, a '## Changes' list of the notable changes, and a '## Testing' section on how to verify them, with what the diff shows about tests. Be concrete and brief; do not invent facts the diff does not show. Only output the description.\n\n%s%s--- DIFF ---\n%s",
+		section("COMMITS", log),
+		section("CHANGED FILES", stat),
+		diff,
+	)
+}
+
+func section(title, content string) string {
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	return "--- " + title + " ---\n" + strings.TrimRight(content, "\n") + "\n\n"
+}
+
+// Unfence removes the code fence a model sometimes wraps a whole file in,
+// despite being asked not to.
+func Unfence(content string) string {
+	trimmed := strings.TrimSpace(content)
+	for _, fence := range []string{"```", "~~~"} {
+		if !strings.HasPrefix(trimmed, fence) || !strings.HasSuffix(trimmed, fence) || len(trimmed) < 2*len(fence) {
+			continue
+		}
+		body := strings.TrimSuffix(trimmed, fence)
+		newline := strings.Index(body, "\n")
+		if newline < 0 {
+			continue
+		}
+		body = body[newline+1:]
+		if strings.Contains(body, "\n"+fence) {
+			// More than one block: not a single wrapped file.
+			continue
+		}
+		return strings.TrimSuffix(body, "\n") + "\n"
+	}
+	return content
+}
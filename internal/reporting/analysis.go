@@ -0,0 +1,143 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/agent/analyzer"
+)
+
+// GenerateReport renders the analysis of a project.
+func GenerateReport(analysis *analyzer.ProjectAnalysis, format Format) ([]byte, error) {
+	switch format {
+	case Markdown:
+		return []byte(analysisMarkdown(analysis)), nil
+	case JSON:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(analysis); err != nil {
+			return nil, fmt.Errorf("error encoding analysis: %w", err)
+		}
+		return buf.Bytes(), nil
+	case HTML:
+		return []byte(analysisHTML(analysis)), nil
+	}
+	return nil, fmt.Errorf("unknown format '%s'", format)
+}
+
+func usageLine(a *analyzer.ProjectAnalysis) string {
+	return fmt.Sprintf("%d files · %s · %d tokens (%d in, %d out)", len(a.FileSummaries), a.Model, a.Usage.Total, a.Usage.Prompt, a.Usage.Output)
+}
+
+func analysisMarkdown(a *analyzer.ProjectAnalysis) string {
+	var sb strings.Builder
+	list := func(items []string) {
+		for _, item := range items {
+			fmt.Fprintf(&sb, "- %s\n", item)
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("# ANX Agent Project Analysis Report\n\n")
+	fmt.Fprintf(&sb, "- **Path:** `%s`\n", a.Path)
+	fmt.Fprintf(&sb, "- **Generated:** %s\n", timestamp(a.GeneratedAt))
+	fmt.Fprintf(&sb, "- **Analyzed:** %s\n\n", usageLine(a))
+
+	fmt.Fprintf(&sb, "## Project Overview\n\n%s\n\n", strings.TrimSpace(a.Overview))
+	if len(a.KeyTechnologies) > 0 {
+		sb.WriteString("## Key Technologies\n\n")
+		list(a.KeyTechnologies)
+	}
+	if a.Structure != "" {
+		fmt.Fprintf(&sb, "## Project Structure\n\n%s\n\n", strings.TrimSpace(a.Structure))
+	}
+
+	sb.WriteString("## File Summaries\n\n")
+	for _, f := range a.FileSummaries {
+		fmt.Fprintf(&sb, "### `%s`\n\n%s\n\n", f.FilePath, strings.TrimSpace(f.Summary))
+		if f.Error != "" {
+			fmt.Fprintf(&sb, "**Not analyzed:** %s\n\n", f.Error)
+		}
+		if len(f.KeyFunctions) > 0 {
+			fmt.Fprintf(&sb, "**Key functions:** %s\n\n", strings.Join(f.KeyFunctions, ", "))
+		}
+		if len(f.Dependencies) > 0 {
+			fmt.Fprintf(&sb, "**Dependencies:** %s\n\n", strings.Join(f.Dependencies, ", "))
+		}
+		if len(f.Issues) > 0 {
+			sb.WriteString("**Issues:**\n\n")
+			list(f.Issues)
+		}
+	}
+
+	if len(a.Suggestions) > 0 || len(a.PotentialIssues) > 0 {
+		sb.WriteString("## Suggestions & Potential Issues\n\n")
+		if len(a.Suggestions) > 0 {
+			sb.WriteString("### Suggestions\n\n")
+			list(a.Suggestions)
+		}
+		if len(a.PotentialIssues) > 0 {
+			sb.WriteString("### Potential Issues\n\n")
+			list(a.PotentialIssues)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+func analysisHTML(a *analyzer.ProjectAnalysis) string {
+	var sb strings.Builder
+	esc := html.EscapeString
+	list := func(items []string) {
+		sb.WriteString("<ul>\n")
+		for _, item := range items {
+			fmt.Fprintf(&sb, "<li>%s</li>\n", esc(item))
+		}
+		sb.WriteString("</ul>\n")
+	}
+
+	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>Analysis of %s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", esc(a.Path), htmlStyle)
+	fmt.Fprintf(&sb, "<h1>Project Analysis</h1>\n<p class=\"meta\"><code>%s</code> · Generated %s<br>%s</p>\n",
+		esc(a.Path), timestamp(a.GeneratedAt), esc(usageLine(a)))
+
+	fmt.Fprintf(&sb, "<h2>Project Overview</h2>\n<p>%s</p>\n", esc(strings.TrimSpace(a.Overview)))
+	if len(a.KeyTechnologies) > 0 {
+		sb.WriteString("<h2>Key Technologies</h2>\n")
+		list(a.KeyTechnologies)
+	}
+	if a.Structure != "" {
+		fmt.Fprintf(&sb, "<h2>Project Structure</h2>\n<p>%s</p>\n", esc(strings.TrimSpace(a.Structure)))
+	}
+	sb.WriteString("<h2>File Summaries</h2>\n")
+	for _, f := range a.FileSummaries {
+		fmt.Fprintf(&sb, "<section class=\"entry\">\n<header><code>%s</code></header>\n<p>%s</p>\n", esc(f.FilePath), esc(strings.TrimSpace(f.Summary)))
+		if f.Error != "" {
+			fmt.Fprintf(&sb, "<p><strong>Not analyzed:</strong> %s</p>\n", esc(f.Error))
+		}
+		if len(f.KeyFunctions) > 0 {
+			fmt.Fprintf(&sb, "<p><strong>Key functions:</strong> %s</p>\n", esc(strings.Join(f.KeyFunctions, ", ")))
+		}
+		if len(f.Dependencies) > 0 {
+			fmt.Fprintf(&sb, "<p><strong>Dependencies:</strong> %s</p>\n", esc(strings.Join(f.Dependencies, ", ")))
+		}
+		if len(f.Issues) > 0 {
+			list(f.Issues)
+		}
+		sb.WriteString("</section>\n")
+	}
+	if len(a.Suggestions) > 0 {
+		sb.WriteString("<h2>Suggestions</h2>\n")
+		list(a.Suggestions)
+	}
+	if len(a.PotentialIssues) > 0 {
+		sb.WriteString("<h2>Potential Issues</h2>\n")
+		list(a.PotentialIssues)
+	}
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
@@ -0,0 +1,290 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+	"github.com/anthonycursewl/anx-agent/internal/session"
+	"github.com/charmbracelet/x/ansi"
+)
+
+type Format string
+
+const (
+	Markdown Format = "md"
+	JSON     Format = "json"
+	HTML     Format = "html"
+)
+
+// ParseFormat accepts a format name or a file extension such as ".markdown".
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "md", "markdown":
+		return Markdown, nil
+	case "json":
+		return JSON, nil
+	case "html", "htm":
+		return HTML, nil
+	}
+	return "", fmt.Errorf("unknown format '%s'; use md, json or html", name)
+}
+
+// FormatOf infers the format from the extension of path, defaulting to
+// Markdown.
+func FormatOf(path string) Format {
+	if f, err := ParseFormat(filepath.Ext(path)); err == nil {
+		return f
+	}
+	return Markdown
+}
+
+// Export renders the transcript of sess. Terminal escape codes, such as the
+// highlighting of 'cat' output, are stripped.
+func Export(sess *session.Session, format Format, exported time.Time) ([]byte, error) {
+	switch format {
+	case Markdown:
+		return []byte(conversationMarkdown(sess, exported)), nil
+	case JSON:
+		return conversationJSON(sess, exported)
+	case HTML:
+		return []byte(conversationHTML(sess, exported)), nil
+	}
+	return nil, fmt.Errorf("unknown format '%s'", format)
+}
+
+var kindLabels = map[string]string{
+	"user":  "You",
+	"ai":    "AI",
+	"info":  "Info",
+	"error": "Error",
+	"code":  "Output",
+}
+
+func label(msg session.Message) string {
+	l, ok := kindLabels[msg.Kind]
+	if !ok {
+		l = msg.Kind
+	}
+	if msg.Model != "" {
+		l += " (" + msg.Model + ")"
+	}
+	return l
+}
+
+// details describes the token usage of a reply and the files it was given.
+func details(msg session.Message) string {
+	var parts []string
+	if u := msg.Usage; u != nil && u.Total > 0 {
+		parts = append(parts, fmt.Sprintf("%d tokens (%d in, %d out)", u.Total, u.Prompt, u.Output))
+	}
+	if len(msg.Files) > 0 {
+		parts = append(parts, "files: "+strings.Join(msg.Files, ", "))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func title(sess *session.Session) string {
+	if sess.Title == "" {
+		return "Conversation " + sess.ID
+	}
+	return sess.Title
+}
+
+// models lists the models that answered in the conversation, in order of
+// first use.
+func models(sess *session.Session) []string {
+	var list []string
+	seen := map[string]bool{}
+	for _, msg := range sess.Messages {
+		if msg.Model != "" && !seen[msg.Model] {
+			seen[msg.Model] = true
+			list = append(list, msg.Model)
+		}
+	}
+	return list
+}
+
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// fence picks a code fence longer than any backtick run inside text.
+func fence(text string) string {
+	f := "```"
+	for strings.Contains(text, f) {
+		f += "`"
+	}
+	return f
+}
+
+func conversationMarkdown(sess *session.Session, exported time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", title(sess))
+	fmt.Fprintf(&sb, "- **Session:** %s\n", sess.ID)
+	fmt.Fprintf(&sb, "- **Workspace:** `%s`\n", sess.Workspace)
+	fmt.Fprintf(&sb, "- **Started:** %s\n", timestamp(sess.Created))
+	fmt.Fprintf(&sb, "- **Exported:** %s\n", timestamp(exported))
+	if m := models(sess); len(m) > 0 {
+		fmt.Fprintf(&sb, "- **Models:** %s\n", strings.Join(m, ", "))
+	}
+
+	if len(sess.Context) > 0 {
+		sb.WriteString("\n## Context files\n\n")
+		for _, f := range sess.Context {
+			pinned := ""
+			if f.Pinned {
+				pinned = " (pinned)"
+			}
+			fmt.Fprintf(&sb, "- `%s`%s\n", f.Path, pinned)
+		}
+	}
+
+	sb.WriteString("\n## Conversation\n")
+	for _, msg := range sess.Messages {
+		content := strings.TrimSpace(ansi.Strip(msg.Content))
+		fmt.Fprintf(&sb, "\n### %s", label(msg))
+		if t := timestamp(msg.Time); t != "" {
+			fmt.Fprintf(&sb, " · %s", t)
+		}
+		sb.WriteString("\n\n")
+		if d := details(msg); d != "" {
+			fmt.Fprintf(&sb, "_%s_\n\n", d)
+		}
+		switch msg.Kind {
+		case "user", "ai":
+			sb.WriteString(content + "\n")
+		case "code":
+			f := fence(content)
+			sb.WriteString(f + "\n" + content + "\n" + f + "\n")
+		default:
+			prefix := "> "
+			if msg.Kind == "error" {
+				prefix = "> **Error:** "
+			}
+			for i, line := range strings.Split(content, "\n") {
+				if i > 0 {
+					prefix = "> "
+				}
+				sb.WriteString(strings.TrimRight(prefix+line, " ") + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+type exportedMessage struct {
+	Kind    string     `json:"kind"`
+	Content string     `json:"content"`
+	Time    *time.Time `json:"time,omitempty"`
+	Model   string     `json:"model,omitempty"`
+	Usage   *ai.Usage  `json:"usage,omitempty"`
+	Files   []string   `json:"files,omitempty"`
+}
+
+type exportedConversation struct {
+	ID        string                `json:"id"`
+	Title     string                `json:"title"`
+	Workspace string                `json:"workspace"`
+	Created   time.Time             `json:"created"`
+	Exported  time.Time             `json:"exported"`
+	Models    []string              `json:"models"`
+	Context   []session.ContextFile `json:"context"`
+	Messages  []exportedMessage     `json:"messages"`
+}
+
+func conversationJSON(sess *session.Session, exported time.Time) ([]byte, error) {
+	doc := exportedConversation{
+		ID:        sess.ID,
+		Title:     title(sess),
+		Workspace: sess.Workspace,
+		Created:   sess.Created,
+		Exported:  exported,
+		Models:    models(sess),
+		Context:   sess.Context,
+		Messages:  make([]exportedMessage, 0, len(sess.Messages)),
+	}
+	if doc.Models == nil {
+		doc.Models = []string{}
+	}
+	if doc.Context == nil {
+		doc.Context = []session.ContextFile{}
+	}
+	for _, msg := range sess.Messages {
+		e := exportedMessage{Kind: msg.Kind, Content: ansi.Strip(msg.Content), Model: msg.Model, Usage: msg.Usage, Files: msg.Files}
+		if !msg.Time.IsZero() {
+			t := msg.Time
+			e.Time = &t
+		}
+		doc.Messages = append(doc.Messages, e)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("error encoding conversation: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+const htmlStyle = `body{font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",Helvetica,Arial,sans-serif;max-width:860px;margin:2rem auto;padding:0 1rem;color:#1f2328;line-height:1.5}
+h1{margin-bottom:.25rem}
+.meta{color:#59636e;font-size:.9rem}
+.meta code,.context code{font-size:.85rem}
+.entry{border-left:4px solid #d1d9e0;border-radius:4px;margin:1rem 0;padding:.5rem 1rem;background:#f6f8fa}
+.entry header{font-weight:600;font-size:.85rem;color:#59636e;margin-bottom:.25rem}
+.entry header time,.entry header .details{font-weight:400;margin-left:.5rem}
+.entry pre{white-space:pre-wrap;word-wrap:break-word;margin:0;font-family:inherit}
+.entry.user{border-color:#0969da;background:#ddf4ff}
+.entry.ai{border-color:#8250df;background:#fbefff}
+.entry.info{border-color:#1a7f37;background:#dafbe1}
+.entry.error{border-color:#cf222e;background:#ffebe9}
+.entry.code pre{font-family:ui-monospace,SFMono-Regular,Menlo,Consolas,monospace;font-size:.85rem}`
+
+func conversationHTML(sess *session.Session, exported time.Time) string {
+	var sb strings.Builder
+	esc := html.EscapeString
+	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", esc(title(sess)), htmlStyle)
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n<p class=\"meta\">Session %s · Workspace <code>%s</code><br>Started %s · Exported %s",
+		esc(title(sess)), esc(sess.ID), esc(sess.Workspace), timestamp(sess.Created), timestamp(exported))
+	if m := models(sess); len(m) > 0 {
+		fmt.Fprintf(&sb, "<br>Models: %s", esc(strings.Join(m, ", ")))
+	}
+	sb.WriteString("</p>\n")
+
+	if len(sess.Context) > 0 {
+		sb.WriteString("<h2>Context files</h2>\n<ul class=\"context\">\n")
+		for _, f := range sess.Context {
+			pinned := ""
+			if f.Pinned {
+				pinned = " (pinned)"
+			}
+			fmt.Fprintf(&sb, "<li><code>%s</code>%s</li>\n", esc(f.Path), pinned)
+		}
+		sb.WriteString("</ul>\n")
+	}
+
+	sb.WriteString("<h2>Conversation</h2>\n")
+	for _, msg := range sess.Messages {
+		fmt.Fprintf(&sb, "<section class=\"entry %s\">\n<header>%s", esc(msg.Kind), esc(label(msg)))
+		if !msg.Time.IsZero() {
+			fmt.Fprintf(&sb, "<time datetime=\"%s\">%s</time>", msg.Time.Format(time.RFC3339), timestamp(msg.Time))
+		}
+		if d := details(msg); d != "" {
+			fmt.Fprintf(&sb, "<span class=\"details\">%s</span>", esc(d))
+		}
+		fmt.Fprintf(&sb, "</header>\n<pre>%s</pre>\n</section>\n", esc(strings.TrimSpace(ansi.Strip(msg.Content))))
+	}
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
@@ -0,0 +1,96 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/anthonycursewl/anx-agent/internal/agent/reviewer"
+)
+
+// ReviewReport renders the findings of a review.
+func ReviewReport(review *reviewer.Review, format Format) ([]byte, error) {
+	switch format {
+	case Markdown:
+		return []byte(reviewMarkdown(review)), nil
+	case JSON:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(review); err != nil {
+			return nil, fmt.Errorf("error encoding review: %w", err)
+		}
+		return buf.Bytes(), nil
+	case HTML:
+		return []byte(reviewHTML(review)), nil
+	}
+	return nil, fmt.Errorf("unknown format '%s'", format)
+}
+
+func reviewSummary(r *reviewer.Review) string {
+	return fmt.Sprintf("%d file(s) · %s · %s · %d tokens (%d in, %d out)", len(r.Files), r.Counts(), r.Model, r.Usage.Total, r.Usage.Prompt, r.Usage.Output)
+}
+
+func reviewMarkdown(r *reviewer.Review) string {
+	var sb strings.Builder
+	sb.WriteString("# Code Review\n\n")
+	fmt.Fprintf(&sb, "- **Target:** `%s`\n", r.Target)
+	fmt.Fprintf(&sb, "- **Generated:** %s\n", timestamp(r.GeneratedAt))
+	fmt.Fprintf(&sb, "- **Result:** %s\n\n", reviewSummary(r))
+	if len(r.Findings) == 0 {
+		sb.WriteString("No findings.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("| # | Severity | Category | Location | Finding |\n| --- | --- | --- | --- | --- |\n")
+	for i, f := range r.Findings {
+		fmt.Fprintf(&sb, "| %d | %s | %s | `%s` | %s |\n", i+1, f.Severity, f.Category, f.Location(), strings.ReplaceAll(firstLine(f.Message), "|", `\|`))
+	}
+	sb.WriteString("\n")
+	for i, f := range r.Findings {
+		fmt.Fprintf(&sb, "## %d. %s: %s\n\n", i+1, strings.ToUpper(f.Severity.String()), f.Location())
+		fmt.Fprintf(&sb, "*%s*\n\n%s\n\n", f.Category, strings.TrimSpace(f.Message))
+		if fix := strings.TrimSpace(f.Fix); fix != "" {
+			fmt.Fprintf(&sb, "**Suggested fix:**\n\n%s\n\n", fix)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+var severityColors = map[reviewer.Severity]string{
+	reviewer.Critical: "#cf222e",
+	reviewer.High:     "#bc4c00",
+	reviewer.Medium:   "#9a6700",
+	reviewer.Low:      "#0969da",
+	reviewer.Info:     "#59636e",
+}
+
+func reviewHTML(r *reviewer.Review) string {
+	var sb strings.Builder
+	esc := html.EscapeString
+	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>Review of %s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", esc(r.Target), htmlStyle)
+	fmt.Fprintf(&sb, "<h1>Code Review</h1>\n<p class=\"meta\"><code>%s</code> · Generated %s<br>%s</p>\n",
+		esc(r.Target), timestamp(r.GeneratedAt), esc(reviewSummary(r)))
+	if len(r.Findings) == 0 {
+		sb.WriteString("<p>No findings.</p>\n")
+	}
+	for i, f := range r.Findings {
+		fmt.Fprintf(&sb, "<section class=\"entry\" style=\"border-color:%s\">\n<header>%d. %s · %s · <code>%s</code></header>\n<p>%s</p>\n",
+			severityColors[f.Severity], i+1, strings.ToUpper(f.Severity.String()), esc(f.Category), esc(f.Location()), esc(strings.TrimSpace(f.Message)))
+		if fix := strings.TrimSpace(f.Fix); fix != "" {
+			fmt.Fprintf(&sb, "<p><strong>Suggested fix:</strong></p>\n<pre>%s</pre>\n", esc(fix))
+		}
+		sb.WriteString("</section>\n")
+	}
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
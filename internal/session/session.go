@@ -0,0 +1,189 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anthonycursewl/anx-agent/internal/ai"
+)
+
+// Version is the current format of saved sessions. Sessions written by a
+// newer version are refused instead of being misread.
+const Version = 1
+
+// DirName is the directory inside the user data directory holding sessions.
+const DirName = "sessions"
+
+var ErrNoSessions = errors.New("no saved sessions")
+
+type Message struct {
+	ID      int       `json:"id,omitempty"`
+	ReplyTo int       `json:"reply_to,omitempty"`
+	Kind    string    `json:"kind"`
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
+	Model   string    `json:"model,omitempty"`
+	Usage   *ai.Usage `json:"usage,omitempty"`
+	Files   []string  `json:"files,omitempty"`
+}
+
+// Branch is a fork of the conversation. The messages of the current branch
+// are those of the session.
+type Branch struct {
+	ID           int       `json:"id"`
+	Parent       int       `json:"parent,omitempty"`
+	Title        string    `json:"title"`
+	ForkedAt     int       `json:"forked_at,omitempty"`
+	Created      time.Time `json:"created"`
+	Messages     []Message `json:"messages,omitempty"`
+	Conversation []ai.Turn `json:"conversation,omitempty"`
+}
+
+type ContextFile struct {
+	Path   string `json:"path"`
+	Pinned bool   `json:"pinned,omitempty"`
+}
+
+// Session is everything needed to continue a conversation later: the
+// transcript shown on screen, the context files and the turns sent to the
+// AI.
+type Session struct {
+	Version      int           `json:"version"`
+	ID           string        `json:"id"`
+	Title        string        `json:"title"`
+	Workspace    string        `json:"workspace"`
+	Created      time.Time     `json:"created"`
+	Updated      time.Time     `json:"updated"`
+	Messages     []Message     `json:"messages"`
+	Context      []ContextFile `json:"context,omitempty"`
+	Conversation []ai.Turn     `json:"conversation,omitempty"`
+	Branch       int           `json:"branch,omitempty"`
+	Branches     []Branch      `json:"branches,omitempty"`
+}
+
+// New starts a session for the workspace at dir.
+func New(workspace string) *Session {
+	now := time.Now()
+	return &Session{
+		Version:   Version,
+		ID:        now.Format("20060102-150405.000000"),
+		Workspace: workspace,
+		Created:   now,
+		Updated:   now,
+	}
+}
+
+// Store keeps one JSON file per session in a directory.
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) *Store { return &Store{dir: dir} }
+
+func (s *Store) path(id string) string { return filepath.Join(s.dir, id+".json") }
+
+// Save writes the session, replacing any previous version of it.
+func (s *Store) Save(sess *Session) error {
+	sess.Version = Version
+	data, err := json.MarshalIndent(sess, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding session: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("error creating sessions directory: %w", err)
+	}
+	tmp := s.path(sess.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("error saving session: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(sess.ID)); err != nil {
+		return fmt.Errorf("error saving session: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) Load(id string) (*Session, error) {
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		return nil, fmt.Errorf("error reading session '%s': %w", id, err)
+	}
+	sess := &Session{}
+	if err := json.Unmarshal(data, sess); err != nil {
+		return nil, fmt.Errorf("error parsing session '%s': %w", id, err)
+	}
+	if sess.Version > Version {
+		return nil, fmt.Errorf("session '%s' was saved by a newer version (format %d)", id, sess.Version)
+	}
+	return sess, nil
+}
+
+// List returns every saved session, most recently updated first.
+func (s *Store) List() ([]*Session, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading sessions directory: %w", err)
+	}
+
+	var sessions []*Session
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		sess, err := s.Load(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, sess)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Updated.After(sessions[j].Updated) })
+	return sessions, nil
+}
+
+// Find loads the session whose ID starts with prefix. It fails when the
+// prefix is ambiguous.
+func (s *Store) Find(prefix string) (*Session, error) {
+	sessions, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var found *Session
+	for _, sess := range sessions {
+		if sess.ID == prefix {
+			return sess, nil
+		}
+		if strings.HasPrefix(sess.ID, prefix) {
+			if found != nil {
+				return nil, fmt.Errorf("'%s' matches more than one session", prefix)
+			}
+			found = sess
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no session '%s'", prefix)
+	}
+	return found, nil
+}
+
+// Latest returns the most recent session of workspace. Sessions of other
+// workspaces are never returned, so resuming cannot mix up projects.
+func (s *Store) Latest(workspace string) (*Session, error) {
+	sessions, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, sess := range sessions {
+		if sess.Workspace == workspace {
+			return sess, nil
+		}
+	}
+	return nil, fmt.Errorf("%w for %s", ErrNoSessions, workspace)
+}
@@ -0,0 +1,275 @@
+package theme
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/anthonycursewl/anx-agent/internal/config"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Auto picks the dark or light theme from the terminal background.
+const Auto = "auto"
+
+// DirName is the directory inside the user config directory holding user
+// themes, one YAML file per theme.
+const DirName = "themes"
+
+// Palette names the color of every role in the interface. Colors are hex
+// values such as "#76D7C4" or ANSI color numbers from 0 to 255.
+type Palette struct {
+	Accent   string `yaml:"accent"`
+	User     string `yaml:"user"`
+	AI       string `yaml:"ai"`
+	Error    string `yaml:"error"`
+	Info     string `yaml:"info"`
+	Muted    string `yaml:"muted"`
+	Border   string `yaml:"border"`
+	Spinner  string `yaml:"spinner"`
+	Added    string `yaml:"added"`
+	Removed  string `yaml:"removed"`
+	Selected string `yaml:"selected"`
+	Match    string `yaml:"match"`
+}
+
+type Theme struct {
+	Name   string  `yaml:"-"`
+	Colors Palette `yaml:"colors"`
+	// Syntax is the chroma style used to highlight code.
+	Syntax string `yaml:"syntax"`
+	// NoColor disables every color, as asked by NO_COLOR.
+	NoColor bool `yaml:"-"`
+}
+
+var builtins = map[string]Theme{
+	"dark": {
+		Name: "dark",
+		Colors: Palette{
+			Accent: "#76D7C4", User: "#5DADE2", AI: "#F7DC6F", Error: "#E74C3C",
+			Info: "#AAB7B8", Muted: "#85929E", Border: "#566573", Spinner: "#FAD02E",
+			Added: "#58D68D", Removed: "#E74C3C", Selected: "#C472DA", Match: "#F7DC6F",
+		},
+		Syntax: "monokai",
+	},
+	"light": {
+		Name: "light",
+		Colors: Palette{
+			Accent: "#117A65", User: "#1F618D", AI: "#7D6608", Error: "#C0392B",
+			Info: "#566573", Muted: "#707B7C", Border: "#AAB7B8", Spinner: "#B9770E",
+			Added: "#1E8449", Removed: "#C0392B", Selected: "#7D3C98", Match: "#B9770E",
+		},
+		Syntax: "github",
+	},
+	"high-contrast": {
+		Name: "high-contrast",
+		Colors: Palette{
+			Accent: "#00FFFF", User: "#5FD7FF", AI: "#FFFF00", Error: "#FF5F5F",
+			Info: "#FFFFFF", Muted: "#D0D0D0", Border: "#FFFFFF", Spinner: "#FFFF00",
+			Added: "#00FF00", Removed: "#FF5F5F", Selected: "#FF00FF", Match: "#FFFF00",
+		},
+		Syntax: "hr_high_contrast",
+	},
+}
+
+var (
+	mu      sync.RWMutex
+	current = builtins["dark"]
+	// profile is the color profile detected for the terminal, which Set
+	// restores after a theme without colors.
+	profile termenv.Profile
+	detect  sync.Once
+)
+
+// Current returns the theme in use, for output outside the TUI.
+func Current() *Theme {
+	mu.RLock()
+	defer mu.RUnlock()
+	t := current
+	return &t
+}
+
+// Set makes t the theme in use. When t disables colors, every lipgloss style
+// renders plain text until a theme with colors is set.
+func Set(t *Theme) {
+	detect.Do(func() { profile = lipgloss.ColorProfile() })
+	mu.Lock()
+	current = *t
+	mu.Unlock()
+	if t.NoColor {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	} else {
+		lipgloss.SetColorProfile(profile)
+	}
+}
+
+// Dir returns the directory where user themes are looked up.
+func Dir() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, DirName), nil
+}
+
+// Names lists the built-in themes followed by the user themes.
+func Names() []string {
+	names := []string{Auto, "dark", "light", "high-contrast"}
+	dir, err := Dir()
+	if err != nil {
+		return names
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return names
+	}
+	var user []string
+	for _, e := range entries {
+		name, ext := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())), filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		if _, builtin := builtins[name]; !builtin && name != Auto {
+			user = append(user, name)
+		}
+	}
+	sort.Strings(user)
+	return append(names, user...)
+}
+
+// Load resolves name to a theme: "auto" or an empty name, a built-in theme,
+// a user theme in Dir(), or the path of a YAML file. NO_COLOR in the
+// environment turns colors off whatever the theme.
+func Load(name string) (*Theme, error) {
+	t, err := load(name)
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		t.NoColor = true
+	}
+	return t, nil
+}
+
+func load(name string) (*Theme, error) {
+	if name == "" || name == Auto {
+		name = "dark"
+		if !lipgloss.HasDarkBackground() {
+			name = "light"
+		}
+	}
+	if t, ok := builtins[name]; ok {
+		return &t, nil
+	}
+
+	path := name
+	if !strings.ContainsRune(name, filepath.Separator) && filepath.Ext(name) == "" {
+		dir, err := Dir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, name+".yaml")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if _, err := os.Stat(filepath.Join(dir, name+".yml")); err == nil {
+				path = filepath.Join(dir, name+".yml")
+			}
+		}
+	}
+	return loadFile(path)
+}
+
+// loadFile reads a user theme. A theme may extend another one and only set
+// the colors it changes.
+func loadFile(path string) (*Theme, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("unknown theme '%s'; available: %s", strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), strings.Join(Names(), ", "))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading theme: %w", err)
+	}
+
+	var header struct {
+		Extends string `yaml:"extends"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("error parsing theme '%s': %w", path, err)
+	}
+	if header.Extends == "" {
+		header.Extends = Auto
+	}
+	if _, ok := builtins[header.Extends]; !ok && header.Extends != Auto {
+		return nil, fmt.Errorf("theme '%s' extends unknown theme '%s'; use a built-in theme", path, header.Extends)
+	}
+	base, err := load(header.Extends)
+	if err != nil {
+		return nil, err
+	}
+
+	t := *base
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("error parsing theme '%s': %w", path, err)
+	}
+	t.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("theme '%s': %w", path, err)
+	}
+	return &t, nil
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func (t *Theme) validate() error {
+	p := &t.Colors
+	roles := []struct {
+		name  string
+		value string
+	}{
+		{"accent", p.Accent}, {"user", p.User}, {"ai", p.AI}, {"error", p.Error},
+		{"info", p.Info}, {"muted", p.Muted}, {"border", p.Border}, {"spinner", p.Spinner},
+		{"added", p.Added}, {"removed", p.Removed}, {"selected", p.Selected}, {"match", p.Match},
+	}
+	for _, r := range roles {
+		if hexColor.MatchString(r.value) {
+			continue
+		}
+		if n, err := strconv.Atoi(r.value); err == nil && n >= 0 && n <= 255 {
+			continue
+		}
+		return fmt.Errorf("invalid %s color '%s'; use #rrggbb or 0-255", r.name, r.value)
+	}
+	if _, ok := styles.Registry[t.Syntax]; !ok {
+		return fmt.Errorf("unknown syntax style '%s'", t.Syntax)
+	}
+	return nil
+}
+
+// Color returns c as a terminal color, or no color when colors are off.
+func (t *Theme) Color(c string) lipgloss.TerminalColor {
+	if t.NoColor || c == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(c)
+}
+
+// Style returns a style with c as its foreground.
+func (t *Theme) Style(c string) lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(t.Color(c))
+}
+
+// SyntaxStyle returns the chroma style for code, or "" when code should not
+// be highlighted.
+func (t *Theme) SyntaxStyle() string {
+	if t.NoColor {
+		return ""
+	}
+	return t.Syntax
+}
@@ -1,11 +1,16 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"unicode/utf8"
 )
 
+// binarySniffBytes is how much of a file IsBinary looks at.
+const binarySniffBytes = 8000
+
 func ReadFile(filePath string) (string, error) {
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
@@ -28,3 +33,23 @@ func ReadFile(filePath string) (string, error) {
 
 	return string(content), nil
 }
+
+// IsBinary reports whether data looks like the start of a binary file: it
+// holds a NUL byte or is not valid UTF-8.
+func IsBinary(data []byte) bool {
+	sniff := data
+	if len(sniff) > binarySniffBytes {
+		sniff = sniff[:binarySniffBytes]
+	}
+	if bytes.IndexByte(sniff, 0) >= 0 {
+		return true
+	}
+	for len(sniff) > 0 {
+		r, size := utf8.DecodeRune(sniff)
+		if r == utf8.RuneError && size == 1 && len(sniff) > utf8.UTFMax {
+			return true
+		}
+		sniff = sniff[size:]
+	}
+	return false
+}